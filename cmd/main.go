@@ -2,21 +2,38 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
 	"net"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
+	"weezel/ruuvigraph/pkg/auth"
 	"weezel/ruuvigraph/pkg/btlistener"
+	"weezel/ruuvigraph/pkg/cache"
+	"weezel/ruuvigraph/pkg/compression"
+	"weezel/ruuvigraph/pkg/connection"
 	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
 	"weezel/ruuvigraph/pkg/logging"
 	"weezel/ruuvigraph/pkg/plot"
 	"weezel/ruuvigraph/pkg/profiling"
+	"weezel/ruuvigraph/pkg/ruuvi"
+	"weezel/ruuvigraph/pkg/segstore"
+	"weezel/ruuvigraph/pkg/storage"
+	"weezel/ruuvigraph/pkg/storage/persistent"
+	"weezel/ruuvigraph/pkg/tlsconfig"
+	"weezel/ruuvigraph/pkg/units"
+	"weezel/ruuvigraph/pkg/unixsocket"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
 )
 
 // Linker will fill these
@@ -28,38 +45,342 @@ var (
 var logger *slog.Logger = logging.NewColorLogHandler()
 
 var (
-	grpcHost    = flag.String("h", "127.0.0.1", "Host where to serve or connect to")
-	grpcPort    = flag.String("p", "50051", "Port where to serve or connect to")
-	aliasesFile = flag.String("a", "ruuvi_aliases.conf", "Aliases file for friendly names to devices")
-	runServer   = flag.Bool("s", false, "Run as a server & plotter")
-	listenOnly  = flag.Bool("l", false, "Only listen incoming beacons, don't do anything else")
-	tickTime    = flag.Duration("t", 1*time.Minute, "Transmit measurements to server every N time units") // TODO
+	grpcHost      = flag.String("h", "127.0.0.1", "Host where to serve or connect to, or unix:/path/to/socket")
+	grpcPort      = flag.String("p", "50051", "Port where to serve or connect to")
+	aliasesFile   = flag.String("a", "ruuvi_aliases.conf", "Aliases file for friendly names to devices")
+	groupsFile    = flag.String("g", "", "Groups file for grouping devices, used in subscriptions")
+	archiveFile   = flag.String("archive", "", "Archive measurements to this file and restore them on start")
+	archiveGens   = flag.Int("archive-generations", 0, "Keep N previous archive files to restore from")
+	archiveGzip   = flag.Bool("archive-gzip", false, "Compress the archive file with gzip")
+	storageKind   = flag.String("storage", "memory", "Measurement storage: memory or persistent")
+	dataDir       = flag.String("data-dir", "ruuvi_data", "Directory of the persistent storage")
+	fsyncPolicy   = flag.String("fsync", "interval", "Sync segment files to disk: interval, always or none")
+	retention     = flag.Duration("retention", cache.DefaultMaxAge, "Keep raw measurements for N")
+	hourlyRetain  = flag.Duration("hourly-retention", cache.DefaultHourlyAge, "Keep hourly rollups for N")
+	dailyRetain   = flag.Duration("daily-retention", cache.DefaultDailyAge, "Keep daily rollups for N")
+	retainRules   = flag.String("retention-rules", "", "File of kind|device, MAC or group|duration retention rules")
+	plotRange     = flag.Duration("plot-range", 0, "Plot the last N, rollups past -retention, 0 plots all")
+	lateness      = flag.Duration("lateness", cache.DefaultLateness, "Accept measurements N older than newer ones")
+	sampleBudget  = flag.Int("sample-budget", 0, "Measurements kept in memory at most, 0 is unlimited")
+	memoryBudget  = flag.Int("memory-budget", 0, "Approximate MiB of measurements kept in memory, 0 is unlimited")
+	runServer     = flag.Bool("s", false, "Run as a server & plotter")
+	allInOne      = flag.Bool("all-in-one", false, "Run collector and server & plotter in a single process")
+	acceptRemote  = flag.Bool("remote-collectors", false, "Accept also remote collectors in all-in-one mode")
+	listenOnly    = flag.Bool("l", false, "Only listen incoming beacons, don't do anything else")
+	useTLS        = flag.Bool("tls", false, "Use TLS between collectors and server")
+	tlsCert       = flag.String("tls-cert", "", "Certificate file for TLS, client certificate in collector mode")
+	tlsKey        = flag.String("tls-key", "", "Key file for TLS, client key in collector mode")
+	tlsCA         = flag.String("tls-ca", "", "CA file for verifying client (server mode) or server certificates")
+	tlsServerName = flag.String("tls-server-name", "", "Override server name used in certificate verification")
+	authTokens    = flag.String("auth-tokens", "", "File of collector|token lines, enables token authentication")
+	authTokenFile = flag.String("auth-token-file", "", "File containing the collector's authentication token")
+	httpPort      = flag.String("http-port", "", "Serve Connect, gRPC-Web and JSON API on this port if set")
+	corsOrigins   = flag.String("cors-origins", "", "Comma separated origins allowed to call the HTTP API")
+	useReflection = flag.Bool("reflection", false, "Enable gRPC server reflection")
+	compressor    = flag.String("compression", "none", "Compress batches sent to server: none, gzip or zstd")
+	compact       = flag.Bool("compact-batches", true, "Send device names and MAC addresses once per batch")
+	pressureUnit  = flag.String("pressure-unit", "hPa", "Pressure unit in plots: Pa, hPa, kPa, inHg or mmHg")
+	tempUnit      = flag.String("temperature-unit", "C", "Temperature unit in plots: C, F or K")
+	statusEvery   = flag.Duration("status-interval", time.Minute, "Report collector status every N, 0 disables")
+	sendTimeout   = flag.Duration("send-timeout", 30*time.Second, "Time server has to acknowledge a batch")
+	remoteConfig  = flag.Bool("remote-config", false, "Receive aliases and intervals from the server")
+	configCache   = flag.String("config-cache", "ruuvi_config.json", "Last configuration received from server")
+	scanWindow    = flag.Duration("scan-window", 0, "Scan for N in every scan interval, 0 scans continuously")
+	scanInterval  = flag.Duration("scan-interval", time.Minute, "Interval of scan windows")
+	socketMode    = flag.String("socket-mode", "0660", "Permissions of the unix socket in server mode")
+	socketGroup   = flag.String("socket-group", "", "Owning group of the unix socket in server mode")
+	rateLimit     = flag.Float64("rate-limit", 0, "Measurements per second accepted from a collector, 0 disables")
+	rateBurst     = flag.Int("rate-burst", 1000, "Measurements a collector may send at once over its rate limit")
+	globalRate    = flag.Float64("global-rate-limit", 0, "Measurements per second accepted in total, 0 disables")
+	globalBurst   = flag.Int("global-rate-burst", 5000, "Measurements accepted at once over the global rate limit")
+	tickTime      = flag.Duration("t", 10*time.Minute, "Transmit measurements to server every N time units")
 )
 
+func displayUnits() (units.Display, error) {
+	pressure, err := units.ParsePressureUnit(*pressureUnit)
+	if err != nil {
+		return units.Display{}, fmt.Errorf("pressure unit: %w", err)
+	}
+	temperature, err := units.ParseTemperatureUnit(*tempUnit)
+	if err != nil {
+		return units.Display{}, fmt.Errorf("temperature unit: %w", err)
+	}
+	return units.Display{Pressure: pressure, Temperature: temperature}, nil
+}
+
+// newServer creates the plotting server configured by the flags
+func newServer() (*plot.PlottingServer, error) {
+	display, err := displayUnits()
+	if err != nil {
+		return nil, fmt.Errorf("display units: %w", err)
+	}
+
+	serverOpts := []plot.OptionServer{
+		plot.WithVersion(Version, BuildTime),
+		plot.WithReflection(*useReflection),
+		plot.WithDisplayUnits(display),
+		plot.WithPlotRange(*plotRange),
+		plot.WithRateLimit(*rateLimit, *rateBurst, *globalRate, *globalBurst),
+	}
+	if *groupsFile != "" {
+		serverOpts = append(serverOpts, plot.WithGroupsFile(*groupsFile))
+	}
+	if *archiveFile != "" {
+		serverOpts = append(
+			serverOpts,
+			plot.WithArchiveFilename(*archiveFile),
+			plot.WithArchiveGenerations(*archiveGens),
+			plot.WithArchiveGzip(*archiveGzip),
+		)
+	}
+	if *corsOrigins != "" {
+		serverOpts = append(serverOpts, plot.WithCORSOrigins(strings.Split(*corsOrigins, ",")...))
+	}
+	if *useTLS {
+		tlsCfg, tlsErr := tlsconfig.ServerConfig(*tlsCert, *tlsKey, *tlsCA)
+		if tlsErr != nil {
+			return nil, fmt.Errorf("configure TLS: %w", tlsErr)
+		}
+		serverOpts = append(serverOpts, plot.WithTLSConfig(tlsCfg))
+		logger.Info(
+			"TLS enabled",
+			slog.Bool("client_certificates", *tlsCA != ""),
+		)
+	}
+	if *authTokens != "" {
+		tokens, tokensErr := auth.ReadTokens(*authTokens)
+		if tokensErr != nil {
+			return nil, fmt.Errorf("read authentication tokens: %w", tokensErr)
+		}
+		serverOpts = append(serverOpts, plot.WithTokens(tokens))
+		logger.Info(
+			"Token authentication enabled",
+			slog.Int("collectors", len(tokens)),
+		)
+	}
+
+	mode, err := strconv.ParseUint(*socketMode, 8, 32)
+	if err != nil {
+		return nil, fmt.Errorf("socket mode %q: %w", *socketMode, err)
+	}
+	fileMode := os.FileMode(mode) //nolint:gosec // Parsed as 32 bits
+	serverOpts = append(serverOpts, plot.WithSocketPermissions(fileMode, *socketGroup))
+
+	collectorCfg, err := collectorConfig()
+	if err != nil {
+		return nil, fmt.Errorf("collector configuration: %w", err)
+	}
+	if collectorCfg != nil {
+		serverOpts = append(serverOpts, plot.WithCollectorConfig(collectorCfg))
+	}
+
+	backend, err := newStorage()
+	if err != nil {
+		return nil, fmt.Errorf("storage: %w", err)
+	}
+	serverOpts = append(serverOpts, plot.WithStorage(backend))
+
+	return plot.NewPlottingServer(serverOpts...), nil
+}
+
+// newMemory creates the in-memory measurements configured by the flags
+func newMemory() (*cache.Measurements, error) {
+	opts := []cache.OptionMeasurement{
+		cache.WithMaxMeasureAge(*retention),
+		cache.WithRollups(*hourlyRetain, *dailyRetain),
+		cache.WithLateness(*lateness),
+		cache.WithBudget(cache.Budget{Samples: *sampleBudget, Bytes: *memoryBudget << 20}),
+	}
+	if *retainRules != "" {
+		groups := map[string][]string{}
+		if *groupsFile != "" {
+			var err error
+			if groups, err = ruuvi.ReadGroups(*groupsFile); err != nil {
+				return nil, fmt.Errorf("read groups: %w", err)
+			}
+		}
+		rules, err := cache.ReadRetentionRules(*retainRules, groups)
+		if err != nil {
+			return nil, fmt.Errorf("read retention rules: %w", err)
+		}
+		opts = append(opts, cache.WithRetentionRules(rules...))
+		logger.Info(
+			"Retention rules enabled",
+			slog.Int("rules", len(rules)),
+		)
+	}
+	return cache.New(opts...), nil
+}
+
+// newStorage opens the storage backend selected with -storage
+func newStorage() (storage.Backend, error) {
+	switch *storageKind {
+	case "memory":
+		memory, err := newMemory()
+		if err != nil {
+			return nil, err
+		}
+		return memory, nil
+	case "persistent":
+		if *archiveFile != "" {
+			return nil, errors.New("-archive can't be used with persistent storage")
+		}
+		syncPolicy, err := segstore.ParseSyncPolicy(*fsyncPolicy)
+		if err != nil {
+			return nil, fmt.Errorf("fsync: %w", err)
+		}
+		memory, err := newMemory()
+		if err != nil {
+			return nil, err
+		}
+		backend, err := persistent.Open(*dataDir, memory, segstore.WithSyncPolicy(syncPolicy))
+		if err != nil {
+			return nil, fmt.Errorf("open persistent storage: %w", err)
+		}
+		return backend, nil
+	default:
+		return nil, fmt.Errorf("unknown storage %q", *storageKind)
+	}
+}
+
+// serve runs the server until a listener fails. Without the gRPC listener
+// only the in-process collector feeds the server.
+func serve(ctx context.Context, server *plot.PlottingServer, listenGRPC bool) {
+	server.Start()
+	defer server.Stop()
+
+	errCh := make(chan error, 2)
+	if listenGRPC {
+		go func() {
+			errCh <- server.Listen(*grpcHost, *grpcPort)
+		}()
+	}
+	if *httpPort != "" {
+		httpHost := *grpcHost
+		if _, isUnix := unixsocket.Path(httpHost); isUnix {
+			// HTTP API is served over TCP only
+			httpHost = "127.0.0.1"
+		}
+		go func() {
+			errCh <- server.ListenHTTP(httpHost, *httpPort)
+		}()
+	}
+
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	defer signal.Stop(reload)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-reload:
+			reloadCollectorConfig(server)
+		case err := <-errCh:
+			if err != nil {
+				logger.Error(
+					"Failed to start server on listen mode",
+					slog.Any("error", err),
+				)
+			}
+			return
+		}
+	}
+}
+
 func runAsServer(ctx context.Context) {
 	pprofServer := profiling.NewPprofServer()
 	pprofServer.Start()
 	defer pprofServer.Shutdown(ctx)
 
-	server := plot.NewPlottingServer()
-	errCh := make(chan error, 1)
-	go func() {
-		errCh <- server.Listen(*grpcHost, *grpcPort)
-	}()
-	defer server.Stop()
+	server, err := newServer()
+	if err != nil {
+		logger.Error(
+			"Failed to create server",
+			slog.Any("error", err),
+		)
+		return
+	}
+	serve(ctx, server, true)
+}
 
-	select {
-	case <-ctx.Done():
+// runAllInOne collects and plots in a single process, the collector hands
+// measurements directly to the server. Remote collectors are optional.
+func runAllInOne(ctx context.Context) {
+	pprofServer := profiling.NewPprofServer()
+	pprofServer.Start()
+	defer pprofServer.Shutdown(ctx)
+
+	cCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	server, err := newServer()
+	if err != nil {
+		logger.Error(
+			"Failed to create server",
+			slog.Any("error", err),
+		)
 		return
-	case err := <-errCh:
-		if err != nil {
-			logger.Error(
-				"Failed to start server on listen mode",
-				slog.Any("error", err),
-			)
-			return
+	}
+
+	listenerOpts := collectorOptions()
+	listenerOpts = append(listenerOpts, btlistener.WithIngester(server))
+	btListener := btlistener.NewListener(nil, listenerOpts...)
+	if err = btListener.InitializeDevice(cCtx); err != nil {
+		logger.Error(
+			"Couldn't initialize device",
+			slog.Any("error", err),
+		)
+		return
+	}
+	go btListener.Listen(cCtx)
+
+	serve(cCtx, server, *acceptRemote)
+}
+
+// collectorConfig builds the configuration pushed to collectors from the aliases
+// file and interval flags. Remote configuration is disabled without aliases file.
+func collectorConfig() (*ruuvipb.RuuviCollectorConfig, error) {
+	aliases, err := ruuvi.ReadAliases(*aliasesFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			logger.Info("No aliases file, collectors use their own configuration")
+			return nil, nil
 		}
+		return nil, fmt.Errorf("read aliases: %w", err)
+	}
+	return plot.NewCollectorConfig(aliases, *tickTime, *scanWindow, *scanInterval), nil
+}
+
+// reloadCollectorConfig rereads the configuration and pushes it to the collectors
+func reloadCollectorConfig(server *plot.PlottingServer) {
+	cfg, err := collectorConfig()
+	if err != nil {
+		logger.Error(
+			"Failed to reload collector configuration",
+			slog.Any("error", err),
+		)
+		return
+	}
+	if cfg == nil {
+		// Collectors keep the configuration they have
+		return
+	}
+	server.SetCollectorConfig(cfg)
+}
+
+// collectorOptions returns the options shared by remote and in-process collectors
+func collectorOptions() []btlistener.ListenerOption {
+	opts := []btlistener.ListenerOption{
+		btlistener.WithAliasesFile(*aliasesFile),
+		btlistener.WithVersion(Version),
+		btlistener.WithStatusInterval(*statusEvery),
+		btlistener.WithSendInterval(*tickTime),
 	}
+	if *scanWindow > 0 {
+		opts = append(opts, btlistener.WithScanWindow(*scanWindow, *scanInterval))
+	}
+	return opts
 }
 
 func runAsClient(ctx context.Context) {
@@ -86,9 +407,42 @@ func runAsClient(ctx context.Context) {
 	}
 
 	logger.Info("Collecting measurements")
-	conn, err := grpc.NewClient(
-		net.JoinHostPort(*grpcHost, *grpcPort),
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	creds := insecure.NewCredentials()
+	if *useTLS {
+		var err error
+		creds, err = tlsconfig.ClientCredentials(*tlsCA, *tlsCert, *tlsKey, *tlsServerName)
+		if err != nil {
+			logger.Error(
+				"Failed to configure TLS",
+				slog.Any("error", err),
+			)
+			return
+		}
+	}
+
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+	}
+	if *authTokenFile != "" {
+		token, err := auth.ReadToken(*authTokenFile)
+		if err != nil {
+			logger.Error(
+				"Failed to read authentication token",
+				slog.Any("error", err),
+			)
+			return
+		}
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(auth.NewTokenCredentials(token, *useTLS)))
+	}
+
+	// gRPC understands unix socket addresses as such
+	target := net.JoinHostPort(*grpcHost, *grpcPort)
+	if _, isUnix := unixsocket.Path(*grpcHost); isUnix {
+		target = *grpcHost
+	}
+	connManager, err := connection.NewManager(
+		target,
+		connection.WithDialOptions(dialOpts...),
 	)
 	if err != nil {
 		logger.Error(
@@ -97,15 +451,35 @@ func runAsClient(ctx context.Context) {
 		)
 		return
 	}
-	defer conn.Close()
-	logger.Info(fmt.Sprintf("Measurements receiving endpoint configured to %s:%s", *grpcHost, *grpcPort))
+	defer connManager.Close()
+	go connManager.Run(cCtx)
+	logger.Info(fmt.Sprintf("Measurements receiving endpoint configured to %s", target))
 
-	client := ruuvipb.NewRuuviClient(conn)
+	client := ruuvipb.NewRuuviClient(connManager.Conn())
 
-	btListener := btlistener.NewListener(
-		client,
-		btlistener.WithAliasesFile(*aliasesFile),
+	listenerOpts := collectorOptions()
+	listenerOpts = append(
+		listenerOpts,
+		btlistener.WithCompactBatches(*compact),
+		btlistener.WithConnectionManager(connManager),
+		btlistener.WithSendTimeout(*sendTimeout),
 	)
+	if *remoteConfig {
+		listenerOpts = append(listenerOpts, btlistener.WithRemoteConfig(*configCache))
+	}
+	switch *compressor {
+	case "none", "":
+	case gzip.Name, compression.ZstdName:
+		listenerOpts = append(listenerOpts, btlistener.WithCompressor(*compressor))
+	default:
+		logger.Error(
+			"Unknown compression",
+			slog.String("compression", *compressor),
+		)
+		return
+	}
+
+	btListener := btlistener.NewListener(client, listenerOpts...)
 
 	if err := btListener.InitializeDevice(cCtx); err != nil {
 		logger.Error(
@@ -129,9 +503,19 @@ func main() {
 
 	flag.Parse()
 
+	if *tickTime <= 0 {
+		logger.Error(
+			"Send interval must be positive",
+			slog.Duration("t", *tickTime),
+		)
+		return
+	}
+
 	switch {
 	case *listenOnly && *runServer == false:
 		runAsClient(ctx)
+	case *allInOne:
+		runAllInOne(ctx)
 	case *runServer:
 		runAsServer(ctx)
 	default:
@@ -3,9 +3,11 @@ module weezel/ruuvigraph
 go 1.24.5
 
 require (
+	connectrpc.com/connect v1.18.1
 	github.com/fatih/color v1.18.0
 	github.com/go-ble/ble v0.0.0-20240122180141-8c5522f54333
 	github.com/go-echarts/go-echarts/v2 v2.6.1
+	github.com/klauspost/compress v1.18.0
 	github.com/peterhellberg/ruuvitag v0.1.0
 	github.com/pkg/errors v0.9.1
 	google.golang.org/grpc v1.74.2
@@ -13,7 +15,6 @@ require (
 )
 
 require (
-	connectrpc.com/connect v1.18.1 // indirect
 	github.com/JuulLabs-OSS/cbgo v0.0.2 // indirect
 	github.com/mattn/go-colorable v0.1.14 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
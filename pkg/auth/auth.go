@@ -0,0 +1,291 @@
+package auth
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"weezel/ruuvigraph/pkg/logging"
+
+	"connectrpc.com/connect"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+var logger *slog.Logger = logging.NewColorLogHandler()
+
+const (
+	authorizationHeader = "authorization"
+	bearerPrefix        = "Bearer "
+)
+
+var (
+	errMissingToken = errors.New("missing token")
+	errInvalidToken = errors.New("invalid token")
+	errEmptyToken   = errors.New("empty token")
+)
+
+type collectorKey struct{}
+
+// CollectorFromContext returns the name of the authenticated collector
+func CollectorFromContext(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(collectorKey{}).(string)
+	return name, ok
+}
+
+// ReadTokens reads pre-shared collector tokens into memory. Each line
+// consists of a collector name and its token separated by a pipe.
+func ReadTokens(filename string) (map[string]string, error) {
+	file, err := os.OpenFile(filepath.Clean(filename), os.O_RDONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("file open: %w", err)
+	}
+	defer file.Close()
+
+	collectorTokens := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		splt := strings.Split(strings.TrimSpace(scanner.Text()), "|")
+		if len(splt) != 2 || splt[1] == "" {
+			// Don't print the line, it might contain a secret
+			logger.Warn("Malformed line in tokens file", slog.Int("line", lineNo))
+			continue
+		}
+		collectorTokens[splt[0]] = splt[1]
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan: %w", err)
+	}
+
+	return collectorTokens, nil
+}
+
+// ReadToken reads a single token used by the collector
+func ReadToken(filename string) (string, error) {
+	data, err := os.ReadFile(filepath.Clean(filename))
+	if err != nil {
+		return "", fmt.Errorf("read file: %w", err)
+	}
+
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", errEmptyToken
+	}
+
+	return token, nil
+}
+
+type collectorToken struct {
+	name  string
+	token []byte
+}
+
+// Authenticator verifies that the incoming requests carry a known token
+type Authenticator struct {
+	tokens         []collectorToken
+	publicServices []string
+}
+
+type AuthenticatorOption func(*Authenticator)
+
+// WithPublicServices lets requests to the given gRPC services through without a token,
+// e.g. grpc.health.v1.Health for orchestrator's probes.
+func WithPublicServices(services ...string) AuthenticatorOption {
+	return func(a *Authenticator) {
+		a.publicServices = append(a.publicServices, services...)
+	}
+}
+
+func NewAuthenticator(tokens map[string]string, opts ...AuthenticatorOption) *Authenticator {
+	a := &Authenticator{
+		tokens: make([]collectorToken, 0, len(tokens)),
+	}
+	for name, token := range tokens {
+		a.tokens = append(a.tokens, collectorToken{name: name, token: []byte(token)})
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return a
+}
+
+func (a *Authenticator) isPublic(method string) bool {
+	for _, service := range a.publicServices {
+		if strings.HasPrefix(method, "/"+service+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticate returns the collector name for the token in the authorization header.
+// All the tokens are compared to avoid leaking which of them partially matched.
+func (a *Authenticator) authenticate(header string) (string, error) {
+	if !strings.HasPrefix(header, bearerPrefix) {
+		return "", errMissingToken
+	}
+	presented := []byte(strings.TrimPrefix(header, bearerPrefix))
+
+	collector := ""
+	for _, t := range a.tokens {
+		if subtle.ConstantTimeCompare(presented, t.token) == 1 {
+			collector = t.name
+		}
+	}
+	if collector == "" {
+		return "", errInvalidToken
+	}
+
+	return collector, nil
+}
+
+// verify authenticates the request and stores the collector name into the returned context
+func (a *Authenticator) verify(ctx context.Context, method, header, peerAddr string) (context.Context, error) {
+	if a.isPublic(method) {
+		return ctx, nil
+	}
+
+	collector, err := a.authenticate(header)
+	if err != nil {
+		logger.Warn(
+			"Rejected unauthenticated request",
+			slog.String("peer", peerAddr),
+			slog.String("method", method),
+			slog.Any("error", err),
+		)
+		return nil, err
+	}
+
+	return context.WithValue(ctx, collectorKey{}, collector), nil
+}
+
+func (a *Authenticator) verifyGRPC(ctx context.Context, method string) (context.Context, error) {
+	header := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(authorizationHeader); len(values) > 0 {
+			header = values[0]
+		}
+	}
+	peerAddr := "unknown"
+	if p, ok := peer.FromContext(ctx); ok {
+		peerAddr = p.Addr.String()
+	}
+
+	ctx, err := a.verify(ctx, method, header, peerAddr)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return ctx, nil
+}
+
+func (a *Authenticator) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		ctx, err := a.verifyGRPC(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (a *Authenticator) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv any,
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		ctx, err := a.verifyGRPC(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// ConnectInterceptor is the counterpart of the gRPC interceptors for Connect handlers
+func (a *Authenticator) ConnectInterceptor() connect.Interceptor {
+	return &connectInterceptor{a: a}
+}
+
+type connectInterceptor struct {
+	a *Authenticator
+}
+
+func (c *connectInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		header := req.Header().Get(authorizationHeader)
+		ctx, err := c.a.verify(ctx, req.Spec().Procedure, header, req.Peer().Addr)
+		if err != nil {
+			return nil, connect.NewError(connect.CodeUnauthenticated, err)
+		}
+		return next(ctx, req)
+	}
+}
+
+func (c *connectInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (c *connectInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		header := conn.RequestHeader().Get(authorizationHeader)
+		ctx, err := c.a.verify(ctx, conn.Spec().Procedure, header, conn.Peer().Addr)
+		if err != nil {
+			return connect.NewError(connect.CodeUnauthenticated, err)
+		}
+		return next(ctx, conn)
+	}
+}
+
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// TokenCredentials attaches the collector's token to every outgoing request
+type TokenCredentials struct {
+	token      string
+	requireTLS bool
+}
+
+// NewTokenCredentials constructs per-RPC credentials. Sending tokens over
+// plaintext connections is only allowed when requireTLS is false.
+func NewTokenCredentials(token string, requireTLS bool) *TokenCredentials {
+	return &TokenCredentials{
+		token:      token,
+		requireTLS: requireTLS,
+	}
+}
+
+func (t *TokenCredentials) GetRequestMetadata(_ context.Context, _ ...string) (map[string]string, error) {
+	return map[string]string{
+		authorizationHeader: bearerPrefix + t.token,
+	}, nil
+}
+
+func (t *TokenCredentials) RequireTransportSecurity() bool {
+	return t.requireTLS
+}
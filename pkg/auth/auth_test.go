@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type fakeStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (f *fakeStream) Context() context.Context {
+	return f.ctx
+}
+
+func TestAuthenticator_StreamServerInterceptor(t *testing.T) {
+	a := NewAuthenticator(
+		map[string]string{
+			"livingroom-pi": "s3cret",
+			"garage-pi":     "an0ther",
+		},
+		WithPublicServices("grpc.health.v1.Health"),
+	)
+	interceptor := a.StreamServerInterceptor()
+
+	tests := []struct {
+		name          string
+		method        string
+		md            metadata.MD
+		wantCollector string
+		wantCode      codes.Code
+	}{
+		{
+			name:          "Valid token",
+			md:            metadata.Pairs(authorizationHeader, "Bearer an0ther"),
+			wantCollector: "garage-pi",
+			wantCode:      codes.OK,
+		},
+		{
+			name:     "Unknown token",
+			md:       metadata.Pairs(authorizationHeader, "Bearer s3cre"),
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "Missing bearer prefix",
+			md:       metadata.Pairs(authorizationHeader, "s3cret"),
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "Missing token",
+			md:       metadata.MD{},
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "Public service without token",
+			method:   "/grpc.health.v1.Health/Watch",
+			md:       metadata.MD{},
+			wantCode: codes.OK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+			peerAddr := &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 1234}
+			ctx = peer.NewContext(ctx, &peer.Peer{Addr: peerAddr})
+
+			gotCollector := ""
+			handler := func(_ any, ss grpc.ServerStream) error {
+				gotCollector, _ = CollectorFromContext(ss.Context())
+				return nil
+			}
+			method := tt.method
+			if method == "" {
+				method = "/ruuvi.v1.Ruuvi/StreamData"
+			}
+			info := &grpc.StreamServerInfo{FullMethod: method}
+			err := interceptor(nil, &fakeStream{ctx: ctx}, info, handler)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Errorf("interceptor() code = %v, want %v", code, tt.wantCode)
+			}
+			if gotCollector != tt.wantCollector {
+				t.Errorf("CollectorFromContext() = %q, want %q", gotCollector, tt.wantCollector)
+			}
+		})
+	}
+}
+
+func TestReadTokens(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "tokens.conf")
+	content := "livingroom-pi|s3cret\nmalformed\ngarage-pi|\ngarage-pi|an0ther\n"
+	if err := os.WriteFile(fname, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ReadTokens(fname)
+	if err != nil {
+		t.Fatalf("ReadTokens() error = %v", err)
+	}
+	want := map[string]string{
+		"livingroom-pi": "s3cret",
+		"garage-pi":     "an0ther",
+	}
+	if len(got) != len(want) {
+		t.Errorf("ReadTokens() = %v, want %v", got, want)
+	}
+	for key, val := range want {
+		if got[key] != val {
+			t.Errorf("ReadTokens() values differ for %s: got=%s, want=%s", key, got[key], val)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package batching
+
+import (
+	"time"
+
+	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Compact moves the batch's measurements into the compact form where every
+// device name and MAC address is sent only once and timestamps are offsets
+// from the earliest one. Measurements without a timestamp are left as they are,
+// since an offset can't tell them apart from ones taken at the earliest time.
+func Compact(batch *ruuvipb.RuuviMeasurementBatch) {
+	var msgs, untimed []*ruuvipb.RuuviStreamDataRequest
+	for _, m := range batch.GetMeasurements() {
+		if m.GetTimestamp() == nil {
+			untimed = append(untimed, m)
+			continue
+		}
+		msgs = append(msgs, m)
+	}
+	if len(msgs) == 0 {
+		return
+	}
+
+	base := msgs[0].GetTimestamp().AsTime()
+	for _, m := range msgs[1:] {
+		if ts := m.GetTimestamp().AsTime(); ts.Before(base) {
+			base = ts
+		}
+	}
+	batch.BaseTimestamp = timestamppb.New(base)
+
+	deviceIndex := map[string]uint32{}
+	for _, m := range msgs {
+		key := m.GetDevice() + "|" + m.GetMacAddress()
+		idx, found := deviceIndex[key]
+		if !found {
+			idx = uint32(len(batch.Devices)) //nolint:gosec // Batch can't have more than 2^32 devices
+			deviceIndex[key] = idx
+			batch.Devices = append(batch.Devices, &ruuvipb.RuuviDevice{
+				Name:       m.GetDevice(),
+				MacAddress: m.GetMacAddress(),
+			})
+		}
+
+		batch.CompactMeasurements = append(batch.CompactMeasurements, &ruuvipb.RuuviCompactMeasurement{
+			Device:            idx,
+			Temperature:       m.GetTemperature(),
+			Humidity:          m.GetHumidity(),
+			Pressure:          m.GetPressure(),
+			BatteryVolts:      m.GetBatterVolts(),
+			Rssi:              m.GetRssi(),
+			TimestampOffsetMs: m.GetTimestamp().AsTime().Sub(base).Milliseconds(),
+		})
+	}
+	batch.Measurements = untimed
+}
+
+// Measurements returns all the measurements of the batch, compact ones
+// expanded after the full ones. A compact measurement referring to unknown
+// device is returned without device information, hence it fails validation.
+func Measurements(batch *ruuvipb.RuuviMeasurementBatch) []*ruuvipb.RuuviStreamDataRequest {
+	msgs := make(
+		[]*ruuvipb.RuuviStreamDataRequest,
+		0,
+		len(batch.GetMeasurements())+len(batch.GetCompactMeasurements()),
+	)
+	msgs = append(msgs, batch.GetMeasurements()...)
+
+	base := batch.GetBaseTimestamp().AsTime()
+	devices := batch.GetDevices()
+	for _, c := range batch.GetCompactMeasurements() {
+		offset := time.Duration(c.GetTimestampOffsetMs()) * time.Millisecond
+		m := &ruuvipb.RuuviStreamDataRequest{
+			Temperature: c.GetTemperature(),
+			Humidity:    c.GetHumidity(),
+			Pressure:    c.GetPressure(),
+			BatterVolts: c.GetBatteryVolts(),
+			Rssi:        c.GetRssi(),
+			Timestamp:   timestamppb.New(base.Add(offset)),
+		}
+		if int(c.GetDevice()) < len(devices) {
+			m.Device = devices[c.GetDevice()].GetName()
+			m.MacAddress = devices[c.GetDevice()].GetMacAddress()
+		}
+		msgs = append(msgs, m)
+	}
+
+	return msgs
+}
+
+// Len returns the count of measurements in the batch, both full and compact ones
+func Len(batch *ruuvipb.RuuviMeasurementBatch) int {
+	return len(batch.GetMeasurements()) + len(batch.GetCompactMeasurements())
+}
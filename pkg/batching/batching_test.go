@@ -0,0 +1,186 @@
+package batching
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"testing"
+	"time"
+
+	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var devices = []struct {
+	name string
+	mac  string
+}{
+	{"Kitchen", "d8:82:aa:bb:cc:dd"},
+	{"Balcony", "fc:8a:aa:bb:cc:dd"},
+	{"Bedroom", "cb:15:aa:bb:cc:dd"},
+	{"Living room", "e1:07:aa:bb:cc:dd"},
+}
+
+func newBatch(readingsPerDevice int) *ruuvipb.RuuviMeasurementBatch {
+	started := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	batch := &ruuvipb.RuuviMeasurementBatch{
+		Collector: "livingroom-pi",
+		Session:   "0123456789abcdef",
+		BatchId:   42,
+	}
+	for i := range readingsPerDevice {
+		for j, dev := range devices {
+			batch.Measurements = append(batch.Measurements, &ruuvipb.RuuviStreamDataRequest{
+				Device:      dev.name,
+				MacAddress:  dev.mac,
+				Temperature: 21.5 + float32(j) + float32(i)*0.01,
+				Humidity:    40.25 - float32(i)*0.05,
+				Pressure:    101324 + float32(i%3*10),
+				BatterVolts: 2.911,
+				Rssi:        int32(-70 - j),
+				Timestamp:   timestamppb.New(started.Add(time.Duration(i) * time.Minute)),
+			})
+		}
+	}
+	return batch
+}
+
+func TestCompact(t *testing.T) {
+	batch := newBatch(3)
+	want := proto.Clone(batch).(*ruuvipb.RuuviMeasurementBatch) //nolint:forcetypeassert // Clone of the same type
+
+	Compact(batch)
+	if len(batch.GetMeasurements()) != 0 {
+		t.Errorf("Compact() left %d full measurements", len(batch.GetMeasurements()))
+	}
+	if len(batch.GetDevices()) != len(devices) {
+		t.Errorf("Compact() devices = %d, want %d", len(batch.GetDevices()), len(devices))
+	}
+
+	// Round trip over the wire
+	data, err := proto.Marshal(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := &ruuvipb.RuuviMeasurementBatch{}
+	if err = proto.Unmarshal(data, received); err != nil {
+		t.Fatal(err)
+	}
+
+	got := Measurements(received)
+	if len(got) != len(want.GetMeasurements()) {
+		t.Fatalf("Measurements() = %d items, want %d", len(got), len(want.GetMeasurements()))
+	}
+	for i := range got {
+		if !proto.Equal(got[i], want.GetMeasurements()[i]) {
+			t.Errorf("Measurements()[%d] = %v, want %v", i, got[i], want.GetMeasurements()[i])
+		}
+	}
+}
+
+func TestCompact_missingTimestamp(t *testing.T) {
+	batch := newBatch(2)
+	batch.Measurements[1].Timestamp = nil
+
+	Compact(batch)
+	if len(batch.GetMeasurements()) != 1 || batch.GetMeasurements()[0].GetTimestamp() != nil {
+		t.Fatalf("Compact() left %v, want the one without timestamp", batch.GetMeasurements())
+	}
+	if len(batch.GetCompactMeasurements()) != 2*len(devices)-1 {
+		t.Errorf("Compact() compacted %d, want %d", len(batch.GetCompactMeasurements()), 2*len(devices)-1)
+	}
+	for _, m := range Measurements(batch)[1:] {
+		if m.GetTimestamp() == nil || m.GetTimestamp().AsTime().Year() != 2025 {
+			t.Errorf("Measurements() expanded timestamp %v, want the original", m.GetTimestamp())
+		}
+	}
+}
+
+func TestMeasurements_UnknownDevice(t *testing.T) {
+	batch := &ruuvipb.RuuviMeasurementBatch{
+		Devices: []*ruuvipb.RuuviDevice{{Name: "Kitchen"}},
+		CompactMeasurements: []*ruuvipb.RuuviCompactMeasurement{
+			{Device: 0},
+			{Device: 1},
+		},
+	}
+
+	got := Measurements(batch)
+	if got[0].GetDevice() != "Kitchen" {
+		t.Errorf("Measurements()[0] device = %q, want Kitchen", got[0].GetDevice())
+	}
+	if got[1].GetDevice() != "" || got[1].GetMacAddress() != "" {
+		t.Errorf("Measurements()[1] = %v, want no device information", got[1])
+	}
+}
+
+func gzipSize(b *testing.B, data []byte) int {
+	b.Helper()
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		b.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		b.Fatal(err)
+	}
+	return buf.Len()
+}
+
+func zstdSize(b *testing.B, enc *zstd.Encoder, data []byte) int {
+	b.Helper()
+	return len(enc.EncodeAll(data, nil))
+}
+
+// BenchmarkBatchSize reports how many bytes a batch takes on the wire.
+// Collector sends the latest reading of each device per batch, but resent
+// batches after an outage and longer send intervals grow the batches.
+func BenchmarkBatchSize(b *testing.B) {
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer enc.Close()
+
+	for _, readings := range []int{1, 10, 60} {
+		for _, compact := range []bool{false, true} {
+			format := "full"
+			if compact {
+				format = "compact"
+			}
+
+			batch := newBatch(readings)
+			if compact {
+				Compact(batch)
+			}
+			data, err := proto.Marshal(batch)
+			if err != nil {
+				b.Fatal(err)
+			}
+			count := float64(readings * len(devices))
+
+			for _, codec := range []string{"none", "gzip", "zstd"} {
+				name := fmt.Sprintf("readings=%d/format=%s/compression=%s", readings, format, codec)
+				b.Run(name, func(b *testing.B) {
+					size := 0
+					for b.Loop() {
+						switch codec {
+						case "gzip":
+							size = gzipSize(b, data)
+						case "zstd":
+							size = zstdSize(b, enc, data)
+						default:
+							size = len(data)
+						}
+					}
+					b.ReportMetric(float64(size), "bytes/batch")
+					b.ReportMetric(float64(size)/count, "bytes/measurement")
+				})
+			}
+		}
+	}
+}
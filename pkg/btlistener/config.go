@@ -0,0 +1,198 @@
+package btlistener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func (b *BtListener) alias(mac string) (string, bool) {
+	b.configMu.RLock()
+	defer b.configMu.RUnlock()
+	name, found := b.deviceAliases[mac]
+	return name, found
+}
+
+func (b *BtListener) scanSchedule() (window, interval time.Duration) {
+	b.configMu.RLock()
+	defer b.configMu.RUnlock()
+	return b.scanWindow, b.scanInterval
+}
+
+// applyConfig takes the server pushed configuration into use
+func (b *BtListener) applyConfig(cfg *ruuvipb.RuuviCollectorConfig) {
+	aliases := make(map[string]string, len(cfg.GetDevices()))
+	for _, dev := range cfg.GetDevices() {
+		aliases[dev.GetMacAddress()] = dev.GetName()
+	}
+
+	b.configMu.Lock()
+	b.deviceAliases = aliases
+	scheduleChanged := b.scanWindow != cfg.GetScanWindow().AsDuration() ||
+		b.scanInterval != cfg.GetScanInterval().AsDuration()
+	b.scanWindow = cfg.GetScanWindow().AsDuration()
+	b.scanInterval = cfg.GetScanInterval().AsDuration()
+	b.configMu.Unlock()
+
+	if cfg.GetSendInterval().AsDuration() > 0 {
+		b.ticker.Reset(cfg.GetSendInterval().AsDuration())
+	}
+	if scheduleChanged {
+		select {
+		case b.rescan <- struct{}{}:
+		default: // Rescan already requested
+		}
+	}
+	b.configVersion.Store(cfg.GetVersion())
+
+	logger.Info(
+		"Applied configuration",
+		slog.Uint64("version", cfg.GetVersion()),
+		slog.Int("devices", len(aliases)),
+		slog.Duration("send_interval", cfg.GetSendInterval().AsDuration()),
+		slog.Duration("scan_window", cfg.GetScanWindow().AsDuration()),
+		slog.Duration("scan_interval", cfg.GetScanInterval().AsDuration()),
+	)
+}
+
+// loadCachedConfig applies the configuration received on an earlier run
+func (b *BtListener) loadCachedConfig() {
+	data, err := os.ReadFile(filepath.Clean(b.configCacheFile))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.Warn(
+				"Failed to read cached configuration",
+				slog.Any("error", err),
+			)
+		}
+		return
+	}
+
+	cfg := &ruuvipb.RuuviCollectorConfig{}
+	if err = protojson.Unmarshal(data, cfg); err != nil {
+		logger.Warn(
+			"Failed to parse cached configuration",
+			slog.String("filename", b.configCacheFile),
+			slog.Any("error", err),
+		)
+		return
+	}
+	b.applyConfig(cfg)
+}
+
+// cacheConfig writes the configuration through a synced temporary file, so that
+// an interrupted write or a power cut doesn't destroy the previous configuration
+func (b *BtListener) cacheConfig(cfg *ruuvipb.RuuviCollectorConfig) error {
+	data, err := protojson.MarshalOptions{Multiline: true}.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("marshal config: %w", err)
+	}
+
+	tmpFilename := b.configCacheFile + ".tmp"
+	f, err := os.OpenFile(filepath.Clean(tmpFilename), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("create config: %w", err)
+	}
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpFilename)
+		return fmt.Errorf("write config: %w", err)
+	}
+	if err = os.Rename(tmpFilename, b.configCacheFile); err != nil {
+		return fmt.Errorf("rename config: %w", err)
+	}
+
+	// Rename is durable only after the directory is synced
+	dir, err := os.Open(filepath.Dir(b.configCacheFile))
+	if err != nil {
+		return fmt.Errorf("open config directory: %w", err)
+	}
+	defer dir.Close()
+	if err = dir.Sync(); err != nil {
+		return fmt.Errorf("sync config directory: %w", err)
+	}
+	return nil
+}
+
+// configLoop keeps the configuration stream open, reconnecting with backoff
+func (b *BtListener) configLoop(ctx context.Context) {
+	attempt := 0
+	for {
+		received, err := b.receiveConfig(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if received {
+			attempt = 0
+		}
+
+		delay := b.retryBackoff.Delay(attempt)
+		attempt++
+		logger.Warn(
+			"Configuration stream closed, reconnecting",
+			slog.Any("error", err),
+			slog.Duration("delay", delay),
+		)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// receiveConfig applies configurations until the stream breaks. Returns
+// whether any configuration was received.
+func (b *BtListener) receiveConfig(ctx context.Context) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := b.streamerClient.Configure(ctx)
+	if err != nil {
+		return false, fmt.Errorf("configure: %w", err)
+	}
+	err = stream.Send(&ruuvipb.RuuviConfigureRequest{
+		Collector:      b.collector,
+		AppliedVersion: b.configVersion.Load(),
+	})
+	if err != nil {
+		return false, fmt.Errorf("send applied version: %w", err)
+	}
+
+	received := false
+	for {
+		cfg, recvErr := stream.Recv()
+		if recvErr != nil {
+			return received, fmt.Errorf("receive config: %w", recvErr)
+		}
+		received = true
+
+		b.applyConfig(cfg)
+		if err = b.cacheConfig(cfg); err != nil {
+			logger.Warn(
+				"Failed to cache configuration",
+				slog.Any("error", err),
+			)
+		}
+		err = stream.Send(&ruuvipb.RuuviConfigureRequest{
+			Collector:      b.collector,
+			AppliedVersion: cfg.GetVersion(),
+		})
+		if err != nil {
+			return received, fmt.Errorf("send applied version: %w", err)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package btlistener
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func newTestListener(t *testing.T) *BtListener {
+	t.Helper()
+
+	ticker := time.NewTicker(time.Hour)
+	t.Cleanup(ticker.Stop)
+	return &BtListener{
+		ticker:          ticker,
+		deviceAliases:   map[string]string{"aa:bb:cc:dd:ee:ff": "Local"},
+		rescan:          make(chan struct{}, 1),
+		remoteConfig:    true,
+		configCacheFile: filepath.Join(t.TempDir(), "config.json"),
+	}
+}
+
+func TestBtListener_cachedConfig(t *testing.T) {
+	cfg := &ruuvipb.RuuviCollectorConfig{
+		Version: 42,
+		Devices: []*ruuvipb.RuuviDevice{
+			{Name: "Kitchen", MacAddress: "d8:82:aa:bb:cc:dd"},
+		},
+		SendInterval: durationpb.New(time.Minute),
+		ScanWindow:   durationpb.New(10 * time.Second),
+		ScanInterval: durationpb.New(time.Minute),
+	}
+
+	b := newTestListener(t)
+	b.applyConfig(cfg)
+	if err := b.cacheConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if _, found := b.alias("aa:bb:cc:dd:ee:ff"); found {
+		t.Error("local alias wasn't replaced by the server's")
+	}
+	select {
+	case <-b.rescan:
+	default:
+		t.Error("changing scan schedule didn't request rescan")
+	}
+
+	// Offline start with the cached configuration
+	restarted := newTestListener(t)
+	restarted.configCacheFile = b.configCacheFile
+	restarted.loadCachedConfig()
+
+	if name, _ := restarted.alias("d8:82:aa:bb:cc:dd"); name != "Kitchen" {
+		t.Errorf("alias = %q, want Kitchen", name)
+	}
+	if window, interval := restarted.scanSchedule(); window != 10*time.Second || interval != time.Minute {
+		t.Errorf("scanSchedule() = %s, %s", window, interval)
+	}
+	if restarted.configVersion.Load() != 42 {
+		t.Errorf("configVersion = %d, want 42", restarted.configVersion.Load())
+	}
+}
+
+func TestBtListener_loadCachedConfig_missing(t *testing.T) {
+	b := newTestListener(t)
+	b.loadCachedConfig()
+
+	if name, _ := b.alias("aa:bb:cc:dd:ee:ff"); name != "Local" {
+		t.Errorf("alias = %q, want local one kept", name)
+	}
+}
@@ -2,13 +2,20 @@ package btlistener
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"weezel/ruuvigraph/pkg/batching"
+	"weezel/ruuvigraph/pkg/connection"
 	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
 	"weezel/ruuvigraph/pkg/logging"
 	"weezel/ruuvigraph/pkg/ruuvi"
@@ -16,21 +23,67 @@ import (
 	"github.com/go-ble/ble"
 	blelinux "github.com/go-ble/ble/linux"
 	"github.com/peterhellberg/ruuvitag"
+	"google.golang.org/grpc"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 var logger *slog.Logger = logging.NewColorLogHandler()
 
+var (
+	errUnacknowledged = errors.New("unacknowledged")
+	errAckTimeout     = errors.New("timed out waiting for acknowledgement")
+)
+
+// Ingester stores measurements in the same process, which lets collector and
+// server run together without a gRPC connection in between
+type Ingester interface {
+	IngestBatch(ctx context.Context, batch *ruuvipb.RuuviMeasurementBatch) *ruuvipb.RuuviBatchAck
+	ReportStatus(
+		ctx context.Context,
+		status *ruuvipb.RuuviCollectorStatus,
+	) (*ruuvipb.RuuviReportStatusResponse, error)
+}
+
 type BtListener struct {
 	ruuvipb.UnimplementedRuuviServer
 
 	listenOnly      bool
 	streamerClient  ruuvipb.RuuviClient
+	ingester        Ingester
 	device          *blelinux.Device
 	ticker          *time.Ticker
-	deviceAliases   map[string]string
 	aliasesFilename string
 	measurements    sync.Map // key=string, value=*ruuvipb.RuuviStreamDataRequest
+
+	// configMu guards the settings which can be changed by server pushed configuration
+	configMu      sync.RWMutex
+	deviceAliases map[string]string
+	scanWindow    time.Duration
+	scanInterval  time.Duration
+	rescan        chan struct{}
+
+	remoteConfig    bool
+	configCacheFile string
+	configVersion   atomic.Uint64
+
+	collector         string
+	session           string
+	nextBatchID       atomic.Uint64
+	pendingMu         sync.Mutex
+	pending           []*ruuvipb.RuuviMeasurementBatch
+	maxPendingBatches int
+	retryBackoff      connection.Backoff
+	connManager       *connection.Manager
+	sendTimeout       time.Duration
+	compactBatches    bool
+	compressor        string
+
+	version            string
+	started            time.Time
+	statusInterval     time.Duration
+	adapterState       atomic.Int32 // ruuvipb.RuuviAdapterState
+	advertisementsSeen atomic.Uint64
+	parseErrors        atomic.Uint64
 }
 
 type ListenerOption func(*BtListener)
@@ -47,12 +100,124 @@ func WithListenOnly(listenOnly bool) ListenerOption {
 	}
 }
 
+// WithSendInterval sets how often measurements are sent to the server, non-positive
+// interval keeps the default
+func WithSendInterval(interval time.Duration) ListenerOption {
+	return func(bl *BtListener) {
+		if interval > 0 {
+			bl.ticker.Reset(interval)
+		}
+	}
+}
+
+// WithScanWindow makes scanning last window in every interval instead of scanning continuously
+func WithScanWindow(window, interval time.Duration) ListenerOption {
+	return func(bl *BtListener) {
+		bl.scanWindow = window
+		bl.scanInterval = interval
+	}
+}
+
+// WithRemoteConfig receives device aliases and intervals from the server. Last received
+// configuration is cached into cacheFile and used when starting without connection to the server.
+// Local aliases file is optional with remote configuration.
+func WithRemoteConfig(cacheFile string) ListenerOption {
+	return func(bl *BtListener) {
+		bl.remoteConfig = true
+		bl.configCacheFile = cacheFile
+	}
+}
+
+// WithIngester hands measurements and status to the ingester instead of sending them to the server
+func WithIngester(ingester Ingester) ListenerOption {
+	return func(bl *BtListener) {
+		bl.ingester = ingester
+	}
+}
+
+// WithCollectorName sets the name collector announces to the server, defaults to hostname
+func WithCollectorName(name string) ListenerOption {
+	return func(bl *BtListener) {
+		bl.collector = name
+	}
+}
+
+// WithMaxPendingBatches limits how many unacknowledged batches are kept for resending.
+// Oldest batches are dropped when the limit is exceeded.
+func WithMaxPendingBatches(count int) ListenerOption {
+	return func(bl *BtListener) {
+		bl.maxPendingBatches = count
+	}
+}
+
+// WithRetryBackoff sets how failed sends are retried before the next tick
+func WithRetryBackoff(backoff connection.Backoff) ListenerOption {
+	return func(bl *BtListener) {
+		bl.retryBackoff = backoff
+	}
+}
+
+// WithConnectionManager retries failed sends as soon as the connection is ready,
+// and reports its reconnects in the status
+func WithConnectionManager(m *connection.Manager) ListenerOption {
+	return func(bl *BtListener) {
+		bl.connManager = m
+	}
+}
+
+// WithSendTimeout sets how long the server may take to acknowledge a batch. The
+// timeout starts again on each acknowledgement, so many pending batches can be sent.
+func WithSendTimeout(timeout time.Duration) ListenerOption {
+	return func(bl *BtListener) {
+		bl.sendTimeout = timeout
+	}
+}
+
+// WithCompactBatches sends device names and MAC addresses only once per batch
+func WithCompactBatches(compact bool) ListenerOption {
+	return func(bl *BtListener) {
+		bl.compactBatches = compact
+	}
+}
+
+// WithCompressor compresses batches with the named gRPC compressor, e.g. gzip or zstd
+func WithCompressor(name string) ListenerOption {
+	return func(bl *BtListener) {
+		bl.compressor = name
+	}
+}
+
+// WithVersion sets the version reported to the server
+func WithVersion(version string) ListenerOption {
+	return func(bl *BtListener) {
+		bl.version = version
+	}
+}
+
+// WithStatusInterval sets how often status is reported to the server, zero disables reporting
+func WithStatusInterval(interval time.Duration) ListenerOption {
+	return func(bl *BtListener) {
+		bl.statusInterval = interval
+	}
+}
+
 func NewListener(streamerClient ruuvipb.RuuviClient, opts ...ListenerOption) *BtListener {
+	hostname, _ := os.Hostname()
 	listener := &BtListener{
-		streamerClient:  streamerClient,
-		ticker:          time.NewTicker(10 * time.Minute),
-		aliasesFilename: "ruuvi_aliases.conf",
+		streamerClient:    streamerClient,
+		ticker:            time.NewTicker(10 * time.Minute),
+		aliasesFilename:   "ruuvi_aliases.conf",
+		collector:         hostname,
+		session:           newSessionID(),
+		maxPendingBatches: 144, // A day worth of batches with the default interval
+		retryBackoff:      connection.DefaultBackoff,
+		sendTimeout:       30 * time.Second,
+		started:           time.Now(),
+		statusInterval:    time.Minute,
+		deviceAliases:     map[string]string{},
+		rescan:            make(chan struct{}, 1),
 	}
+	listener.setAdapterState(ruuvipb.RuuviAdapterState_RUUVI_ADAPTER_STATE_DOWN)
 
 	for _, opt := range opts {
 		opt(listener)
@@ -60,15 +225,21 @@ func NewListener(streamerClient ruuvipb.RuuviClient, opts ...ListenerOption) *Bt
 
 	if !listener.listenOnly {
 		devAliases, err := ruuvi.ReadAliases(listener.aliasesFilename)
-		if err != nil {
+		switch {
+		case err == nil:
+			listener.deviceAliases = devAliases
+		case listener.remoteConfig && errors.Is(err, os.ErrNotExist):
+			logger.Info("No local aliases file, waiting for configuration from the server")
+		default:
 			logger.Error(
 				"Failed to read aliases file",
 				slog.Any("error", err),
 			)
 			os.Exit(1)
 		}
-
-		listener.deviceAliases = devAliases
+	}
+	if listener.remoteConfig {
+		listener.loadCachedConfig()
 	}
 
 	return listener
@@ -77,6 +248,7 @@ func NewListener(streamerClient ruuvipb.RuuviClient, opts ...ListenerOption) *Bt
 func (b *BtListener) InitializeDevice(ctx context.Context) error {
 	dev, err := blelinux.NewDevice()
 	if err != nil {
+		b.setAdapterState(ruuvipb.RuuviAdapterState_RUUVI_ADAPTER_STATE_FAILED)
 		return fmt.Errorf("initialize bluetooth device: %w", err)
 	}
 	b.device = dev
@@ -84,68 +256,162 @@ func (b *BtListener) InitializeDevice(ctx context.Context) error {
 	return nil
 }
 
-func (b *BtListener) SendMeasurements(ctx context.Context) error {
+func newSessionID() string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// enqueueBatch moves the collected measurements into a new batch waiting for sending
+func (b *BtListener) enqueueBatch() {
 	started := time.Now()
 
-	// Normalise timestamps
-	b.measurements.Range(func(_, value any) bool {
-		if m, ok := value.(*ruuvipb.RuuviStreamDataRequest); ok {
-			m.Timestamp = timestamppb.New(started)
+	batch := &ruuvipb.RuuviMeasurementBatch{
+		Collector: b.collector,
+		Session:   b.session,
+	}
+	b.measurements.Range(func(key, value any) bool {
+		m, ok := value.(*ruuvipb.RuuviStreamDataRequest)
+		if !ok {
+			return true
 		}
+		// Newer value might have been stored in between, leave it for the next batch
+		if !b.measurements.CompareAndDelete(key, value) {
+			return true
+		}
+		// Normalise timestamps
+		m.Timestamp = timestamppb.New(started)
+		batch.Measurements = append(batch.Measurements, m)
 		return true
 	})
+	if len(batch.Measurements) == 0 {
+		return
+	}
+	batch.BatchId = b.nextBatchID.Add(1)
+	if b.compactBatches {
+		batching.Compact(batch)
+	}
+
+	b.pendingMu.Lock()
+	defer b.pendingMu.Unlock()
+
+	b.pending = append(b.pending, batch)
+	if dropCount := len(b.pending) - b.maxPendingBatches; dropCount > 0 {
+		logger.Warn(
+			"Too many unacknowledged batches, dropping the oldest ones",
+			slog.Int("dropped", dropCount),
+		)
+		b.pending = b.pending[dropCount:]
+	}
+}
+
+func (b *BtListener) pendingBatches() []*ruuvipb.RuuviMeasurementBatch {
+	b.pendingMu.Lock()
+	defer b.pendingMu.Unlock()
+	return slices.Clone(b.pending)
+}
+
+func (b *BtListener) acknowledge(batchID uint64) {
+	b.pendingMu.Lock()
+	defer b.pendingMu.Unlock()
+	b.pending = slices.DeleteFunc(b.pending, func(batch *ruuvipb.RuuviMeasurementBatch) bool {
+		return batch.GetBatchId() == batchID
+	})
+}
+
+func (b *BtListener) handleAck(ack *ruuvipb.RuuviBatchAck) {
+	b.acknowledge(ack.GetBatchId())
+	logger.Info(
+		"Server acknowledged batch",
+		slog.Uint64("batch_id", ack.GetBatchId()),
+		slog.Int("accepted", int(ack.GetAccepted())),
+		slog.Int("rejected", int(ack.GetRejected())),
+		slog.Bool("duplicate", ack.GetDuplicate()),
+	)
+	for _, rejection := range ack.GetRejections() {
+		logger.Warn(
+			"Server rejected measurement",
+			slog.Uint64("batch_id", ack.GetBatchId()),
+			slog.Int("index", int(rejection.GetIndex())),
+			slog.String("reason", rejection.GetReason()),
+		)
+	}
+}
+
+// SendMeasurements sends all the unacknowledged batches and removes the ones server
+// acknowledged. Unacknowledged batches are resent on the next call.
+func (b *BtListener) SendMeasurements(ctx context.Context) error {
+	batches := b.pendingBatches()
+	if len(batches) == 0 {
+		return nil
+	}
 
-	ctx, cancel := context.WithTimeout(ctx, time.Second*30)
-	defer cancel()
-	stream, err := b.streamerClient.StreamData(ctx)
+	if b.ingester != nil {
+		for _, batch := range batches {
+			b.handleAck(b.ingester.IngestBatch(ctx, batch))
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	ackTimer := time.AfterFunc(b.sendTimeout, func() { cancel(errAckTimeout) })
+	defer ackTimer.Stop()
+	callOpts := []grpc.CallOption{}
+	if b.compressor != "" {
+		callOpts = append(callOpts, grpc.UseCompressor(b.compressor))
+	}
+	stream, err := b.streamerClient.SendBatches(ctx, callOpts...)
 	if err != nil {
-		return fmt.Errorf("stream data: %w", err)
+		return fmt.Errorf("send batches: %w", err)
 	}
-	defer func() {
-		if err = stream.CloseSend(); err != nil {
-			logger.Error(
-				"Failed to close stream",
-				slog.Any("error", err),
-			)
+
+	acked := 0
+	recvErrCh := make(chan error, 1)
+	go func() {
+		for {
+			ack, recvErr := stream.Recv()
+			if recvErr != nil {
+				if errors.Is(recvErr, io.EOF) {
+					recvErr = nil
+				}
+				recvErrCh <- recvErr
+				return
+			}
+
+			ackTimer.Reset(b.sendTimeout)
+			b.handleAck(ack)
+			acked++
 		}
 	}()
 
-	b.measurements.Range(func(_, value any) bool {
-		m, ok := value.(*ruuvipb.RuuviStreamDataRequest)
-		if !ok {
-			return true
-		}
+	for _, batch := range batches {
 		logger.Info(
-			"Sending data",
-			slog.String("device", m.Device),
-			slog.String("mac", m.MacAddress),
-			slog.Time("timestamp", m.Timestamp.AsTime().Local()),
+			"Sending batch",
+			slog.Uint64("batch_id", batch.GetBatchId()),
+			slog.Int("count", len(batch.GetMeasurements())+len(batch.GetCompactMeasurements())),
 		)
-		if err = stream.Send(m); err != nil {
-			logger.Warn(
-				"Error sending data",
-				slog.String("device", m.Device),
-				slog.String("mac", m.MacAddress),
-				slog.Any("error", err),
-			)
-			return true
+		if err = stream.Send(batch); err != nil {
+			// Actual error is returned by Recv
+			break
 		}
-
-		logger.Info(
-			"Sent data",
-			slog.String("device", m.Device),
-			slog.String("mac", m.MacAddress),
-			slog.Time("timestamp", m.Timestamp.AsTime().Local()),
+	}
+	if err = stream.CloseSend(); err != nil {
+		logger.Error(
+			"Failed to close stream",
+			slog.Any("error", err),
 		)
-		return true
-	})
+	}
 
-	// Receive ACK
-	resp, err := stream.CloseAndRecv()
-	if err != nil {
-		return fmt.Errorf("receive ack: %w", err)
+	if err = <-recvErrCh; err != nil {
+		if cause := context.Cause(ctx); errors.Is(cause, errAckTimeout) {
+			err = cause
+		}
+		return fmt.Errorf("receive acks: %w", err)
+	}
+	if acked < len(batches) {
+		return fmt.Errorf("%d of %d batches: %w", len(batches)-acked, len(batches), errUnacknowledged)
 	}
-	logger.Info(fmt.Sprintf("Server responded: %q", resp.GetMessage()))
 
 	return nil
 }
@@ -170,57 +436,127 @@ func (b *BtListener) Listen(ctx context.Context) {
 		return
 	}
 
-	go func() {
-		for {
+	go b.sendLoop(ctx)
+	if b.statusInterval > 0 {
+		go b.statusLoop(ctx)
+	}
+	if b.remoteConfig {
+		go b.configLoop(ctx)
+	}
+	go b.scanLoop(ctx)
+
+	<-ctx.Done()
+}
+
+// scanLoop scans continuously, or for the scan window in every scan interval.
+// Scanning restarts when the schedule is changed.
+func (b *BtListener) scanLoop(ctx context.Context) {
+	for {
+		window, interval := b.scanSchedule()
+
+		scanCtx, cancel := context.WithCancel(ctx)
+		if window > 0 {
+			scanCtx, cancel = context.WithTimeout(ctx, window)
+		}
+		go func() {
+			select {
+			case <-b.rescan:
+				cancel()
+			case <-scanCtx.Done():
+			}
+		}()
+
+		b.setAdapterState(ruuvipb.RuuviAdapterState_RUUVI_ADAPTER_STATE_SCANNING)
+		err := ble.Scan(scanCtx, true, b.handleAdvertisement, nil)
+		cancel()
+		if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+			b.setAdapterState(ruuvipb.RuuviAdapterState_RUUVI_ADAPTER_STATE_FAILED)
+			logger.Error("Scan failed", slog.Any("error", err))
+			return
+		}
+		b.setAdapterState(ruuvipb.RuuviAdapterState_RUUVI_ADAPTER_STATE_DOWN)
+		if ctx.Err() != nil {
+			logger.Info("Scanning stopped")
+			return
+		}
+
+		if pause := interval - window; window > 0 && pause > 0 {
+			timer := time.NewTimer(pause)
 			select {
 			case <-ctx.Done():
+				timer.Stop()
+				logger.Info("Scanning stopped")
 				return
-			case <-b.ticker.C:
-				b.handleMeasurementSending(ctx)
+			case <-b.rescan:
+			case <-timer.C:
 			}
+			timer.Stop()
 		}
-	}()
+	}
+}
 
-	go func() {
-		err := ble.Scan(ctx, true, b.handleAdvertisement, nil)
-		if err != nil && !errors.Is(err, context.Canceled) {
-			logger.Error("Scan failed", slog.Any("error", err))
+// sendLoop batches measurements on every tick and sends them. Failed sends
+// are retried with backoff instead of waiting for the next tick.
+func (b *BtListener) sendLoop(ctx context.Context) {
+	retry := time.NewTimer(0)
+	<-retry.C
+	defer retry.Stop()
+
+	// Nil channel until a send fails, then closed when the connection is ready
+	var ready <-chan struct{}
+	attempt := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-b.ticker.C:
+			b.enqueueBatch()
+		case <-retry.C:
+		case <-ready:
 		}
-		logger.Info("Scanning stopped")
-	}()
 
-	<-ctx.Done()
+		if err := b.handleMeasurementSending(ctx); err != nil {
+			delay := b.retryBackoff.Delay(attempt)
+			attempt++
+			attrs := []any{
+				slog.Int("attempt", attempt),
+				slog.Duration("delay", delay),
+			}
+			if b.connManager != nil {
+				ready = b.connManager.Ready()
+				attrs = append(attrs, slog.String("connection_state", b.connManager.State().String()))
+			}
+			logger.Warn("Retrying sending measurements", attrs...)
+			retry.Reset(delay)
+			continue
+		}
+		attempt = 0
+		ready = nil
+		retry.Stop()
+	}
 }
 
-func (b *BtListener) handleMeasurementSending(ctx context.Context) {
+func (b *BtListener) handleMeasurementSending(ctx context.Context) error {
 	started := time.Now()
-	countMeasurements := 0
-	b.measurements.Range(func(_, _ any) bool {
-		countMeasurements++
-		return true
-	})
+	countBatches := len(b.pendingBatches())
 
 	logger.Info("Streaming results")
 	if err := b.SendMeasurements(ctx); err != nil {
 		logger.Error(
 			"Failed to send measurements",
 			slog.Any("error", err),
-			slog.Int("count", countMeasurements),
+			slog.Int("batches", countBatches),
 		)
-		return
+		return err
 	}
 
 	logger.Info(
 		"Streamed results",
-		slog.Int("count", countMeasurements),
+		slog.Int("batches", countBatches),
 		slog.Duration("duration", time.Since(started)),
 	)
 
-	// Clear measurements
-	b.measurements.Range(func(key, _ any) bool {
-		b.measurements.Delete(key)
-		return true
-	})
+	return nil
 }
 
 func (b *BtListener) listenOnlyAdvertisements(bleAdv ble.Advertisement) {
@@ -232,20 +568,23 @@ func (b *BtListener) listenOnlyAdvertisements(bleAdv ble.Advertisement) {
 }
 
 func (b *BtListener) handleAdvertisement(bleAdv ble.Advertisement) {
-	var found bool
-	var devName string
-	if devName, found = b.deviceAliases[bleAdv.Addr().String()]; !found {
+	b.advertisementsSeen.Add(1)
+
+	devName, found := b.alias(bleAdv.Addr().String())
+	if !found {
 		return
 	}
 	flogger := logger.With("device", devName) // FIXME this is broken and doesn't work
 
 	mfData := bleAdv.ManufacturerData()
 	if len(mfData) == 0 {
+		b.parseErrors.Add(1)
 		flogger.Warn("Manufacturing data was empty")
 		return
 	}
 	payload, err := ruuvitag.ParseRAWv2(mfData)
 	if err != nil {
+		b.parseErrors.Add(1)
 		flogger.Error(
 			"Failed to parse tag",
 			slog.Any("error", err),
@@ -262,7 +601,7 @@ func (b *BtListener) handleAdvertisement(bleAdv ble.Advertisement) {
 			MacAddress:  bleAdv.Addr().String(),
 			Temperature: float32(payload.Temperature),
 			Humidity:    float32(payload.Humidity),
-			Pressure:    float32(payload.Pressure) / 10.0,
+			Pressure:    float32(payload.Pressure),
 			BatterVolts: float32(payload.Battery) / 1000.0,
 			Rssi:        int32(bleAdv.RSSI()),
 			Timestamp:   timestamppb.New(time.Now().Local()),
@@ -0,0 +1,226 @@
+package btlistener
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type fakeIngester struct {
+	batches []*ruuvipb.RuuviMeasurementBatch
+	status  *ruuvipb.RuuviCollectorStatus
+}
+
+func (f *fakeIngester) IngestBatch(
+	_ context.Context,
+	batch *ruuvipb.RuuviMeasurementBatch,
+) *ruuvipb.RuuviBatchAck {
+	f.batches = append(f.batches, batch)
+	return &ruuvipb.RuuviBatchAck{
+		BatchId:  batch.GetBatchId(),
+		Accepted: uint32(len(batch.GetMeasurements())), //nolint:gosec // Small in tests
+	}
+}
+
+func (f *fakeIngester) ReportStatus(
+	_ context.Context,
+	status *ruuvipb.RuuviCollectorStatus,
+) (*ruuvipb.RuuviReportStatusResponse, error) {
+	f.status = status
+	return &ruuvipb.RuuviReportStatusResponse{}, nil
+}
+
+func TestBtListener_inProcess(t *testing.T) {
+	ingester := &fakeIngester{}
+	b := newTestListener(t)
+	b.ingester = ingester
+	b.collector = "local"
+	b.pending = []*ruuvipb.RuuviMeasurementBatch{
+		{BatchId: 1, Measurements: []*ruuvipb.RuuviStreamDataRequest{{Device: "Kitchen"}}},
+		{BatchId: 2, Measurements: []*ruuvipb.RuuviStreamDataRequest{{Device: "Sauna"}}},
+	}
+
+	ctx := context.Background()
+	if err := b.SendMeasurements(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(ingester.batches) != 2 {
+		t.Errorf("ingested %d batches, want 2", len(ingester.batches))
+	}
+	if pending := b.pendingBatches(); len(pending) != 0 {
+		t.Errorf("%d batches still pending after ingest", len(pending))
+	}
+
+	if err := b.ReportStatus(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if ingester.status.GetCollector() != "local" {
+		t.Errorf("status = %v, want reported by local", ingester.status)
+	}
+}
+
+// fakeBatchServer acknowledges the batches ack accepts, or fails the stream when fail is set
+type fakeBatchServer struct {
+	ruuvipb.UnimplementedRuuviServer
+
+	mu       sync.Mutex
+	received []uint64
+	ack      func(batchID uint64) bool
+	fail     bool
+}
+
+func (f *fakeBatchServer) SendBatches(
+	stream grpc.BidiStreamingServer[ruuvipb.RuuviMeasurementBatch, ruuvipb.RuuviBatchAck],
+) error {
+	for {
+		batch, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err //nolint:wrapcheck // Returned to the client as is
+		}
+
+		f.mu.Lock()
+		f.received = append(f.received, batch.GetBatchId())
+		fail, ack := f.fail, f.ack(batch.GetBatchId())
+		f.mu.Unlock()
+		if fail {
+			return status.Error(codes.Unavailable, "server going away")
+		}
+		if !ack {
+			continue
+		}
+		if err = stream.Send(&ruuvipb.RuuviBatchAck{BatchId: batch.GetBatchId()}); err != nil {
+			return err //nolint:wrapcheck // Returned to the client as is
+		}
+	}
+}
+
+// take returns and forgets the IDs of the received batches
+func (f *fakeBatchServer) take() []uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	received := f.received
+	f.received = nil
+	return received
+}
+
+func startBatchServer(t *testing.T, fake *fakeBatchServer) ruuvipb.RuuviClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	ruuvipb.RegisterRuuviServer(server, fake)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return ruuvipb.NewRuuviClient(conn)
+}
+
+func pendingIDs(b *BtListener) []uint64 {
+	ids := []uint64{}
+	for _, batch := range b.pendingBatches() {
+		ids = append(ids, batch.GetBatchId())
+	}
+	return ids
+}
+
+func TestBtListener_enqueueBatch(t *testing.T) {
+	b := newTestListener(t)
+	b.maxPendingBatches = 144
+
+	for range 150 {
+		b.measurements.Store("aa:bb:cc:dd:ee:ff", &ruuvipb.RuuviStreamDataRequest{Device: "Kitchen"})
+		b.enqueueBatch()
+	}
+	// Nothing collected, nothing enqueued
+	b.enqueueBatch()
+
+	ids := pendingIDs(b)
+	if len(ids) != 144 {
+		t.Fatalf("%d batches pending, want 144", len(ids))
+	}
+	if ids[0] != 7 || ids[143] != 150 {
+		t.Errorf("pending batches %d..%d, want the oldest ones dropped", ids[0], ids[143])
+	}
+}
+
+func TestBtListener_SendMeasurements(t *testing.T) {
+	fake := &fakeBatchServer{ack: func(batchID uint64) bool { return batchID%2 == 0 }}
+	b := newTestListener(t)
+	b.streamerClient = startBatchServer(t, fake)
+	b.sendTimeout = 5 * time.Second
+	for id := range uint64(4) {
+		b.pending = append(b.pending, &ruuvipb.RuuviMeasurementBatch{
+			BatchId:      id + 1,
+			Measurements: []*ruuvipb.RuuviStreamDataRequest{{Device: "Kitchen"}},
+		})
+	}
+	ctx := context.Background()
+
+	// Unacknowledged batches stay pending
+	if err := b.SendMeasurements(ctx); !errors.Is(err, errUnacknowledged) {
+		t.Fatalf("SendMeasurements() = %v, want unacknowledged", err)
+	}
+	if ids := pendingIDs(b); !slices.Equal(ids, []uint64{1, 3}) {
+		t.Errorf("pending after partial acks = %v, want [1 3]", ids)
+	}
+	if got := fake.take(); !slices.Equal(got, []uint64{1, 2, 3, 4}) {
+		t.Errorf("server received %v, want [1 2 3 4]", got)
+	}
+
+	// Failed stream keeps them too
+	fake.mu.Lock()
+	fake.fail = true
+	fake.mu.Unlock()
+	if err := b.SendMeasurements(ctx); status.Code(err) != codes.Unavailable {
+		t.Fatalf("SendMeasurements() = %v, want unavailable", err)
+	}
+	if ids := pendingIDs(b); !slices.Equal(ids, []uint64{1, 3}) {
+		t.Errorf("pending after error = %v, want [1 3]", ids)
+	}
+	fake.take()
+
+	// Resent once the server acknowledges them
+	fake.mu.Lock()
+	fake.fail = false
+	fake.ack = func(uint64) bool { return true }
+	fake.mu.Unlock()
+	if err := b.SendMeasurements(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if ids := pendingIDs(b); len(ids) != 0 {
+		t.Errorf("pending after resend = %v, want none", ids)
+	}
+	if got := fake.take(); !slices.Equal(got, []uint64{1, 3}) {
+		t.Errorf("server received %v on resend, want [1 3]", got)
+	}
+}
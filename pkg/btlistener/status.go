@@ -0,0 +1,77 @@
+package btlistener
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (b *BtListener) setAdapterState(state ruuvipb.RuuviAdapterState) {
+	b.adapterState.Store(int32(state))
+}
+
+// Status returns the collector's current status
+func (b *BtListener) Status() *ruuvipb.RuuviCollectorStatus {
+	var reconnects uint64
+	if b.connManager != nil {
+		reconnects = b.connManager.Reconnects()
+	}
+	return &ruuvipb.RuuviCollectorStatus{
+		Collector:          b.collector,
+		Session:            b.session,
+		Version:            b.version,
+		Uptime:             durationpb.New(time.Since(b.started)),
+		AdapterState:       ruuvipb.RuuviAdapterState(b.adapterState.Load()),
+		AdvertisementsSeen: b.advertisementsSeen.Load(),
+		ParseErrors:        b.parseErrors.Load(),
+		QueuedBatches:      uint32(len(b.pendingBatches())), //nolint:gosec // Limited by maxPendingBatches
+		Timestamp:          timestamppb.Now(),
+		Reconnects:         reconnects,
+	}
+}
+
+// ReportStatus sends the collector's status to the server
+func (b *BtListener) ReportStatus(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+
+	var err error
+	if b.ingester != nil {
+		_, err = b.ingester.ReportStatus(ctx, b.Status())
+	} else {
+		_, err = b.streamerClient.ReportStatus(ctx, b.Status())
+	}
+	if err != nil {
+		return fmt.Errorf("report status: %w", err)
+	}
+	return nil
+}
+
+// statusLoop reports status periodically, so the server knows the collector
+// is alive even when no tags are heard
+func (b *BtListener) statusLoop(ctx context.Context) {
+	ticker := time.NewTicker(b.statusInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := b.ReportStatus(ctx); err != nil {
+			attrs := []any{slog.Any("error", err)}
+			if b.connManager != nil {
+				attrs = append(attrs, slog.String("connection_state", b.connManager.State().String()))
+			}
+			logger.Warn("Failed to report status", attrs...)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package cache
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"runtime"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+	"weezel/ruuvigraph/pkg/storage"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// casSlice is the copy-on-write slice the cache used before, kept as a baseline
+type casSlice struct {
+	data atomic.Pointer[[]*ruuvipb.RuuviStreamDataRequest]
+}
+
+func newCASSlice() *casSlice {
+	c := &casSlice{}
+	c.data.Store(&[]*ruuvipb.RuuviStreamDataRequest{})
+	return c
+}
+
+func (c *casSlice) Append(reqs ...*ruuvipb.RuuviStreamDataRequest) error {
+	for {
+		old := c.data.Load()
+		newSlice := make([]*ruuvipb.RuuviStreamDataRequest, len(*old), len(*old)+len(reqs))
+		copy(newSlice, *old)
+		newSlice = append(newSlice, reqs...)
+
+		if c.data.CompareAndSwap(old, &newSlice) {
+			return nil
+		}
+	}
+}
+
+func (c *casSlice) Range(devices []string, from, until time.Time) ([]*ruuvipb.RuuviStreamDataRequest, error) {
+	matching := []*ruuvipb.RuuviStreamDataRequest{}
+	for _, d := range *c.data.Load() {
+		if storage.Matches(d, devices) && storage.InRange(d.GetTimestamp().AsTime(), from, until) {
+			matching = append(matching, d)
+		}
+	}
+	return matching, nil
+}
+
+type benchStore interface {
+	Append(reqs ...*ruuvipb.RuuviStreamDataRequest) error
+	Range(devices []string, from, until time.Time) ([]*ruuvipb.RuuviStreamDataRequest, error)
+}
+
+const benchDevices = 10
+
+// benchData returns points measurements of benchDevices devices, one per device about every
+// ten seconds. Values drift like real ones in the resolution of the sensors.
+func benchData(points int) []*ruuvipb.RuuviStreamDataRequest {
+	rng := rand.New(rand.NewPCG(1, 2))
+	walk := func(v *float64, step, resolution float64) float32 {
+		*v += (rng.Float64() - 0.5) * step
+		return float32(math.Round(*v/resolution) * resolution)
+	}
+
+	start := time.Now().Add(-time.Duration(points) * 10 * time.Second)
+	data := make([]*ruuvipb.RuuviStreamDataRequest, 0, benchDevices*points)
+	for d := range benchDevices {
+		temperature, humidity, pressure, battery := 21.0, 40.0, 101325.0, 2.9
+		rssi := int32(-70)
+		for i := range points {
+			jitter := time.Duration(rng.IntN(int(50 * time.Millisecond)))
+			data = append(data, &ruuvipb.RuuviStreamDataRequest{
+				Device:      fmt.Sprintf("Device %d", d),
+				MacAddress:  fmt.Sprintf("aa:bb:cc:dd:ee:%02x", d),
+				Temperature: walk(&temperature, 0.05, 0.005),
+				Humidity:    walk(&humidity, 0.1, 0.0025),
+				Pressure:    walk(&pressure, 4, 1),
+				BatterVolts: walk(&battery, 0.001, 0.001),
+				Rssi:        rssi + rng.Int32N(5),
+				Timestamp:   timestamppb.New(start.Add(time.Duration(i)*10*time.Second + jitter)),
+			})
+		}
+	}
+	// Interleaved by time, like they arrive
+	slices.SortStableFunc(data, func(a, b *ruuvipb.RuuviStreamDataRequest) int {
+		return a.GetTimestamp().AsTime().Compare(b.GetTimestamp().AsTime())
+	})
+	return data
+}
+
+func benchStores() []struct {
+	name string
+	new  func() benchStore
+} {
+	return []struct {
+		name string
+		new  func() benchStore
+	}{
+		{"cas-slice", func() benchStore { return newCASSlice() }},
+		{"per-device", func() benchStore { return New() }},
+	}
+}
+
+func BenchmarkMeasurements_Append(b *testing.B) {
+	// Fewer points, since copying the whole slice on every append is quadratic
+	data := benchData(1_000)
+	for _, bs := range benchStores() {
+		b.Run(bs.name, func(b *testing.B) {
+			for b.Loop() {
+				s := bs.new()
+				for _, m := range data {
+					if err := s.Append(m); err != nil {
+						b.Fatal(err)
+					}
+				}
+				if c, ok := s.(*Measurements); ok {
+					c.Stop()
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkMeasurements_memory reports the heap used per measurement, when they're
+// unmarshalled protobuf messages and when they're encoded in the cache
+func BenchmarkMeasurements_memory(b *testing.B) {
+	encoded := make([][]byte, 0, benchDevices*10_000)
+	for _, m := range benchData(10_000) {
+		raw, err := proto.Marshal(m)
+		if err != nil {
+			b.Fatal(err)
+		}
+		encoded = append(encoded, raw)
+	}
+	// Measurements are received over the network, hence unmarshalled each on its own
+	received := func() []*ruuvipb.RuuviStreamDataRequest {
+		data := make([]*ruuvipb.RuuviStreamDataRequest, 0, len(encoded))
+		for _, raw := range encoded {
+			m := &ruuvipb.RuuviStreamDataRequest{}
+			if err := proto.Unmarshal(raw, m); err != nil {
+				b.Fatal(err)
+			}
+			data = append(data, m)
+		}
+		return data
+	}
+	heapUsed := func(store func() any) float64 {
+		var stats runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&stats)
+		before := stats.HeapAlloc
+		kept := store()
+		runtime.GC()
+		runtime.ReadMemStats(&stats)
+		runtime.KeepAlive(kept)
+		return float64(stats.HeapAlloc-before) / float64(len(encoded))
+	}
+
+	b.Run("protobuf", func(b *testing.B) {
+		var perSample float64
+		for b.Loop() {
+			perSample = heapUsed(func() any { return received() })
+		}
+		b.ReportMetric(perSample, "bytes/sample")
+	})
+	b.Run("encoded", func(b *testing.B) {
+		var perSample float64
+		for b.Loop() {
+			perSample = heapUsed(func() any {
+				m := New()
+				for _, r := range received() {
+					if err := m.Append(r); err != nil {
+						b.Fatal(err)
+					}
+				}
+				m.Stop()
+				return m
+			})
+		}
+		b.ReportMetric(perSample, "bytes/sample")
+	})
+}
+
+func BenchmarkMeasurements_Range(b *testing.B) {
+	data := benchData(10_000)
+	// The last hour of one device
+	device := []string{"Device 3"}
+	until := data[len(data)-1].GetTimestamp().AsTime()
+	from := until.Add(-time.Hour)
+	want := 0
+	for _, m := range data {
+		if storage.Matches(m, device) && storage.InRange(m.GetTimestamp().AsTime(), from, until) {
+			want++
+		}
+	}
+
+	for _, bs := range benchStores() {
+		b.Run(bs.name, func(b *testing.B) {
+			s := bs.new()
+			if err := s.Append(data...); err != nil {
+				b.Fatal(err)
+			}
+			if c, ok := s.(*Measurements); ok {
+				b.Cleanup(c.Stop)
+			}
+
+			for b.Loop() {
+				got, err := s.Range(device, from, until)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(got) != want {
+					b.Fatalf("Range() returned %d measurements, want %d", len(got), want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package cache
+
+// bitWriter appends bits most significant first
+type bitWriter struct {
+	buf  []byte
+	free int // Unused bits in the last byte
+}
+
+// writeBits writes the n low bits of v
+func (w *bitWriter) writeBits(v uint64, n int) {
+	for n > 0 {
+		if w.free == 0 {
+			w.buf = append(w.buf, 0)
+			w.free = 8
+		}
+		take := min(n, w.free)
+		chunk := byte(v>>(n-take)) & (1<<take - 1)
+		w.buf[len(w.buf)-1] |= chunk << (w.free - take)
+		w.free -= take
+		n -= take
+	}
+}
+
+func (w *bitWriter) writeBit(bit bool) {
+	if bit {
+		w.writeBits(1, 1)
+	} else {
+		w.writeBits(0, 1)
+	}
+}
+
+// bitReader reads bits written by bitWriter. Reading past the end returns zeros,
+// since only data encoded in memory is read and the counts are known.
+type bitReader struct {
+	buf []byte
+	pos int // In bits
+}
+
+func (r *bitReader) readBits(n int) uint64 {
+	var v uint64
+	for n > 0 {
+		i := r.pos / 8
+		if i >= len(r.buf) {
+			v <<= n
+			r.pos += n
+			return v
+		}
+		used := r.pos % 8
+		take := min(n, 8-used)
+		chunk := r.buf[i] >> (8 - used - take) & (1<<take - 1)
+		v = v<<take | uint64(chunk)
+		r.pos += take
+		n -= take
+	}
+	return v
+}
+
+func (r *bitReader) readBit() bool {
+	return r.readBits(1) == 1
+}
+
+// signExtend interprets the n low bits of v as a two's complement number
+func signExtend(v uint64, n int) int64 {
+	shift := 64 - n
+	return int64(v<<shift) >> shift //nolint:gosec // Sign bit is meant to wrap
+}
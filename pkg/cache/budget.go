@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// Budget bounds the raw measurements kept in memory, zero fields are unlimited.
+// Bytes are approximate, rollups aren't counted.
+type Budget struct {
+	Samples int
+	Bytes   int
+}
+
+// WithBudget evicts measurements when there are more than the budget allows. Devices
+// are trimmed fairly: the ones having the most lose their oldest measurements first, and
+// devices having less than their share aren't trimmed at all. Latest measurement of a
+// device is never evicted.
+func WithBudget(budget Budget) OptionMeasurement {
+	return func(mopt *Measurements) {
+		mopt.budget = budget
+	}
+}
+
+// budgetSlack is the fraction of the budget freed on eviction, so that
+// eviction isn't needed again on the next append
+const budgetSlack = 10
+
+// fairShare returns the largest usage per series, which keeps the total within target
+func fairShare(usages []int, target int) int {
+	sorted := slices.Sorted(slices.Values(usages))
+	for i, usage := range sorted {
+		left := len(sorted) - i
+		if usage*left > target {
+			return target / left
+		}
+		target -= usage
+	}
+	return sorted[len(sorted)-1]
+}
+
+// enforceBudget evicts measurements exceeding the budget, caller holds the lock.
+// Returns the count of evicted ones.
+func (m *Measurements) enforceBudget() int {
+	evicted := m.enforce("bytes", m.budget.Bytes, m.bytes, func(s *series) int { return s.bytes })
+	return evicted + m.enforce("samples", m.budget.Samples, m.count, func(s *series) int { return s.count })
+}
+
+// enforce evicts measurements when the total usage of series exceeds the limit.
+// The running total is checked first, series are gone through only when it does.
+func (m *Measurements) enforce(unit string, limit, total int, usage func(*series) int) int {
+	if limit <= 0 || total <= limit {
+		return 0
+	}
+
+	keys := make([]string, 0, len(m.devices))
+	usages := make([]int, 0, len(m.devices))
+	for key, s := range m.devices {
+		if s.count == 0 {
+			continue
+		}
+		keys = append(keys, key)
+		usages = append(usages, usage(s))
+	}
+
+	share := fairShare(usages, limit-limit/budgetSlack)
+	evicted := 0
+	for i, key := range keys {
+		if usages[i] <= share {
+			continue
+		}
+		s := m.devices[key]
+		// Bytes are converted to measurements by their average size
+		n := (s.count*(usages[i]-share) + usages[i] - 1) / usages[i]
+		bytes := s.bytes
+		evicted += s.evict(min(n, s.count-1), m.rollups())
+		m.bytes += s.bytes - bytes
+	}
+	m.count -= evicted
+	m.evicted += evicted
+
+	logger.Warn(
+		"Measurement budget reached, evicted oldest measurements",
+		slog.String("unit", unit),
+		slog.Int("budget", limit),
+		slog.Int("used", total),
+		slog.Int("evicted", evicted),
+		slog.Int("evicted_total", m.evicted),
+	)
+	return evicted
+}
+
+// evict removes the n oldest measurements, n is less than the count. See prune.
+// Returns the count of removed ones.
+func (s *series) evict(n int, rollup bool) int {
+	if n <= 0 {
+		return 0
+	}
+
+	// Timestamp of the first one kept, ties keep the later ones too
+	var kept time.Time
+	skip := n
+	for _, c := range s.chunks {
+		if skip < c.count {
+			kept = timestamp(s.decode(c)[skip])
+			break
+		}
+		skip -= c.count
+	}
+	if kept.IsZero() {
+		kept = timestamp(s.head[skip])
+	}
+	return s.prune(kept, rollup)
+}
@@ -0,0 +1,133 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	ruuviv1 "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestFairShare(t *testing.T) {
+	tests := []struct {
+		name   string
+		usages []int
+		target int
+		want   int
+	}{
+		{"all fit", []int{10, 20}, 100, 20},
+		{"largest trimmed", []int{10, 100}, 60, 50},
+		{"small ones kept", []int{1, 2, 100, 200}, 103, 50},
+		{"equal", []int{50, 50, 50}, 90, 30},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fairShare(tt.usages, tt.target); got != tt.want {
+				t.Errorf("fairShare() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMeasurements_budget(t *testing.T) {
+	m := New(WithBudget(Budget{Samples: 100}))
+	t.Cleanup(m.Stop)
+
+	start := time.Now().Add(-time.Hour)
+	add := func(device string, ts time.Time) {
+		if err := m.Append(&ruuviv1.RuuviStreamDataRequest{
+			Device:    device,
+			Timestamp: timestamppb.New(ts),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Quiet tag's measurements are the oldest ones
+	for i := range 5 {
+		add("Quiet", start.Add(time.Duration(i)*time.Second))
+	}
+	for i := range 1000 {
+		add("Noisy", start.Add(time.Minute+time.Duration(i)*time.Second))
+	}
+
+	if m.Len() > 100 {
+		t.Errorf("Len() = %d, want within the budget", m.Len())
+	}
+	quiet, err := m.Range([]string{"Quiet"}, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(quiet) != 5 {
+		t.Errorf("quiet tag has %d measurements left, want all 5", len(quiet))
+	}
+	noisy, err := m.Range([]string{"Noisy"}, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := start.Add(time.Minute + 999*time.Second)
+	if newest := noisy[len(noisy)-1].GetTimestamp().AsTime(); !newest.Equal(last) {
+		t.Errorf("noisy tag's newest measurement is at %v, want the last one kept", newest)
+	}
+	if stats := m.Stats(); stats.Evicted != 1005-stats.Measurements {
+		t.Errorf("Stats() evicted %d and kept %d, want them to add up to 1005",
+			stats.Evicted, stats.Measurements)
+	}
+}
+
+func TestMeasurements_budgetBytes(t *testing.T) {
+	const budget = 4096
+	m := New(WithBudget(Budget{Bytes: budget}))
+	t.Cleanup(m.Stop)
+
+	start := time.Now().Add(-time.Hour)
+	for i := range 2000 {
+		if err := m.Append(&ruuviv1.RuuviStreamDataRequest{
+			Device:      "Kitchen",
+			Temperature: float32(i%100) / 10,
+			Timestamp:   timestamppb.New(start.Add(time.Duration(i) * time.Second)),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	size := m.size()
+	if size > budget {
+		t.Errorf("size() = %d, want within %d", size, budget)
+	}
+	counted := 0
+	for _, s := range m.devices {
+		counted += s.size()
+	}
+	if size != counted {
+		t.Errorf("size() = %d, but the series count %d", size, counted)
+	}
+	if m.Stats().Evicted == 0 {
+		t.Error("nothing was evicted")
+	}
+}
+
+func TestMeasurements_budgetManyDevices(t *testing.T) {
+	m := New(WithBudget(Budget{Samples: 10}))
+	t.Cleanup(m.Stop)
+
+	for i := range 20 {
+		for j := range 3 {
+			if err := m.Append(&ruuviv1.RuuviStreamDataRequest{
+				Device:    string(rune('A' + i)),
+				Timestamp: timestamppb.New(time.Now().Add(time.Duration(j-3) * time.Minute)),
+			}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// More devices than the budget, yet every device keeps its latest measurement
+	latest, err := m.Latest(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(latest) != 20 {
+		t.Errorf("Latest() returned %d devices, want 20", len(latest))
+	}
+}
@@ -1,7 +1,10 @@
 package cache
 
 import (
+	"errors"
+	"fmt"
 	"log/slog"
+	"maps"
 	"slices"
 	"sync"
 	"sync/atomic"
@@ -9,18 +12,47 @@ import (
 
 	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
 	"weezel/ruuvigraph/pkg/logging"
+	"weezel/ruuvigraph/pkg/storage"
 )
 
 var logger *slog.Logger = logging.NewColorLogHandler()
 
+const (
+	// DefaultMaxAge is how long measurements are kept by default
+	DefaultMaxAge = 7 * 24 * time.Hour
+	// DefaultHourlyAge is how long hourly rollups are kept by default
+	DefaultHourlyAge = 90 * 24 * time.Hour
+	// DefaultDailyAge is how long daily rollups are kept by default
+	DefaultDailyAge = 5 * 365 * 24 * time.Hour
+	// DefaultLateness is how late measurements are accepted by default. Collectors
+	// keep a day of unsent batches.
+	DefaultLateness = 25 * time.Hour
+)
+
+// Measurements keeps the measurements in memory, implementing storage.Backend.
+// Measurements are indexed by device and sorted by time. Pruned measurements are
+// summarized in hourly rollups, which are later summarized in daily rollups.
 type Measurements struct {
-	ticker *time.Ticker
-	once   *sync.Once
-	quit   chan struct{}
-	data   atomic.Pointer[[]*ruuvipb.RuuviStreamDataRequest]
-	maxAge time.Duration
+	ticker    *time.Ticker
+	once      *sync.Once
+	quit      chan struct{}
+	mu        sync.RWMutex
+	devices   map[string]*series
+	count     int
+	bytes     int
+	maxAge    time.Duration
+	hourlyAge time.Duration
+	dailyAge  time.Duration
+	budget    Budget
+	lateness  time.Duration
+	rules     []RetentionRule
+	evicted   int
+	now       func() time.Time
+	running   atomic.Bool
 }
 
+var _ storage.Backend = (*Measurements)(nil)
+
 type OptionMeasurement func(mopt *Measurements)
 
 func WithTickerRate(rate time.Duration) OptionMeasurement {
@@ -35,19 +67,41 @@ func WithMaxMeasureAge(maxAge time.Duration) OptionMeasurement {
 	}
 }
 
+// WithLateness accepts measurements at most d older than the newest one of the device,
+// zero accepts any not expired
+func WithLateness(d time.Duration) OptionMeasurement {
+	return func(mopt *Measurements) {
+		mopt.lateness = d
+	}
+}
+
+// WithRollups keeps hourly rollups for hourlyAge and daily rollups for dailyAge,
+// both zero disables rollups
+func WithRollups(hourlyAge, dailyAge time.Duration) OptionMeasurement {
+	return func(mopt *Measurements) {
+		mopt.hourlyAge = hourlyAge
+		mopt.dailyAge = dailyAge
+	}
+}
+
 func New(opts ...OptionMeasurement) *Measurements {
 	m := &Measurements{
-		quit:   make(chan struct{}),
-		once:   &sync.Once{},
-		maxAge: time.Hour * 24 * 7,
-		ticker: time.NewTicker(time.Minute * 5),
+		quit:      make(chan struct{}),
+		once:      &sync.Once{},
+		devices:   map[string]*series{},
+		maxAge:    DefaultMaxAge,
+		hourlyAge: DefaultHourlyAge,
+		dailyAge:  DefaultDailyAge,
+		lateness:  DefaultLateness,
+		now:       time.Now,
+		ticker:    time.NewTicker(time.Minute * 5),
 	}
-	m.data.Store(&[]*ruuvipb.RuuviStreamDataRequest{})
 
 	for _, opt := range opts {
 		opt(m)
 	}
 
+	m.running.Store(true)
 	go m.run()
 
 	return m
@@ -61,29 +115,188 @@ func (m *Measurements) Stop() {
 	logger.Info("Shat down measurements ticker")
 }
 
-func (m *Measurements) Add(req *ruuvipb.RuuviStreamDataRequest) {
-	for {
-		old := m.data.Load()
-		newSlice := make([]*ruuvipb.RuuviStreamDataRequest, len(*old)+1)
-		copy(newSlice, *old)
-		newSlice[len(*old)] = req
+// Append stores the measurements, which must be in canonical units. Invalid ones, ones
+// older than the max age, duplicates and ones later than the lateness allows are skipped.
+func (m *Measurements) Append(reqs ...*ruuvipb.RuuviStreamDataRequest) error {
+	_, err := m.AppendAccepted(reqs...)
+	return err
+}
 
-		if m.data.CompareAndSwap(old, &newSlice) {
-			return
+// AppendAccepted is Append, which returns also the stored measurements
+func (m *Measurements) AppendAccepted(
+	reqs ...*ruuvipb.RuuviStreamDataRequest,
+) ([]*ruuvipb.RuuviStreamDataRequest, error) {
+	now := m.now()
+	errs := make([]error, len(reqs))
+	for i, req := range reqs {
+		maxAge := m.maxAgeOf(m.ruleOf(req.GetDevice(), req.GetMacAddress()))
+		errs[i] = storage.Check(req, now.Add(-maxAge))
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	accepted := make([]*ruuvipb.RuuviStreamDataRequest, 0, len(reqs))
+	for i, req := range reqs {
+		if errs[i] == nil {
+			errs[i] = m.insert(req)
+		}
+		if errs[i] != nil {
+			errs[i] = fmt.Errorf("measurement %d: %w", i, errs[i])
+			continue
+		}
+		accepted = append(accepted, req)
+	}
+	m.enforceBudget()
+
+	return accepted, errors.Join(errs...)
+}
+
+// seriesOf returns the series of the measurement's device, which is created when
+// missing. Caller holds the lock.
+func (m *Measurements) seriesOf(req *ruuvipb.RuuviStreamDataRequest) *series {
+	key := storage.DeviceKey(req)
+	s, ok := m.devices[key]
+	if !ok {
+		s = newSeries(req)
+		s.rule = m.ruleOf(s.device, s.mac)
+		m.devices[key] = s
+	}
+	return s
+}
+
+// insert adds the measurement to the series of its device, caller holds the lock
+func (m *Measurements) insert(req *ruuvipb.RuuviStreamDataRequest) error {
+	s := m.seriesOf(req)
+	if s.count > 0 && m.lateness > 0 && timestamp(req).Before(s.newest().Add(-m.lateness)) {
+		return storage.ErrTooLate
+	}
+	if s.contains(req) {
+		return storage.ErrDuplicate
+	}
+	bytes := s.bytes
+	s.insert(req)
+	m.count++
+	m.bytes += s.bytes - bytes
+	return nil
+}
+
+// matching returns the series of the devices in key order, caller holds the lock
+func (m *Measurements) matching(devices []string) []*series {
+	keys := slices.Sorted(maps.Keys(m.devices))
+	matching := make([]*series, 0, len(keys))
+	for _, key := range keys {
+		if s := m.devices[key]; s.matches(devices) {
+			matching = append(matching, s)
+		}
+	}
+	return matching
+}
+
+// Range returns the measurements of the devices between from and until, see storage.Backend
+func (m *Measurements) Range(
+	devices []string,
+	from, until time.Time,
+) ([]*ruuvipb.RuuviStreamDataRequest, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	matching := []*ruuvipb.RuuviStreamDataRequest{}
+	for _, s := range m.matching(devices) {
+		matching = append(matching, s.between(from, until)...)
+	}
+	return matching, nil
+}
+
+// Latest returns the latest measurement of each device, see storage.Backend
+func (m *Measurements) Latest(devices []string) ([]*ruuvipb.RuuviStreamDataRequest, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	latest := []*ruuvipb.RuuviStreamDataRequest{}
+	for _, s := range m.matching(devices) {
+		if m := s.latest(); m != nil {
+			latest = append(latest, m)
+		}
+	}
+	return latest, nil
+}
+
+func (m *Measurements) Stats() storage.Stats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stats := storage.Stats{Measurements: m.count, Evicted: m.evicted}
+	for _, s := range m.devices {
+		if s.count == 0 {
+			continue
+		}
+		stats.Devices++
+		oldest, newest := s.oldest(), s.newest()
+		if stats.Oldest.IsZero() || oldest.Before(stats.Oldest) {
+			stats.Oldest = oldest
+		}
+		if newest.After(stats.Newest) {
+			stats.Newest = newest
 		}
 	}
+	return stats
 }
 
+// Close stops the pruning loop
+func (m *Measurements) Close() error {
+	m.Stop()
+	return nil
+}
+
+// All returns a copy of the stored measurements, grouped by device
 func (m *Measurements) All() []*ruuvipb.RuuviStreamDataRequest {
-	data := m.data.Load()
-	// Return a copy to prevent external mutation
-	copied := make([]*ruuvipb.RuuviStreamDataRequest, len(*data))
-	copy(copied, *data)
-	return copied
+	all, _ := m.Range(nil, time.Time{}, time.Time{})
+	return all
+}
+
+// Len returns the count of stored measurements
+func (m *Measurements) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.count
+}
+
+// DeviceCount returns the count of distinct devices having stored measurements
+func (m *Measurements) DeviceCount() int {
+	return m.Stats().Devices
+}
+
+// size returns approximately the bytes used by the measurements
+func (m *Measurements) size() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.bytes
+}
+
+// MaxAge returns how long measurements of any device are kept at most
+func (m *Measurements) MaxAge() time.Duration {
+	maxAge := m.maxAge
+	for _, r := range m.rules {
+		maxAge = max(maxAge, r.MaxAge)
+	}
+	return maxAge
+}
+
+func (m *Measurements) rollups() bool {
+	return m.hourlyAge > 0 || m.dailyAge > 0
+}
+
+// Running reports whether the pruning loop is still running
+func (m *Measurements) Running() bool {
+	return m.running.Load()
 }
 
 func (m *Measurements) run() {
 	defer func() {
+		m.running.Store(false)
 		m.ticker.Stop()
 		close(m.quit)
 	}()
@@ -95,12 +308,11 @@ func (m *Measurements) run() {
 		case <-m.ticker.C:
 			logger.Info(
 				"Cleaning old measurements",
-				slog.Int("len", len(*m.data.Load())),
+				slog.Int("len", m.Len()),
+				slog.Int("bytes", m.size()),
 			)
 
-			countBefore := len(*m.data.Load())
-			m.pruneOldData()
-			removedItems := len(*m.data.Load()) - countBefore
+			removedItems := m.pruneOldData()
 			logger.Info(
 				"Cleaned old measurements",
 				slog.Int("removed_items", removedItems),
@@ -111,26 +323,44 @@ func (m *Measurements) run() {
 
 // pruneOldData method will be run by the ticker and is executed in scheduled manner.
 // There shouldn't be a need to run this manually.
-func (m *Measurements) pruneOldData() {
-	cutoff := time.Now().Add(-m.maxAge)
+func (m *Measurements) pruneOldData() int {
+	removed := m.pruneExpired()
+	if len(m.rules) > 0 {
+		m.logRetention(removed)
+	}
 
-	for {
-		old := m.data.Load()
-		original := *old
-
-		// Copy original slice to avoid mutating shared memory
-		copied := make([]*ruuvipb.RuuviStreamDataRequest, len(original))
-		copy(copied, original)
-
-		cleaned := slices.DeleteFunc(copied, func(d *ruuvipb.RuuviStreamDataRequest) bool {
-			if d.Timestamp == nil {
-				return true
-			}
-			return d.Timestamp.AsTime().Before(cutoff)
-		})
-
-		if m.data.CompareAndSwap(old, &cleaned) {
-			break
-		}
+	total := 0
+	for _, n := range removed {
+		total += n
+	}
+	return total
+}
+
+// Prune removes measurements older than the cutoff, see storage.Backend. Removed
+// measurements are rolled up and expired rollups pruned too.
+func (m *Measurements) Prune(cutoff time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	removed := 0
+	for key, s := range m.devices {
+		removed += m.pruneSeries(key, s, cutoff, now)
+	}
+
+	return removed, nil
+}
+
+// pruneSeries removes measurements of the series older than the cutoff, and the series
+// if nothing is left. Caller holds the lock. Returns the count of removed measurements.
+func (m *Measurements) pruneSeries(key string, s *series, cutoff, now time.Time) int {
+	bytes := s.bytes
+	removed := s.prune(cutoff, m.rollups())
+	m.bytes += s.bytes - bytes
+	s.pruneRollups(now.Add(-m.hourlyAge), now.Add(-m.dailyAge))
+	if s.empty() {
+		delete(m.devices, key)
 	}
+	m.count -= removed
+	return removed
 }
@@ -1,11 +1,15 @@
 package cache
 
 import (
+	"errors"
+	"slices"
 	"sync"
 	"testing"
 	"time"
 
 	ruuviv1 "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+	"weezel/ruuvigraph/pkg/storage"
+	"weezel/ruuvigraph/pkg/storage/storagetest"
 
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
@@ -19,6 +23,11 @@ func TestMeasurements_pruneOldData(t *testing.T) {
 
 	started := time.Now()
 	m := New(WithTickerRate(time.Second*1), WithMaxMeasureAge(time.Second*2))
+	add := func(ts time.Time) {
+		if err := m.Append(&ruuviv1.RuuviStreamDataRequest{Timestamp: timestamppb.New(ts)}); err != nil {
+			t.Error(err)
+		}
+	}
 
 	wg := sync.WaitGroup{}
 	wg.Add(1)
@@ -27,17 +36,11 @@ func TestMeasurements_pruneOldData(t *testing.T) {
 		for i := range 5000 {
 			switch {
 			case i%2 == 0:
-				m.Add(&ruuviv1.RuuviStreamDataRequest{
-					Timestamp: timestamppb.New(time.Now().Add(time.Second - 2)),
-				})
+				add(time.Now().Add(time.Second - 2))
 			case i%3 == 0:
-				m.Add(&ruuviv1.RuuviStreamDataRequest{
-					Timestamp: timestamppb.New(time.Now().Add(time.Second - 3)),
-				})
+				add(time.Now().Add(time.Second - 3))
 			default:
-				m.Add(&ruuviv1.RuuviStreamDataRequest{
-					Timestamp: timestamppb.New(time.Now().Add(time.Second - 1)),
-				})
+				add(time.Now().Add(time.Second - 1))
 			}
 		}
 	}()
@@ -48,17 +51,11 @@ func TestMeasurements_pruneOldData(t *testing.T) {
 		for i := range 5000 {
 			switch {
 			case i%2 == 0:
-				m.Add(&ruuviv1.RuuviStreamDataRequest{
-					Timestamp: timestamppb.New(time.Now().Add(time.Second - 2)),
-				})
+				add(time.Now().Add(time.Second - 2))
 			case i%3 == 0:
-				m.Add(&ruuviv1.RuuviStreamDataRequest{
-					Timestamp: timestamppb.New(time.Now().Add(time.Second - 3)),
-				})
+				add(time.Now().Add(time.Second - 3))
 			default:
-				m.Add(&ruuviv1.RuuviStreamDataRequest{
-					Timestamp: timestamppb.New(time.Now().Add(time.Second - 1)),
-				})
+				add(time.Now().Add(time.Second - 1))
 			}
 		}
 	}()
@@ -81,3 +78,146 @@ func TestMeasurements_pruneOldData(t *testing.T) {
 	}
 	t.Logf("Took %s", time.Since(started))
 }
+
+func TestMeasurements_Append(t *testing.T) {
+	m := New()
+	t.Cleanup(m.Stop)
+
+	if err := m.Append(&ruuviv1.RuuviStreamDataRequest{
+		Pressure:  101320,
+		Timestamp: timestamppb.Now(),
+	}); err != nil {
+		t.Errorf("Append() pascals error = %v", err)
+	}
+	// Hectopascals aren't canonical
+	if err := m.Append(&ruuviv1.RuuviStreamDataRequest{
+		Pressure:  1013.2,
+		Timestamp: timestamppb.Now(),
+	}); err == nil {
+		t.Error("Append() hectopascals succeeded, want error")
+	}
+	if m.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", m.Len())
+	}
+}
+
+func TestMeasurements_conformance(t *testing.T) {
+	storagetest.Run(t, func(*testing.T) storage.Backend {
+		return New()
+	})
+}
+
+func TestMeasurements_outOfOrder(t *testing.T) {
+	m := New()
+	t.Cleanup(m.Stop)
+
+	now := time.Now().Truncate(time.Second)
+	for i, offset := range []time.Duration{0, -3 * time.Minute, time.Minute, -time.Minute, -time.Minute} {
+		if err := m.Append(&ruuviv1.RuuviStreamDataRequest{
+			Device:    "Kitchen",
+			Humidity:  float32(i),
+			Timestamp: timestamppb.New(now.Add(offset)),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all := m.All()
+	if len(all) != 5 {
+		t.Fatalf("All() returned %d measurements, want 5", len(all))
+	}
+	if !slices.IsSortedFunc(all, func(a, b *ruuviv1.RuuviStreamDataRequest) int {
+		return a.GetTimestamp().AsTime().Compare(b.GetTimestamp().AsTime())
+	}) {
+		t.Errorf("All() = %v, want sorted by time", all)
+	}
+
+	got, err := m.Range(nil, now.Add(-time.Minute), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 {
+		t.Errorf("Range() returned %d measurements, want 3", len(got))
+	}
+}
+
+func TestMeasurements_interleavedCollectors(t *testing.T) {
+	m := New(WithLateness(time.Hour))
+	t.Cleanup(m.Stop)
+
+	start := time.Now().Add(-2 * time.Hour).Truncate(time.Second)
+	reading := func(device string, minute int, rssi int32) *ruuviv1.RuuviStreamDataRequest {
+		return &ruuviv1.RuuviStreamDataRequest{
+			Device:      device,
+			Temperature: 20 + float32(minute)/10,
+			Rssi:        rssi,
+			Timestamp:   timestamppb.New(start.Add(time.Duration(minute) * time.Minute)),
+		}
+	}
+	batch := func(device string, from, until int, rssi int32) []*ruuviv1.RuuviStreamDataRequest {
+		ms := []*ruuviv1.RuuviStreamDataRequest{}
+		for minute := from; minute < until; minute++ {
+			ms = append(ms, reading(device, minute, rssi))
+		}
+		return ms
+	}
+
+	// Garage collector sends every ten minutes, living room one was offline for half an hour
+	// and sends what it buffered. Both hear the Hallway tag.
+	for minute := 0; minute < 60; minute += 10 {
+		if err := m.Append(batch("Garage", minute, minute+10, -60)...); err != nil {
+			t.Fatal(err)
+		}
+		if minute == 30 {
+			if err := m.Append(batch("Hallway", 0, 30, -80)...); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := m.Append(batch("Hallway", 30, 60, -80)...); err != nil {
+		t.Fatal(err)
+	}
+	// Garage collector hears the Hallway tag too, with a weaker signal
+	for minute := 0; minute < 60; minute += 20 {
+		if err := m.Append(batch("Hallway", minute, minute+20, -90)...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, device := range []string{"Garage", "Hallway"} {
+		got, err := m.Range([]string{device}, time.Time{}, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.IsSortedFunc(got, func(a, b *ruuviv1.RuuviStreamDataRequest) int {
+			return a.GetTimestamp().AsTime().Compare(b.GetTimestamp().AsTime())
+		}) {
+			t.Errorf("%s measurements aren't sorted by time", device)
+		}
+	}
+	if m.Len() != 60+120 {
+		t.Errorf("Len() = %d, want 60 from Garage and 120 from Hallway", m.Len())
+	}
+
+	// Replayed batch is refused as a whole
+	err := m.Append(batch("Garage", 10, 20, -60)...)
+	if !errors.Is(err, storage.ErrDuplicate) {
+		t.Errorf("Append() of a replayed batch error = %v, want ErrDuplicate", err)
+	}
+	if m.Len() != 180 {
+		t.Errorf("Len() = %d after a replay, want 180", m.Len())
+	}
+
+	// Later than the lateness allows
+	err = m.Append(&ruuviv1.RuuviStreamDataRequest{
+		Device:    "Garage",
+		Timestamp: timestamppb.New(start.Add(-10 * time.Minute)),
+	})
+	if !errors.Is(err, storage.ErrTooLate) {
+		t.Errorf("Append() of a too late measurement error = %v, want ErrTooLate", err)
+	}
+	// New device has nothing newer
+	if err = m.Append(reading("Attic", -10, -70)); err != nil {
+		t.Errorf("Append() of a new device error = %v", err)
+	}
+}
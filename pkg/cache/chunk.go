@@ -0,0 +1,293 @@
+package cache
+
+import (
+	"math"
+	"math/bits"
+	"time"
+
+	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// chunkSize is the count of measurements encoded together. Decoding is needed
+// for any of them, hence larger chunks compress better but are slower to query.
+const chunkSize = 128
+
+// Columns of a chunk
+const (
+	columnTimestamp = iota
+	columnTemperature
+	columnHumidity
+	columnPressure
+	columnBattery
+	columnRSSI
+	columns
+)
+
+// chunk is an encoded sequence of measurements of a device sorted by time.
+// Every quantity has a column of its own: timestamps are encoded as deltas of
+// deltas and floats XORed with the previous value, as described in the paper
+// "Gorilla: A Fast, Scalable, In-Memory Time Series Database". Columns are
+// stored one after another in data.
+type chunk struct {
+	first int64 // Unix nanoseconds
+	last  int64
+	count int
+	data  []byte
+	ends  [columns]uint32
+}
+
+// Widths of delta of delta buckets. Zero is encoded as 0, bucket i as i+1 ones and
+// a zero, and the rest as ones for each bucket and one more followed by 64 bits.
+// Timestamps are nanoseconds and jittery, hence wider than in the paper.
+var dodWidths = []int{20, 32, 44}
+
+func encodeChunk(points []*ruuvipb.RuuviStreamDataRequest) *chunk {
+	var w [columns]bitWriter
+	var tsEnc timestampEncoder
+	var temperatureEnc, humidityEnc, pressureEnc, batteryEnc xorEncoder
+	var rssiEnc rssiEncoder
+	for _, m := range points {
+		tsEnc.encode(&w[columnTimestamp], timestamp(m).UnixNano())
+		temperatureEnc.encode(&w[columnTemperature], m.GetTemperature())
+		humidityEnc.encode(&w[columnHumidity], m.GetHumidity())
+		pressureEnc.encode(&w[columnPressure], m.GetPressure())
+		batteryEnc.encode(&w[columnBattery], m.GetBatterVolts())
+		rssiEnc.encode(&w[columnRSSI], m.GetRssi())
+	}
+
+	c := &chunk{
+		first: timestamp(points[0]).UnixNano(),
+		last:  timestamp(points[len(points)-1]).UnixNano(),
+		count: len(points),
+	}
+	size := 0
+	for i := range w {
+		size += len(w[i].buf)
+	}
+	c.data = make([]byte, 0, size)
+	for i := range w {
+		c.data = append(c.data, w[i].buf...)
+		c.ends[i] = uint32(len(c.data)) //nolint:gosec // Chunks are far smaller
+	}
+	return c
+}
+
+func (c *chunk) column(i int) *bitReader {
+	start := uint32(0)
+	if i > 0 {
+		start = c.ends[i-1]
+	}
+	return &bitReader{buf: c.data[start:c.ends[i]]}
+}
+
+// decode returns the measurements, which have the device and MAC of the series
+func (c *chunk) decode(device, mac string) []*ruuvipb.RuuviStreamDataRequest {
+	ts := c.column(columnTimestamp)
+	temperature := c.column(columnTemperature)
+	humidity := c.column(columnHumidity)
+	pressure := c.column(columnPressure)
+	battery := c.column(columnBattery)
+	rssi := c.column(columnRSSI)
+	var tsDec timestampDecoder
+	var temperatureDec, humidityDec, pressureDec, batteryDec xorDecoder
+	var rssiDec rssiDecoder
+
+	points := make([]*ruuvipb.RuuviStreamDataRequest, c.count)
+	for i := range points {
+		points[i] = &ruuvipb.RuuviStreamDataRequest{
+			Device:      device,
+			MacAddress:  mac,
+			Temperature: temperatureDec.decode(temperature),
+			Humidity:    humidityDec.decode(humidity),
+			Pressure:    pressureDec.decode(pressure),
+			BatterVolts: batteryDec.decode(battery),
+			Rssi:        rssiDec.decode(rssi),
+			Timestamp:   timestamppb.New(time.Unix(0, tsDec.decode(ts))),
+		}
+	}
+	return points
+}
+
+// size returns the bytes used by the encoded columns
+func (c *chunk) size() int {
+	return len(c.data)
+}
+
+type timestampEncoder struct {
+	prev  int64
+	delta int64
+	n     int
+}
+
+func (e *timestampEncoder) encode(w *bitWriter, ts int64) {
+	defer func() { e.n++ }()
+	if e.n == 0 {
+		w.writeBits(uint64(ts), 64) //nolint:gosec // Bits are kept as is
+		e.prev = ts
+		return
+	}
+
+	delta := ts - e.prev
+	dod := delta - e.delta
+	e.prev, e.delta = ts, delta
+	if dod == 0 {
+		w.writeBit(false)
+		return
+	}
+	for i, width := range dodWidths {
+		if dod >= -1<<(width-1) && dod < 1<<(width-1) {
+			// i+1 ones and a zero
+			w.writeBits(1<<(i+2)-2, i+2)
+			w.writeBits(uint64(dod), width) //nolint:gosec // Two's complement
+			return
+		}
+	}
+	w.writeBits(1<<(len(dodWidths)+1)-1, len(dodWidths)+1)
+	w.writeBits(uint64(dod), 64) //nolint:gosec // Two's complement
+}
+
+type timestampDecoder struct {
+	prev  int64
+	delta int64
+	n     int
+}
+
+func (d *timestampDecoder) decode(r *bitReader) int64 {
+	defer func() { d.n++ }()
+	if d.n == 0 {
+		d.prev = int64(r.readBits(64)) //nolint:gosec // Bits are kept as is
+		return d.prev
+	}
+
+	ones := 0
+	for ones <= len(dodWidths) && r.readBit() {
+		ones++
+	}
+	var dod int64
+	switch ones {
+	case 0:
+	case len(dodWidths) + 1:
+		dod = int64(r.readBits(64)) //nolint:gosec // Two's complement
+	default:
+		width := dodWidths[ones-1]
+		dod = signExtend(r.readBits(width), width)
+	}
+	d.delta += dod
+	d.prev += d.delta
+	return d.prev
+}
+
+// xorEncoder encodes floats as XOR with the previous value. Zero XOR is a single
+// bit, otherwise the meaningful bits are written within the previous window when
+// they fit, or with a new window of 5 bits of leading zeros and 5 bits of length.
+type xorEncoder struct {
+	prev     uint32
+	leading  int
+	trailing int
+	window   bool
+	n        int
+}
+
+func (e *xorEncoder) encode(w *bitWriter, v float32) {
+	defer func() { e.n++ }()
+	value := math.Float32bits(v)
+	if e.n == 0 {
+		w.writeBits(uint64(value), 32)
+		e.prev = value
+		return
+	}
+
+	x := value ^ e.prev
+	e.prev = value
+	if x == 0 {
+		w.writeBit(false)
+		return
+	}
+	w.writeBit(true)
+
+	leading, trailing := bits.LeadingZeros32(x), bits.TrailingZeros32(x)
+	if e.window && leading >= e.leading && trailing >= e.trailing {
+		w.writeBit(false)
+		w.writeBits(uint64(x>>e.trailing), 32-e.leading-e.trailing)
+		return
+	}
+	w.writeBit(true)
+	length := 32 - leading - trailing
+	w.writeBits(uint64(leading), 5)  //nolint:gosec // At most 31, x isn't zero
+	w.writeBits(uint64(length-1), 5) //nolint:gosec // Between 0 and 31
+	w.writeBits(uint64(x>>trailing), length)
+	e.leading, e.trailing, e.window = leading, trailing, true
+}
+
+type xorDecoder struct {
+	prev     uint32
+	leading  int
+	trailing int
+	n        int
+}
+
+func (d *xorDecoder) decode(r *bitReader) float32 {
+	defer func() { d.n++ }()
+	if d.n == 0 {
+		d.prev = uint32(r.readBits(32)) //nolint:gosec // 32 bits were read
+		return math.Float32frombits(d.prev)
+	}
+
+	if !r.readBit() {
+		return math.Float32frombits(d.prev)
+	}
+	if r.readBit() {
+		d.leading = int(r.readBits(5))
+		length := int(r.readBits(5)) + 1
+		d.trailing = 32 - d.leading - length
+	}
+	x := uint32(r.readBits(32-d.leading-d.trailing)) << d.trailing //nolint:gosec // At most 32 bits were read
+	d.prev ^= x
+	return math.Float32frombits(d.prev)
+}
+
+// rssiEncoder encodes an unchanged RSSI as 0, a change fitting a byte as 10 and
+// the change, and others as 11 and the value
+type rssiEncoder struct {
+	prev int32
+}
+
+func (e *rssiEncoder) encode(w *bitWriter, rssi int32) {
+	delta := int64(rssi) - int64(e.prev)
+	e.prev = rssi
+	switch {
+	case delta == 0:
+		w.writeBit(false)
+	case delta >= math.MinInt8 && delta <= math.MaxInt8:
+		w.writeBits(0b10, 2)
+		w.writeBits(uint64(delta), 8) //nolint:gosec // Two's complement
+	default:
+		w.writeBits(0b11, 2)
+		w.writeBits(uint64(uint32(rssi)), 32) //nolint:gosec // Two's complement
+	}
+}
+
+type rssiDecoder struct {
+	prev int32
+}
+
+func (d *rssiDecoder) decode(r *bitReader) int32 {
+	switch {
+	case !r.readBit():
+	case !r.readBit():
+		d.prev += int32(signExtend(r.readBits(8), 8)) //nolint:gosec // Fits a byte
+	default:
+		d.prev = int32(uint32(r.readBits(32))) //nolint:gosec // Two's complement
+	}
+	return d.prev
+}
+
+const (
+	// pointSize is about the bytes used by a measurement before encoding,
+	// including its timestamp, strings and pointer
+	pointSize = 224
+	// chunkOverhead is about the bytes used by a chunk besides the columns
+	chunkOverhead = 96
+)
@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	ruuviv1 "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestChunk_roundTrip(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	ts := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	gaps := []time.Duration{
+		0, time.Nanosecond, 10 * time.Second, 10*time.Second + 7*time.Millisecond,
+		time.Hour, 30 * 24 * time.Hour, 365 * 24 * time.Hour,
+	}
+	floats := []float32{0, -0, 21.5, -40.25, 101325, math.MaxFloat32, math.SmallestNonzeroFloat32}
+	rssis := []int32{0, -70, -71, -127, math.MaxInt32, math.MinInt32}
+
+	points := []*ruuviv1.RuuviStreamDataRequest{}
+	for range 1000 {
+		ts = ts.Add(gaps[rng.IntN(len(gaps))] + time.Duration(rng.IntN(1000)))
+		points = append(points, &ruuviv1.RuuviStreamDataRequest{
+			Device:      "Kitchen",
+			MacAddress:  "aa:bb:cc:dd:ee:ff",
+			Temperature: floats[rng.IntN(len(floats))],
+			Humidity:    rng.Float32() * 100,
+			Pressure:    floats[rng.IntN(len(floats))],
+			BatterVolts: 2.9,
+			Rssi:        rssis[rng.IntN(len(rssis))],
+			Timestamp:   timestamppb.New(ts),
+		})
+	}
+
+	c := encodeChunk(points)
+	decoded := c.decode("Kitchen", "aa:bb:cc:dd:ee:ff")
+	if len(decoded) != len(points) {
+		t.Fatalf("decoded %d measurements, want %d", len(decoded), len(points))
+	}
+	for i := range points {
+		if !proto.Equal(decoded[i], points[i]) {
+			t.Fatalf("measurement %d = %v, want %v", i, decoded[i], points[i])
+		}
+	}
+	if c.first != points[0].GetTimestamp().AsTime().UnixNano() ||
+		c.last != points[len(points)-1].GetTimestamp().AsTime().UnixNano() {
+		t.Errorf("chunk covers %d-%d, want the first and last timestamps", c.first, c.last)
+	}
+}
+
+func TestMeasurements_lateIntoChunk(t *testing.T) {
+	m := New()
+	t.Cleanup(m.Stop)
+
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	add := func(i int) {
+		if err := m.Append(&ruuviv1.RuuviStreamDataRequest{
+			Device:    "Kitchen",
+			Humidity:  float32(i) / 10,
+			Timestamp: timestamppb.New(start.Add(time.Duration(i) * time.Second)),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Even seconds first, sealing chunks, then odd ones arriving late
+	for i := 0; i < 3*chunkSize; i += 2 {
+		add(i)
+	}
+	for i := 1; i < 3*chunkSize; i += 2 {
+		add(i)
+	}
+
+	all := m.All()
+	if len(all) != 3*chunkSize {
+		t.Fatalf("All() returned %d measurements, want %d", len(all), 3*chunkSize)
+	}
+	for i, d := range all {
+		if d.GetHumidity() != float32(i)/10 {
+			t.Fatalf("measurement %d has humidity %v, want them in order", i, d.GetHumidity())
+		}
+	}
+
+	got, err := m.Range(nil, start.Add(100*time.Second), start.Add(300*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 201 {
+		t.Errorf("Range() returned %d measurements, want 201", len(got))
+	}
+
+	removed, err := m.Prune(start.Add(150 * time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 150 || m.Len() != 3*chunkSize-150 {
+		t.Errorf("Prune() removed %d and left %d, want 150 removed", removed, m.Len())
+	}
+	if oldest := m.Stats().Oldest; !oldest.Equal(start.Add(150 * time.Second)) {
+		t.Errorf("oldest after Prune() = %v, want the cutoff", oldest)
+	}
+}
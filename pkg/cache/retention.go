@@ -0,0 +1,130 @@
+package cache
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// RetentionRule keeps measurements of the devices for MaxAge instead of the default max age
+type RetentionRule struct {
+	// Name identifies the rule in logs
+	Name string
+	// Devices are names or MAC addresses
+	Devices []string
+	MaxAge  time.Duration
+}
+
+// WithRetentionRules keeps measurements of the devices matched by a rule for the
+// rule's max age. First matching rule applies.
+func WithRetentionRules(rules ...RetentionRule) OptionMeasurement {
+	return func(mopt *Measurements) {
+		mopt.rules = rules
+	}
+}
+
+// ReadRetentionRules reads rules from lines of kind|value|max age, where kind is
+// device, mac or group, and groups are resolved from the given ones. Empty lines and
+// lines starting with # are skipped.
+func ReadRetentionRules(filename string, groups map[string][]string) ([]RetentionRule, error) {
+	file, err := os.Open(filepath.Clean(filename))
+	if err != nil {
+		return nil, fmt.Errorf("file open: %w", err)
+	}
+	defer file.Close()
+
+	rules := []RetentionRule{}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		splt := strings.Split(text, "|")
+		if len(splt) != 3 {
+			return nil, fmt.Errorf("line %d: malformed rule %q", line, text)
+		}
+		kind, value := splt[0], splt[1]
+
+		maxAge, err := time.ParseDuration(splt[2])
+		if err != nil || maxAge <= 0 {
+			return nil, fmt.Errorf("line %d: invalid max age %q", line, splt[2])
+		}
+		rule := RetentionRule{
+			Name:   kind + " " + value,
+			MaxAge: maxAge,
+		}
+		switch kind {
+		case "device", "mac":
+			rule.Devices = []string{value}
+		case "group":
+			members, found := groups[value]
+			if !found {
+				return nil, fmt.Errorf("line %d: unknown group %q", line, value)
+			}
+			rule.Devices = members
+		default:
+			return nil, fmt.Errorf("line %d: unknown kind %q, want device, mac or group", line, kind)
+		}
+		rules = append(rules, rule)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+// ruleOf returns the index of the first rule matching the device, -1 if none
+func (m *Measurements) ruleOf(device, mac string) int {
+	return slices.IndexFunc(m.rules, func(r RetentionRule) bool {
+		return slices.Contains(r.Devices, device) || slices.Contains(r.Devices, mac)
+	})
+}
+
+func (m *Measurements) maxAgeOf(rule int) time.Duration {
+	if rule < 0 {
+		return m.maxAge
+	}
+	return m.rules[rule].MaxAge
+}
+
+// pruneExpired removes measurements older than their devices are kept. Returns the
+// count removed by each rule, the last count is by the default max age.
+func (m *Measurements) pruneExpired() []int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	removed := make([]int, len(m.rules)+1)
+	for key, s := range m.devices {
+		n := m.pruneSeries(key, s, now.Add(-m.maxAgeOf(s.rule)), now)
+		if s.rule < 0 {
+			removed[len(m.rules)] += n
+		} else {
+			removed[s.rule] += n
+		}
+	}
+	return removed
+}
+
+// logRetention logs the counts removed by each rule
+func (m *Measurements) logRetention(removed []int) {
+	for i, n := range removed {
+		name, maxAge := "default", m.maxAge
+		if i < len(m.rules) {
+			name, maxAge = m.rules[i].Name, m.rules[i].MaxAge
+		}
+		logger.Info(
+			"Applied retention rule",
+			slog.String("rule", name),
+			slog.Duration("max_age", maxAge),
+			slog.Int("removed_items", n),
+		)
+	}
+}
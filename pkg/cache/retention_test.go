@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	ruuviv1 "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestMeasurements_retentionRules(t *testing.T) {
+	now := time.Now()
+	m := New(
+		WithMaxMeasureAge(7*24*time.Hour),
+		WithLateness(0),
+		WithRollups(0, 0),
+		WithRetentionRules(
+			RetentionRule{Name: "freezer", Devices: []string{"Freezer"}, MaxAge: 365 * 24 * time.Hour},
+			RetentionRule{Name: "test tags", Devices: []string{"aa:bb:cc:dd:ee:01"}, MaxAge: time.Hour},
+		),
+	)
+	t.Cleanup(m.Stop)
+	m.now = func() time.Time { return now }
+
+	if got := m.MaxAge(); got != 365*24*time.Hour {
+		t.Errorf("MaxAge() = %v, want the longest rule", got)
+	}
+
+	maxAges := map[string]time.Duration{
+		"Freezer": 365 * 24 * time.Hour,
+		"Kitchen": 7 * 24 * time.Hour,
+		"Test":    time.Hour,
+	}
+	for device, maxAge := range maxAges {
+		for _, age := range []time.Duration{30 * 24 * time.Hour, 2 * 24 * time.Hour, 2 * time.Hour, 0} {
+			err := m.Append(&ruuviv1.RuuviStreamDataRequest{
+				Device:     device,
+				MacAddress: map[string]string{"Test": "aa:bb:cc:dd:ee:01"}[device],
+				Timestamp:  timestamppb.New(now.Add(-age)),
+			})
+			if want := age < maxAge; (err == nil) != want {
+				t.Errorf("Append(%s, %v ago) error = %v", device, age, err)
+			}
+		}
+	}
+
+	now = now.Add(2 * time.Hour)
+	if removed := m.pruneExpired(); !slices.Equal(removed, []int{0, 1, 0}) {
+		t.Errorf("pruneExpired() = %v, want [0 1 0]", removed)
+	}
+	for device, want := range map[string]int{"Freezer": 4, "Kitchen": 3, "Test": 0} {
+		got, err := m.Range([]string{device}, time.Time{}, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != want {
+			t.Errorf("%s has %d measurements, want %d", device, len(got), want)
+		}
+	}
+
+	now = now.Add(6 * 24 * time.Hour)
+	if removed := m.pruneOldData(); removed != 1 {
+		t.Errorf("pruneOldData() = %d, want 1", removed)
+	}
+}
+
+func TestReadRetentionRules(t *testing.T) {
+	groups := map[string][]string{"lab": {"Lab 1", "aa:bb:cc:dd:ee:02"}}
+	tests := []struct {
+		name    string
+		rules   string
+		want    []RetentionRule
+		wantErr bool
+	}{
+		{
+			"all kinds",
+			"# Comment\n\ndevice|Freezer|8760h\nmac|aa:bb:cc:dd:ee:01|1h\ngroup|lab|24h\n",
+			[]RetentionRule{
+				{"device Freezer", []string{"Freezer"}, 8760 * time.Hour},
+				{"mac aa:bb:cc:dd:ee:01", []string{"aa:bb:cc:dd:ee:01"}, time.Hour},
+				{"group lab", []string{"Lab 1", "aa:bb:cc:dd:ee:02"}, 24 * time.Hour},
+			},
+			false,
+		},
+		{"unknown group", "group|attic|24h\n", nil, true},
+		{"unknown kind", "tag|Freezer|24h\n", nil, true},
+		{"invalid max age", "device|Freezer|forever\n", nil, true},
+		{"negative max age", "device|Freezer|-1h\n", nil, true},
+		{"malformed", "device|Freezer\n", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fname := filepath.Join(t.TempDir(), "retention.conf")
+			if err := os.WriteFile(fname, []byte(tt.rules), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ReadRetentionRules(fname, groups)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadRetentionRules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.EqualFunc(got, tt.want, func(a, b RetentionRule) bool {
+				return a.Name == b.Name && a.MaxAge == b.MaxAge && slices.Equal(a.Devices, b.Devices)
+			}) {
+				t.Errorf("ReadRetentionRules() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,320 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+	"weezel/ruuvigraph/pkg/storage"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Day is the period of daily rollups. Days are UTC days.
+const Day = 24 * time.Hour
+
+var errRollupPeriod = errors.New("rollup period shorter than an hour")
+
+// Aggregate is the minimum, maximum and sum of a quantity over a period
+type Aggregate struct {
+	Min   float64
+	Max   float64
+	Sum   float64
+	Count int
+}
+
+func (a *Aggregate) add(v float64) {
+	a.merge(Aggregate{Min: v, Max: v, Sum: v, Count: 1})
+}
+
+func (a *Aggregate) merge(b Aggregate) {
+	switch {
+	case b.Count == 0:
+		return
+	case a.Count == 0:
+		*a = b
+		return
+	}
+	a.Min = min(a.Min, b.Min)
+	a.Max = max(a.Max, b.Max)
+	a.Sum += b.Sum
+	a.Count += b.Count
+}
+
+// Mean returns the mean, zero when there are no values
+func (a Aggregate) Mean() float64 {
+	if a.Count == 0 {
+		return 0
+	}
+	return a.Sum / float64(a.Count)
+}
+
+// Rollup summarizes the measurements of a device over a period starting at Start
+type Rollup struct {
+	Device       string
+	MAC          string
+	Start        time.Time
+	Period       time.Duration
+	Temperature  Aggregate
+	Humidity     Aggregate
+	Pressure     Aggregate
+	BatteryVolts Aggregate
+}
+
+func pointRollup(m *ruuvipb.RuuviStreamDataRequest) *Rollup {
+	r := &Rollup{
+		Device: m.GetDevice(),
+		MAC:    m.GetMacAddress(),
+		Start:  timestamp(m),
+	}
+	r.Temperature.add(float64(m.GetTemperature()))
+	r.Humidity.add(float64(m.GetHumidity()))
+	r.Pressure.add(float64(m.GetPressure()))
+	r.BatteryVolts.add(float64(m.GetBatterVolts()))
+	return r
+}
+
+func (r *Rollup) merge(b *Rollup) {
+	r.Temperature.merge(b.Temperature)
+	r.Humidity.merge(b.Humidity)
+	r.Pressure.merge(b.Pressure)
+	r.BatteryVolts.merge(b.BatteryVolts)
+}
+
+// Count returns the count of measurements summarized
+func (r *Rollup) Count() int {
+	return r.Temperature.Count
+}
+
+// Measurement returns the means as a measurement timestamped at the start of the period
+func (r *Rollup) Measurement() *ruuvipb.RuuviStreamDataRequest {
+	return &ruuvipb.RuuviStreamDataRequest{
+		Device:      r.Device,
+		MacAddress:  r.MAC,
+		Temperature: float32(r.Temperature.Mean()),
+		Humidity:    float32(r.Humidity.Mean()),
+		Pressure:    float32(r.Pressure.Mean()),
+		BatterVolts: float32(r.BatteryVolts.Mean()),
+		Timestamp:   timestamppb.New(r.Start),
+	}
+}
+
+func (r *Rollup) overlaps(from, until time.Time) bool {
+	return (from.IsZero() || r.Start.Add(r.Period).After(from)) && (until.IsZero() || !r.Start.After(until))
+}
+
+// fold adds r to the rollup of its period, which is created when missing. Rollups
+// are kept sorted and never share memory with r.
+func fold(rollups []*Rollup, r *Rollup, period time.Duration) []*Rollup {
+	start := r.Start.Truncate(period)
+	i, found := slices.BinarySearchFunc(rollups, start, func(e *Rollup, t time.Time) int {
+		return e.Start.Compare(t)
+	})
+	if found {
+		rollups[i].merge(r)
+		return rollups
+	}
+	folded := *r
+	folded.Start = start
+	folded.Period = period
+	return slices.Insert(rollups, i, &folded)
+}
+
+// expired returns the count of rollups starting before the cutoff
+func expired(rollups []*Rollup, cutoff time.Time) int {
+	i, _ := slices.BinarySearchFunc(rollups, cutoff, func(e *Rollup, t time.Time) int {
+		return e.Start.Compare(t)
+	})
+	return i
+}
+
+// pruneRollups folds the expired hourly rollups into daily ones and drops the expired daily ones
+func (s *series) pruneRollups(hourlyCutoff, dailyCutoff time.Time) {
+	if i := expired(s.hourly, hourlyCutoff); i > 0 {
+		for _, r := range s.hourly[:i] {
+			s.daily = fold(s.daily, r, Day)
+		}
+		s.hourly = slices.Clone(s.hourly[i:])
+	}
+	if i := expired(s.daily, dailyCutoff); i > 0 {
+		s.daily = slices.Clone(s.daily[i:])
+	}
+}
+
+// rollups summarizes all tiers between from and until into rollups of the period
+func (s *series) rollups(from, until time.Time, period time.Duration) []*Rollup {
+	rollups := []*Rollup{}
+	for _, tier := range [][]*Rollup{s.daily, s.hourly} {
+		for _, r := range tier {
+			if r.overlaps(from, until) {
+				rollups = fold(rollups, r, period)
+			}
+		}
+	}
+	for _, m := range s.between(from, until) {
+		rollups = fold(rollups, pointRollup(m), period)
+	}
+	return rollups
+}
+
+// Rollups returns rollups of the devices between from and until, zero times are open ends.
+// Period is at least an hour, rollups older than the raw measurements are summarized as
+// coarsely as they're kept.
+func (m *Measurements) Rollups(
+	devices []string,
+	from, until time.Time,
+	period time.Duration,
+) ([]*Rollup, error) {
+	if period < time.Hour {
+		return nil, errRollupPeriod
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	rollups := []*Rollup{}
+	for _, s := range m.matching(devices) {
+		rollups = append(rollups, s.rollups(from, until, period)...)
+	}
+	return rollups, nil
+}
+
+// Downsampled returns the measurements of the devices between from and until in the finest
+// resolution kept for the whole range: raw measurements, or means of hourly or daily rollups.
+func (m *Measurements) Downsampled(
+	devices []string,
+	from, until time.Time,
+) ([]*ruuvipb.RuuviStreamDataRequest, error) {
+	now := m.now()
+	var period time.Duration
+	switch {
+	case !m.rollups() || !from.IsZero() && !from.Before(now.Add(-m.maxAge)):
+		return m.Range(devices, from, until)
+	case !from.IsZero() && !from.Before(now.Add(-m.hourlyAge)):
+		period = time.Hour
+	default:
+		period = Day
+	}
+
+	rollups, err := m.Rollups(devices, from, until, period)
+	if err != nil {
+		return nil, err
+	}
+	measurements := make([]*ruuvipb.RuuviStreamDataRequest, 0, len(rollups))
+	for _, r := range rollups {
+		measurements = append(measurements, r.Measurement())
+	}
+	return measurements, nil
+}
+
+// Summaries are the rollups of a device, which are saved to keep them over restarts
+type Summaries struct {
+	Device string
+	MAC    string
+	// Measurements before Rolled are summarized in the rollups
+	Rolled time.Time
+	Hourly []Rollup
+	Daily  []Rollup
+}
+
+// Summaries returns copies of the rollups of every device
+func (m *Measurements) Summaries() []Summaries {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	summaries := []Summaries{}
+	for _, s := range m.matching(nil) {
+		if s.rolled.IsZero() && len(s.hourly) == 0 && len(s.daily) == 0 {
+			continue
+		}
+		sum := Summaries{Device: s.device, MAC: s.mac, Rolled: s.rolled}
+		for _, r := range s.hourly {
+			sum.Hourly = append(sum.Hourly, *r)
+		}
+		for _, r := range s.daily {
+			sum.Daily = append(sum.Daily, *r)
+		}
+		summaries = append(summaries, sum)
+	}
+	return summaries
+}
+
+// Restore loads the summaries and measurements saved on an earlier run. Unlike Append,
+// expired measurements are rolled up unless the summaries cover them already, so that
+// nothing expiring while stopped is lost. Returns the count of measurements stored as such.
+func (m *Measurements) Restore(
+	summaries []Summaries,
+	reqs ...*ruuvipb.RuuviStreamDataRequest,
+) (int, error) {
+	now := m.now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.rollups() {
+		for _, sum := range summaries {
+			s := m.seriesOf(&ruuvipb.RuuviStreamDataRequest{Device: sum.Device, MacAddress: sum.MAC})
+			if sum.Rolled.After(s.rolled) {
+				s.rolled = sum.Rolled
+			}
+			for _, r := range sum.Hourly {
+				s.hourly = fold(s.hourly, &r, time.Hour)
+			}
+			for _, r := range sum.Daily {
+				s.daily = fold(s.daily, &r, Day)
+			}
+		}
+	}
+
+	restored := 0
+	errs := make([]error, len(reqs))
+	for i, req := range reqs {
+		stored, err := m.restore(req, now)
+		if err != nil {
+			errs[i] = fmt.Errorf("measurement %d: %w", i, err)
+			continue
+		}
+		if stored {
+			restored++
+		}
+	}
+
+	for key, s := range m.devices {
+		s.pruneRollups(now.Add(-m.hourlyAge), now.Add(-m.dailyAge))
+		if s.empty() {
+			delete(m.devices, key)
+		}
+	}
+	m.enforceBudget()
+
+	return restored, errors.Join(errs...)
+}
+
+// restore stores the measurement, or rolls it up when it has expired. Returns whether
+// it was stored as such. Caller holds the lock.
+func (m *Measurements) restore(req *ruuvipb.RuuviStreamDataRequest, now time.Time) (bool, error) {
+	cutoff := now.Add(-m.maxAgeOf(m.ruleOf(req.GetDevice(), req.GetMacAddress())))
+	err := storage.Check(req, cutoff)
+	if errors.Is(err, storage.ErrExpired) && m.rollups() {
+		s := m.seriesOf(req)
+		if timestamp(req).Before(s.rolled) {
+			return false, err
+		}
+		s.hourly = fold(s.hourly, pointRollup(req), time.Hour)
+		s.rolled = cutoff
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	// Summarized already when retention was shorter
+	if s, ok := m.devices[storage.DeviceKey(req)]; ok && timestamp(req).Before(s.rolled) {
+		return false, storage.ErrExpired
+	}
+	if err = m.insert(req); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,169 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	ruuviv1 "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+	"weezel/ruuvigraph/pkg/storage"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestMeasurements_rollups(t *testing.T) {
+	m := New(WithMaxMeasureAge(Day), WithRollups(10*Day, 100*Day))
+	t.Cleanup(m.Stop)
+
+	started := time.Now()
+	hour := started.Truncate(time.Hour).Add(-2 * time.Hour)
+	for i, temperature := range []float32{20, 22, 24, 30} {
+		// Three measurements in one hour and one in the next
+		if err := m.Append(&ruuviv1.RuuviStreamDataRequest{
+			Device:      "Kitchen",
+			Temperature: temperature,
+			Pressure:    101320,
+			Timestamp:   timestamppb.New(hour.Add(time.Duration(i) * 20 * time.Minute)),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m.now = func() time.Time { return started.Add(2 * Day) }
+	if removed := m.pruneOldData(); removed != 4 {
+		t.Fatalf("pruneOldData() removed %d, want 4", removed)
+	}
+	if m.Len() != 0 || m.DeviceCount() != 0 {
+		t.Errorf("Len() = %d, DeviceCount() = %d after pruning, want 0", m.Len(), m.DeviceCount())
+	}
+
+	hourly, err := m.Rollups(nil, time.Time{}, time.Time{}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hourly) != 2 {
+		t.Fatalf("Rollups() returned %d hours, want 2", len(hourly))
+	}
+	got := hourly[0]
+	if !got.Start.Equal(hour) || got.Count() != 3 ||
+		got.Temperature.Min != 20 || got.Temperature.Max != 24 || got.Temperature.Mean() != 22 {
+		t.Errorf("first hour = %+v, want 3 measurements between 20 and 24", got)
+	}
+	if got.Pressure.Mean() != 101320 {
+		t.Errorf("first hour pressure = %v, want 101320", got.Pressure.Mean())
+	}
+
+	m.now = func() time.Time { return started.Add(20 * Day) }
+	m.pruneOldData()
+	daily, err := m.Rollups([]string{"Kitchen"}, time.Time{}, time.Time{}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Hours are gone, hence summarized by day even when asked by hour
+	if len(daily) != 1 || daily[0].Count() != 4 || daily[0].Temperature.Max != 30 {
+		t.Errorf("Rollups() = %+v, want the day of 4 measurements", daily)
+	}
+
+	m.now = func() time.Time { return started.Add(200 * Day) }
+	m.pruneOldData()
+	if rollups, _ := m.Rollups(nil, time.Time{}, time.Time{}, Day); len(rollups) != 0 {
+		t.Errorf("Rollups() = %+v after they expired", rollups)
+	}
+
+	if _, err = m.Rollups(nil, time.Time{}, time.Time{}, time.Minute); err == nil {
+		t.Error("Rollups() with a minute period succeeded")
+	}
+}
+
+func TestMeasurements_Downsampled(t *testing.T) {
+	m := New(WithMaxMeasureAge(Day), WithRollups(10*Day, 100*Day))
+	t.Cleanup(m.Stop)
+
+	now := time.Now()
+	for i := range 12 {
+		if err := m.Append(&ruuviv1.RuuviStreamDataRequest{
+			Device:      "Kitchen",
+			Temperature: 20,
+			Timestamp:   timestamppb.New(now.Add(-time.Duration(i) * 10 * time.Minute)),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		from     time.Time
+		maxCount int
+		minCount int
+	}{
+		{"raw", now.Add(-Day / 2), 12, 12},
+		{"hourly", now.Add(-5 * Day), 3, 2},
+		{"daily", now.Add(-50 * Day), 2, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := m.Downsampled(nil, tt.from, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) < tt.minCount || len(got) > tt.maxCount {
+				t.Errorf("Downsampled() returned %d measurements, want %d-%d",
+					len(got), tt.minCount, tt.maxCount)
+			}
+			for _, d := range got {
+				if d.GetTemperature() != 20 {
+					t.Errorf("Downsampled() temperature = %v, want 20", d.GetTemperature())
+				}
+			}
+		})
+	}
+}
+
+func TestMeasurements_Restore(t *testing.T) {
+	started := time.Now()
+	measurement := func(ts time.Time) *ruuviv1.RuuviStreamDataRequest {
+		return &ruuviv1.RuuviStreamDataRequest{
+			Device:    "Kitchen",
+			Pressure:  101320,
+			Timestamp: timestamppb.New(ts),
+		}
+	}
+	summarized := measurement(started.Add(-150 * time.Minute))
+	expiredMeanwhile := measurement(started.Add(-90 * time.Minute))
+	fresh := measurement(started.Add(-10 * time.Minute))
+
+	m := New(WithMaxMeasureAge(2*time.Hour), WithRollups(10*Day, 100*Day))
+	t.Cleanup(m.Stop)
+	m.now = func() time.Time { return started.Add(-time.Hour) }
+	if err := m.Append(summarized, expiredMeanwhile); err != nil {
+		t.Fatal(err)
+	}
+	m.now = func() time.Time { return started.Add(-20 * time.Minute) }
+	m.pruneOldData()
+	summaries := m.Summaries()
+	if len(summaries) != 1 || len(summaries[0].Hourly) != 1 {
+		t.Fatalf("Summaries() = %+v, want an hourly rollup of Kitchen", summaries)
+	}
+
+	restarted := New(WithMaxMeasureAge(time.Hour), WithRollups(10*Day, 100*Day))
+	t.Cleanup(restarted.Stop)
+	restarted.now = func() time.Time { return started }
+	restored, err := restarted.Restore(summaries, summarized, expiredMeanwhile, fresh)
+	if !errors.Is(err, storage.ErrExpired) {
+		t.Errorf("Restore() error = %v, want the summarized measurement refused", err)
+	}
+	if restored != 1 || restarted.Len() != 1 {
+		t.Errorf("Restore() = %d, Len() = %d, want the fresh measurement stored", restored, restarted.Len())
+	}
+	rollups, err := restarted.Rollups(nil, time.Time{}, started.Add(-time.Hour), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	for _, r := range rollups {
+		count += r.Count()
+	}
+	if count != 2 {
+		t.Errorf("rollups summarize %d measurements, want the one saved and the one expired meanwhile", count)
+	}
+}
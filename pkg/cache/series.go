@@ -0,0 +1,228 @@
+package cache
+
+import (
+	"slices"
+	"time"
+
+	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+)
+
+// series holds the measurements of a device sorted by timestamp. Measurements
+// are sealed in encoded chunks, when there are enough of them in the head.
+type series struct {
+	device string
+	mac    string
+	chunks []*chunk
+	head   []*ruuvipb.RuuviStreamDataRequest
+	count  int
+	bytes  int // Approximately used by the measurements, kept in step with size
+	rule   int // Index of the retention rule, -1 for none
+	hourly []*Rollup
+	daily  []*Rollup
+	// Measurements before rolled have been summarized in the rollups
+	rolled time.Time
+}
+
+func newSeries(m *ruuvipb.RuuviStreamDataRequest) *series {
+	return &series{
+		device: m.GetDevice(),
+		mac:    m.GetMacAddress(),
+		rule:   -1,
+	}
+}
+
+func timestamp(m *ruuvipb.RuuviStreamDataRequest) time.Time {
+	return m.GetTimestamp().AsTime()
+}
+
+// search returns the index of the first measurement not before ts
+func search(points []*ruuvipb.RuuviStreamDataRequest, ts time.Time) int {
+	i, _ := slices.BinarySearchFunc(points, ts, func(m *ruuvipb.RuuviStreamDataRequest, ts time.Time) int {
+		return timestamp(m).Compare(ts)
+	})
+	return i
+}
+
+// searchChunks returns the index of the first chunk ending at or after ts
+func (s *series) searchChunks(ts time.Time) int {
+	i, _ := slices.BinarySearchFunc(s.chunks, ts.UnixNano(), func(c *chunk, ts int64) int {
+		switch {
+		case c.last < ts:
+			return -1
+		case c.last > ts:
+			return 1
+		}
+		return 0
+	})
+	return i
+}
+
+// insertSorted inserts after the ones with the same timestamp, like appending would
+func insertSorted(
+	points []*ruuvipb.RuuviStreamDataRequest,
+	m *ruuvipb.RuuviStreamDataRequest,
+) []*ruuvipb.RuuviStreamDataRequest {
+	ts := timestamp(m)
+	if len(points) == 0 || !ts.Before(timestamp(points[len(points)-1])) {
+		return append(points, m)
+	}
+	return slices.Insert(points, search(points, ts.Add(time.Nanosecond)), m)
+}
+
+// insert keeps the measurements sorted. They mostly arrive in order and are appended
+// to the head, late ones are inserted to the chunk covering them.
+func (s *series) insert(m *ruuvipb.RuuviStreamDataRequest) {
+	s.count++
+	ts := timestamp(m)
+	if len(s.chunks) == 0 || ts.UnixNano() >= s.chunks[len(s.chunks)-1].last {
+		s.head = insertSorted(s.head, m)
+		s.bytes += pointSize
+		if len(s.head) >= chunkSize {
+			c := encodeChunk(s.head)
+			s.chunks = append(s.chunks, c)
+			s.bytes += chunkOverhead + c.size() - len(s.head)*pointSize
+			s.head = nil
+		}
+		return
+	}
+
+	i := s.searchChunks(ts)
+	c := encodeChunk(insertSorted(s.decode(s.chunks[i]), m))
+	s.bytes += c.size() - s.chunks[i].size()
+	s.chunks[i] = c
+}
+
+func (s *series) decode(c *chunk) []*ruuvipb.RuuviStreamDataRequest {
+	return c.decode(s.device, s.mac)
+}
+
+func (s *series) matches(devices []string) bool {
+	return len(devices) == 0 || slices.Contains(devices, s.device) || slices.Contains(devices, s.mac)
+}
+
+// between returns the measurements between from and until inclusive, zero times are open ends
+func (s *series) between(from, until time.Time) []*ruuvipb.RuuviStreamDataRequest {
+	within := func(points []*ruuvipb.RuuviStreamDataRequest) []*ruuvipb.RuuviStreamDataRequest {
+		start := 0
+		if !from.IsZero() {
+			start = search(points, from)
+		}
+		end := len(points)
+		if !until.IsZero() {
+			end = search(points, until.Add(time.Nanosecond))
+		}
+		if start >= end {
+			return nil
+		}
+		return points[start:end]
+	}
+
+	matching := []*ruuvipb.RuuviStreamDataRequest{}
+	i := 0
+	if !from.IsZero() {
+		i = s.searchChunks(from)
+	}
+	for _, c := range s.chunks[i:] {
+		if !until.IsZero() && c.first > until.UnixNano() {
+			break
+		}
+		matching = append(matching, within(s.decode(c))...)
+	}
+	return append(matching, within(s.head)...)
+}
+
+// contains tells whether the series has a measurement with the same timestamp and values
+func (s *series) contains(m *ruuvipb.RuuviStreamDataRequest) bool {
+	ts := timestamp(m)
+	if s.count == 0 || ts.After(s.newest()) {
+		return false
+	}
+	for _, e := range s.between(ts, ts) {
+		if e.GetTemperature() == m.GetTemperature() &&
+			e.GetHumidity() == m.GetHumidity() &&
+			e.GetPressure() == m.GetPressure() &&
+			e.GetBatterVolts() == m.GetBatterVolts() &&
+			e.GetRssi() == m.GetRssi() {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *series) latest() *ruuvipb.RuuviStreamDataRequest {
+	if len(s.head) > 0 {
+		return s.head[len(s.head)-1]
+	}
+	if len(s.chunks) > 0 {
+		points := s.decode(s.chunks[len(s.chunks)-1])
+		return points[len(points)-1]
+	}
+	return nil
+}
+
+func (s *series) oldest() time.Time {
+	if len(s.chunks) > 0 {
+		return time.Unix(0, s.chunks[0].first)
+	}
+	return timestamp(s.head[0])
+}
+
+func (s *series) newest() time.Time {
+	if len(s.head) > 0 {
+		return timestamp(s.head[len(s.head)-1])
+	}
+	return time.Unix(0, s.chunks[len(s.chunks)-1].last)
+}
+
+// prune removes the measurements before the cutoff, folding them into hourly rollups when
+// rollup is set. Returns the count of removed ones.
+func (s *series) prune(cutoff time.Time, rollup bool) int {
+	removed := 0
+	drop := func(points []*ruuvipb.RuuviStreamDataRequest) {
+		removed += len(points)
+		if rollup {
+			for _, m := range points {
+				s.hourly = fold(s.hourly, pointRollup(m), time.Hour)
+			}
+		}
+	}
+
+	// Chunks ending before the cutoff are dropped whole, the one covering it is re-encoded
+	if i := s.searchChunks(cutoff); i > 0 {
+		for _, c := range s.chunks[:i] {
+			drop(s.decode(c))
+		}
+		// Copied, so that the pruned chunks can be garbage collected
+		s.chunks = slices.Clone(s.chunks[i:])
+	}
+	if len(s.chunks) > 0 && s.chunks[0].first < cutoff.UnixNano() {
+		points := s.decode(s.chunks[0])
+		i := search(points, cutoff)
+		drop(points[:i])
+		s.chunks[0] = encodeChunk(points[i:])
+	}
+	if i := search(s.head, cutoff); i > 0 {
+		drop(s.head[:i])
+		s.head = slices.Clone(s.head[i:])
+	}
+
+	if rollup && cutoff.After(s.rolled) {
+		s.rolled = cutoff
+	}
+	s.count -= removed
+	s.bytes = s.size()
+	return removed
+}
+
+// size counts approximately the bytes used by the measurements
+func (s *series) size() int {
+	size := len(s.head) * pointSize
+	for _, c := range s.chunks {
+		size += chunkOverhead + c.size()
+	}
+	return size
+}
+
+func (s *series) empty() bool {
+	return s.count == 0 && len(s.hourly) == 0 && len(s.daily) == 0
+}
@@ -0,0 +1,93 @@
+package compression
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc/encoding"
+)
+
+// ZstdName is the name zstd compressor is registered with in gRPC
+const ZstdName = "zstd"
+
+//nolint:gochecknoinits // Compressors must be registered before the server and clients are created
+func init() {
+	encoding.RegisterCompressor(newZstdCompressor())
+}
+
+// zstdCompressor implements gRPC's encoding.Compressor. Encoders and
+// decoders are expensive to construct, hence they are pooled.
+type zstdCompressor struct {
+	encoders sync.Pool
+	decoders sync.Pool
+}
+
+func newZstdCompressor() *zstdCompressor {
+	return &zstdCompressor{}
+}
+
+func (z *zstdCompressor) Name() string {
+	return ZstdName
+}
+
+func (z *zstdCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	if enc, ok := z.encoders.Get().(*zstd.Encoder); ok {
+		enc.Reset(w)
+		return &zstdWriter{Encoder: enc, pool: &z.encoders}, nil
+	}
+
+	enc, err := zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.SpeedDefault), zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return nil, fmt.Errorf("new zstd writer: %w", err)
+	}
+	return &zstdWriter{Encoder: enc, pool: &z.encoders}, nil
+}
+
+func (z *zstdCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	if dec, ok := z.decoders.Get().(*zstd.Decoder); ok {
+		if err := dec.Reset(r); err != nil {
+			return nil, fmt.Errorf("reset zstd reader: %w", err)
+		}
+		return &zstdReader{Decoder: dec, pool: &z.decoders}, nil
+	}
+
+	dec, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, fmt.Errorf("new zstd reader: %w", err)
+	}
+	return &zstdReader{Decoder: dec, pool: &z.decoders}, nil
+}
+
+type zstdWriter struct {
+	*zstd.Encoder
+	pool *sync.Pool
+}
+
+func (w *zstdWriter) Close() error {
+	defer w.pool.Put(w.Encoder)
+	if err := w.Encoder.Close(); err != nil {
+		return fmt.Errorf("close zstd writer: %w", err)
+	}
+	return nil
+}
+
+type zstdReader struct {
+	*zstd.Decoder
+	pool *sync.Pool
+}
+
+// Read returns the decoder to the pool once the whole message has been read
+func (r *zstdReader) Read(p []byte) (int, error) {
+	if r.Decoder == nil {
+		return 0, io.EOF
+	}
+
+	n, err := r.Decoder.Read(p)
+	if err == io.EOF { //nolint:errorlint // Decoder returns io.EOF as is
+		r.pool.Put(r.Decoder)
+		r.Decoder = nil
+	}
+	return n, err //nolint:wrapcheck // io.EOF must be returned as is
+}
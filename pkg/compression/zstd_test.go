@@ -0,0 +1,85 @@
+package compression
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc/encoding"
+)
+
+func roundTrip(c encoding.Compressor, msg []byte) ([]byte, error) {
+	var compressed bytes.Buffer
+	w, err := c.Compress(&compressed)
+	if err != nil {
+		return nil, fmt.Errorf("compress: %w", err)
+	}
+	if _, err = w.Write(msg); err != nil {
+		return nil, fmt.Errorf("write: %w", err)
+	}
+	if err = w.Close(); err != nil {
+		return nil, fmt.Errorf("close: %w", err)
+	}
+
+	r, err := c.Decompress(&compressed)
+	if err != nil {
+		return nil, fmt.Errorf("decompress: %w", err)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+	return got, nil
+}
+
+func TestZstdCompressor_roundTrip(t *testing.T) {
+	c := encoding.GetCompressor(ZstdName)
+	if c == nil {
+		t.Fatalf("%s compressor isn't registered", ZstdName)
+	}
+
+	for _, msg := range [][]byte{
+		{},
+		[]byte("Kitchen aa:bb:cc:dd:ee:ff"),
+		bytes.Repeat([]byte("Kitchen aa:bb:cc:dd:ee:ff "), 10_000),
+	} {
+		// Twice, the second time with pooled encoder and decoder
+		for range 2 {
+			got, err := roundTrip(c, msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, msg) {
+				t.Fatalf("round trip of %d bytes returned %d bytes", len(msg), len(got))
+			}
+		}
+	}
+}
+
+// TestZstdCompressor_concurrent proves pooled encoders and decoders aren't shared
+// between concurrent streams, run with -race
+func TestZstdCompressor_concurrent(t *testing.T) {
+	c := encoding.GetCompressor(ZstdName)
+
+	var wg sync.WaitGroup
+	for g := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rng := rand.New(rand.NewPCG(uint64(g), 0))
+			for i := range 50 {
+				msg := fmt.Appendf(nil, "stream %d message %d ", g, i)
+				msg = bytes.Repeat(msg, 1+rng.IntN(1000))
+				got, err := roundTrip(c, msg)
+				if err != nil || !bytes.Equal(got, msg) {
+					t.Errorf("stream %d message %d corrupted, error %v", g, i, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
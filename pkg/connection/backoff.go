@@ -0,0 +1,51 @@
+package connection
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"google.golang.org/grpc/backoff"
+)
+
+// Backoff computes exponentially growing delays with random jitter
+// so that collectors don't retry in lockstep after a server restart.
+type Backoff struct {
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Multiplier is the factor delay grows after each failed attempt
+	Multiplier float64
+	// Jitter randomizes the delay by ±Jitter fraction, e.g. 0.2 is ±20%
+	Jitter float64
+}
+
+var DefaultBackoff = Backoff{
+	BaseDelay:  time.Second,
+	MaxDelay:   2 * time.Minute,
+	Multiplier: 1.6,
+	Jitter:     0.2,
+}
+
+// Delay returns how long to wait before the given retry attempt, starting from zero
+func (b Backoff) Delay(attempt int) time.Duration {
+	if attempt < 0 {
+		attempt = 0
+	}
+
+	delay := math.Min(
+		float64(b.BaseDelay)*math.Pow(b.Multiplier, float64(attempt)),
+		float64(b.MaxDelay),
+	)
+	delay *= 1 + b.Jitter*(rand.Float64()*2-1) //nolint:gosec // Jitter doesn't need to be cryptographically secure
+
+	return time.Duration(delay)
+}
+
+func (b Backoff) grpcConfig() backoff.Config {
+	return backoff.Config{
+		BaseDelay:  b.BaseDelay,
+		Multiplier: b.Multiplier,
+		Jitter:     b.Jitter,
+		MaxDelay:   b.MaxDelay,
+	}
+}
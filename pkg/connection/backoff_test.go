@@ -0,0 +1,36 @@
+package connection
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff_Delay(t *testing.T) {
+	b := Backoff{
+		BaseDelay:  time.Second,
+		MaxDelay:   10 * time.Second,
+		Multiplier: 2,
+		Jitter:     0.2,
+	}
+
+	tests := []struct {
+		attempt int
+		base    time.Duration
+	}{
+		{attempt: -1, base: time.Second},
+		{attempt: 0, base: time.Second},
+		{attempt: 1, base: 2 * time.Second},
+		{attempt: 3, base: 8 * time.Second},
+		{attempt: 4, base: 10 * time.Second},
+		{attempt: 100, base: 10 * time.Second},
+	}
+	for _, tt := range tests {
+		lower := time.Duration(float64(tt.base) * 0.8)
+		upper := time.Duration(float64(tt.base) * 1.2)
+		for range 100 {
+			if got := b.Delay(tt.attempt); got < lower || got > upper {
+				t.Fatalf("Delay(%d) = %s, want between %s and %s", tt.attempt, got, lower, upper)
+			}
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package connection
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"weezel/ruuvigraph/pkg/logging"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+var logger *slog.Logger = logging.NewColorLogHandler()
+
+// Manager keeps a single long-lived connection to the server. gRPC reconnects
+// with the configured backoff whenever the connection breaks and Manager keeps
+// it from going idle, so that the next send doesn't need to wait for a handshake.
+type Manager struct {
+	conn     *grpc.ClientConn
+	target   string
+	backoff  Backoff
+	dialOpts []grpc.DialOption
+	state    atomic.Int32
+
+	// ready is closed whenever the connection becomes ready, the first time included
+	mu         sync.Mutex
+	ready      chan struct{}
+	reconnects atomic.Uint64
+}
+
+type Option func(*Manager)
+
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(m *Manager) {
+		m.dialOpts = append(m.dialOpts, opts...)
+	}
+}
+
+func WithBackoff(b Backoff) Option {
+	return func(m *Manager) {
+		m.backoff = b
+	}
+}
+
+func NewManager(target string, opts ...Option) (*Manager, error) {
+	m := &Manager{
+		target:  target,
+		backoff: DefaultBackoff,
+		ready:   make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	dialOpts := append([]grpc.DialOption{
+		grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff:           m.backoff.grpcConfig(),
+			MinConnectTimeout: 10 * time.Second,
+		}),
+	}, m.dialOpts...)
+
+	conn, err := grpc.NewClient(target, dialOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("new client: %w", err)
+	}
+	m.conn = conn
+	m.state.Store(int32(conn.GetState()))
+
+	return m, nil
+}
+
+// Conn returns the managed connection for constructing service clients
+func (m *Manager) Conn() *grpc.ClientConn {
+	return m.conn
+}
+
+// State returns the current connectivity state
+func (m *Manager) State() connectivity.State {
+	return connectivity.State(m.state.Load())
+}
+
+// Reconnects returns how many times the connection has been reestablished
+func (m *Manager) Reconnects() uint64 {
+	return m.reconnects.Load()
+}
+
+// Ready returns a channel closed when the connection becomes ready next time,
+// either connecting for the first time or reconnecting
+func (m *Manager) Ready() <-chan struct{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.ready
+}
+
+func (m *Manager) signalReady(reconnected bool) {
+	if reconnected {
+		m.reconnects.Add(1)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	close(m.ready)
+	m.ready = make(chan struct{})
+}
+
+// Run connects and watches the connection state until context is cancelled
+func (m *Manager) Run(ctx context.Context) {
+	m.conn.Connect()
+
+	state := m.conn.GetState()
+	wasReady := false
+	for {
+		m.state.Store(int32(state))
+		if state == connectivity.Ready {
+			m.signalReady(wasReady)
+			wasReady = true
+		}
+		if state == connectivity.Idle {
+			m.conn.Connect()
+		}
+
+		if !m.conn.WaitForStateChange(ctx, state) {
+			return
+		}
+
+		newState := m.conn.GetState()
+		logFn := logger.Info
+		if newState == connectivity.TransientFailure {
+			logFn = logger.Warn
+		}
+		logFn(
+			"Connection state changed",
+			slog.String("target", m.target),
+			slog.String("from", state.String()),
+			slog.String("to", newState.String()),
+		)
+		state = newState
+	}
+}
+
+func (m *Manager) Close() error {
+	if err := m.conn.Close(); err != nil {
+		return fmt.Errorf("close connection: %w", err)
+	}
+	return nil
+}
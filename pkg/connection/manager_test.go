@@ -0,0 +1,91 @@
+package connection
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// serve starts a server on the address, which is chosen if empty
+func serve(t *testing.T, addr string) (*grpc.Server, string) {
+	t.Helper()
+	if addr == "" {
+		addr = "127.0.0.1:0"
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+	return server, lis.Addr().String()
+}
+
+func waitState(t *testing.T, m *Manager, ready bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for (m.State() == connectivity.Ready) != ready {
+		if time.Now().After(deadline) {
+			t.Fatalf("state = %s, want ready %v", m.State(), ready)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestManager_reconnect(t *testing.T) {
+	server, addr := serve(t, "")
+	m, err := NewManager(
+		addr,
+		WithDialOptions(grpc.WithTransportCredentials(insecure.NewCredentials())),
+		WithBackoff(Backoff{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond, Multiplier: 2}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = m.Close() })
+
+	ready := m.Ready()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	select {
+	case <-ready:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("not connected, state = %s", m.State())
+	}
+	if got := m.Reconnects(); got != 0 {
+		t.Errorf("Reconnects() = %d after connecting, want 0", got)
+	}
+	reconnected := m.Ready()
+
+	// Server restarts
+	server.Stop()
+	waitState(t, m, false)
+	serve(t, addr)
+
+	select {
+	case <-reconnected:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("not reconnected, state = %s", m.State())
+	}
+	if state := m.State(); state != connectivity.Ready {
+		t.Errorf("State() = %s after reconnecting, want READY", state)
+	}
+	if got := m.Reconnects(); got != 1 {
+		t.Errorf("Reconnects() = %d, want 1", got)
+	}
+}
@@ -9,6 +9,7 @@ package ruuviv1
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
 	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
@@ -22,6 +23,62 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type RuuviAdapterState int32
+
+const (
+	RuuviAdapterState_RUUVI_ADAPTER_STATE_UNSPECIFIED RuuviAdapterState = 0
+	// Bluetooth adapter hasn't been initialized or scanning has stopped.
+	RuuviAdapterState_RUUVI_ADAPTER_STATE_DOWN     RuuviAdapterState = 1
+	RuuviAdapterState_RUUVI_ADAPTER_STATE_SCANNING RuuviAdapterState = 2
+	RuuviAdapterState_RUUVI_ADAPTER_STATE_FAILED   RuuviAdapterState = 3
+)
+
+// Enum value maps for RuuviAdapterState.
+var (
+	RuuviAdapterState_name = map[int32]string{
+		0: "RUUVI_ADAPTER_STATE_UNSPECIFIED",
+		1: "RUUVI_ADAPTER_STATE_DOWN",
+		2: "RUUVI_ADAPTER_STATE_SCANNING",
+		3: "RUUVI_ADAPTER_STATE_FAILED",
+	}
+	RuuviAdapterState_value = map[string]int32{
+		"RUUVI_ADAPTER_STATE_UNSPECIFIED": 0,
+		"RUUVI_ADAPTER_STATE_DOWN":        1,
+		"RUUVI_ADAPTER_STATE_SCANNING":    2,
+		"RUUVI_ADAPTER_STATE_FAILED":      3,
+	}
+)
+
+func (x RuuviAdapterState) Enum() *RuuviAdapterState {
+	p := new(RuuviAdapterState)
+	*p = x
+	return p
+}
+
+func (x RuuviAdapterState) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (RuuviAdapterState) Descriptor() protoreflect.EnumDescriptor {
+	return file_ruuvi_v1_ruuvi_proto_enumTypes[0].Descriptor()
+}
+
+func (RuuviAdapterState) Type() protoreflect.EnumType {
+	return &file_ruuvi_v1_ruuvi_proto_enumTypes[0]
+}
+
+func (x RuuviAdapterState) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use RuuviAdapterState.Descriptor instead.
+func (RuuviAdapterState) EnumDescriptor() ([]byte, []int) {
+	return file_ruuvi_v1_ruuvi_proto_rawDescGZIP(), []int{0}
+}
+
+// RuuviStreamDataRequest units are degrees Celsius, relative humidity percent,
+// pascals and volts. Older collectors sent pressure in decapascals (Pa / 10),
+// which server converts to pascals when receiving.
 type RuuviStreamDataRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Device        string                 `protobuf:"bytes,1,opt,name=device,proto3" json:"device,omitempty"`
@@ -125,6 +182,9 @@ func (x *RuuviStreamDataRequest) GetTimestamp() *timestamppb.Timestamp {
 type RuuviStreamDataResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Message       string                 `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	Accepted      uint32                 `protobuf:"varint,2,opt,name=accepted,proto3" json:"accepted,omitempty"`
+	Rejected      uint32                 `protobuf:"varint,3,opt,name=rejected,proto3" json:"rejected,omitempty"`
+	Rejections    []*RuuviRejection      `protobuf:"bytes,4,rep,name=rejections,proto3" json:"rejections,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -166,26 +226,1157 @@ func (x *RuuviStreamDataResponse) GetMessage() string {
 	return ""
 }
 
-var File_ruuvi_v1_ruuvi_proto protoreflect.FileDescriptor
+func (x *RuuviStreamDataResponse) GetAccepted() uint32 {
+	if x != nil {
+		return x.Accepted
+	}
+	return 0
+}
 
-const file_ruuvi_v1_ruuvi_proto_rawDesc = "" +
-	"\n" +
-	"\x14ruuvi/v1/ruuvi.proto\x12\bruuvi.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\x9c\x02\n" +
-	"\x16RuuviStreamDataRequest\x12\x16\n" +
-	"\x06device\x18\x01 \x01(\tR\x06device\x12\x1f\n" +
-	"\vmac_address\x18\x02 \x01(\tR\n" +
-	"macAddress\x12 \n" +
-	"\vtemperature\x18\x03 \x01(\x02R\vtemperature\x12\x1a\n" +
-	"\bhumidity\x18\x04 \x01(\x02R\bhumidity\x12\x1a\n" +
-	"\bpressure\x18\x05 \x01(\x02R\bpressure\x12!\n" +
-	"\fbatter_volts\x18\x06 \x01(\x02R\vbatterVolts\x12\x12\n" +
-	"\x04rssi\x18\a \x01(\x05R\x04rssi\x128\n" +
-	"\ttimestamp\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\"3\n" +
-	"\x17RuuviStreamDataResponse\x12\x18\n" +
-	"\amessage\x18\x01 \x01(\tR\amessage2\\\n" +
+func (x *RuuviStreamDataResponse) GetRejected() uint32 {
+	if x != nil {
+		return x.Rejected
+	}
+	return 0
+}
+
+func (x *RuuviStreamDataResponse) GetRejections() []*RuuviRejection {
+	if x != nil {
+		return x.Rejections
+	}
+	return nil
+}
+
+type RuuviRejection struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Position of the rejected measurement in the stream or batch.
+	// Batch's compact measurements are positioned after the full ones.
+	Index         uint32 `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Reason        string `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RuuviRejection) Reset() {
+	*x = RuuviRejection{}
+	mi := &file_ruuvi_v1_ruuvi_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RuuviRejection) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RuuviRejection) ProtoMessage() {}
+
+func (x *RuuviRejection) ProtoReflect() protoreflect.Message {
+	mi := &file_ruuvi_v1_ruuvi_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RuuviRejection.ProtoReflect.Descriptor instead.
+func (*RuuviRejection) Descriptor() ([]byte, []int) {
+	return file_ruuvi_v1_ruuvi_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *RuuviRejection) GetIndex() uint32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *RuuviRejection) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type RuuviMeasurementBatch struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Collector string                 `protobuf:"bytes,1,opt,name=collector,proto3" json:"collector,omitempty"`
+	// Random identifier chosen by the collector on startup.
+	Session string `protobuf:"bytes,2,opt,name=session,proto3" json:"session,omitempty"`
+	// Sequence number of the batch, increasing within the session.
+	BatchId      uint64                    `protobuf:"varint,3,opt,name=batch_id,json=batchId,proto3" json:"batch_id,omitempty"`
+	Measurements []*RuuviStreamDataRequest `protobuf:"bytes,4,rep,name=measurements,proto3" json:"measurements,omitempty"`
+	// Compact alternative to measurements. Devices are sent once per batch
+	// and measurements refer to them by index.
+	Devices             []*RuuviDevice             `protobuf:"bytes,5,rep,name=devices,proto3" json:"devices,omitempty"`
+	CompactMeasurements []*RuuviCompactMeasurement `protobuf:"bytes,6,rep,name=compact_measurements,json=compactMeasurements,proto3" json:"compact_measurements,omitempty"`
+	// Compact measurement timestamps are relative to this.
+	BaseTimestamp *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=base_timestamp,json=baseTimestamp,proto3" json:"base_timestamp,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RuuviMeasurementBatch) Reset() {
+	*x = RuuviMeasurementBatch{}
+	mi := &file_ruuvi_v1_ruuvi_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RuuviMeasurementBatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RuuviMeasurementBatch) ProtoMessage() {}
+
+func (x *RuuviMeasurementBatch) ProtoReflect() protoreflect.Message {
+	mi := &file_ruuvi_v1_ruuvi_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RuuviMeasurementBatch.ProtoReflect.Descriptor instead.
+func (*RuuviMeasurementBatch) Descriptor() ([]byte, []int) {
+	return file_ruuvi_v1_ruuvi_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *RuuviMeasurementBatch) GetCollector() string {
+	if x != nil {
+		return x.Collector
+	}
+	return ""
+}
+
+func (x *RuuviMeasurementBatch) GetSession() string {
+	if x != nil {
+		return x.Session
+	}
+	return ""
+}
+
+func (x *RuuviMeasurementBatch) GetBatchId() uint64 {
+	if x != nil {
+		return x.BatchId
+	}
+	return 0
+}
+
+func (x *RuuviMeasurementBatch) GetMeasurements() []*RuuviStreamDataRequest {
+	if x != nil {
+		return x.Measurements
+	}
+	return nil
+}
+
+func (x *RuuviMeasurementBatch) GetDevices() []*RuuviDevice {
+	if x != nil {
+		return x.Devices
+	}
+	return nil
+}
+
+func (x *RuuviMeasurementBatch) GetCompactMeasurements() []*RuuviCompactMeasurement {
+	if x != nil {
+		return x.CompactMeasurements
+	}
+	return nil
+}
+
+func (x *RuuviMeasurementBatch) GetBaseTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.BaseTimestamp
+	}
+	return nil
+}
+
+type RuuviDevice struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	MacAddress    string                 `protobuf:"bytes,2,opt,name=mac_address,json=macAddress,proto3" json:"mac_address,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RuuviDevice) Reset() {
+	*x = RuuviDevice{}
+	mi := &file_ruuvi_v1_ruuvi_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RuuviDevice) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RuuviDevice) ProtoMessage() {}
+
+func (x *RuuviDevice) ProtoReflect() protoreflect.Message {
+	mi := &file_ruuvi_v1_ruuvi_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RuuviDevice.ProtoReflect.Descriptor instead.
+func (*RuuviDevice) Descriptor() ([]byte, []int) {
+	return file_ruuvi_v1_ruuvi_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *RuuviDevice) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *RuuviDevice) GetMacAddress() string {
+	if x != nil {
+		return x.MacAddress
+	}
+	return ""
+}
+
+type RuuviCompactMeasurement struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Index of the device in the batch's devices.
+	Device       uint32  `protobuf:"varint,1,opt,name=device,proto3" json:"device,omitempty"`
+	Temperature  float32 `protobuf:"fixed32,2,opt,name=temperature,proto3" json:"temperature,omitempty"`
+	Humidity     float32 `protobuf:"fixed32,3,opt,name=humidity,proto3" json:"humidity,omitempty"`
+	Pressure     float32 `protobuf:"fixed32,4,opt,name=pressure,proto3" json:"pressure,omitempty"`
+	BatteryVolts float32 `protobuf:"fixed32,5,opt,name=battery_volts,json=batteryVolts,proto3" json:"battery_volts,omitempty"`
+	Rssi         int32   `protobuf:"zigzag32,6,opt,name=rssi,proto3" json:"rssi,omitempty"`
+	// Milliseconds from the batch's base timestamp.
+	TimestampOffsetMs int64 `protobuf:"zigzag64,7,opt,name=timestamp_offset_ms,json=timestampOffsetMs,proto3" json:"timestamp_offset_ms,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *RuuviCompactMeasurement) Reset() {
+	*x = RuuviCompactMeasurement{}
+	mi := &file_ruuvi_v1_ruuvi_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RuuviCompactMeasurement) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RuuviCompactMeasurement) ProtoMessage() {}
+
+func (x *RuuviCompactMeasurement) ProtoReflect() protoreflect.Message {
+	mi := &file_ruuvi_v1_ruuvi_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RuuviCompactMeasurement.ProtoReflect.Descriptor instead.
+func (*RuuviCompactMeasurement) Descriptor() ([]byte, []int) {
+	return file_ruuvi_v1_ruuvi_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *RuuviCompactMeasurement) GetDevice() uint32 {
+	if x != nil {
+		return x.Device
+	}
+	return 0
+}
+
+func (x *RuuviCompactMeasurement) GetTemperature() float32 {
+	if x != nil {
+		return x.Temperature
+	}
+	return 0
+}
+
+func (x *RuuviCompactMeasurement) GetHumidity() float32 {
+	if x != nil {
+		return x.Humidity
+	}
+	return 0
+}
+
+func (x *RuuviCompactMeasurement) GetPressure() float32 {
+	if x != nil {
+		return x.Pressure
+	}
+	return 0
+}
+
+func (x *RuuviCompactMeasurement) GetBatteryVolts() float32 {
+	if x != nil {
+		return x.BatteryVolts
+	}
+	return 0
+}
+
+func (x *RuuviCompactMeasurement) GetRssi() int32 {
+	if x != nil {
+		return x.Rssi
+	}
+	return 0
+}
+
+func (x *RuuviCompactMeasurement) GetTimestampOffsetMs() int64 {
+	if x != nil {
+		return x.TimestampOffsetMs
+	}
+	return 0
+}
+
+type RuuviBatchAck struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	BatchId    uint64                 `protobuf:"varint,1,opt,name=batch_id,json=batchId,proto3" json:"batch_id,omitempty"`
+	Accepted   uint32                 `protobuf:"varint,2,opt,name=accepted,proto3" json:"accepted,omitempty"`
+	Rejected   uint32                 `protobuf:"varint,3,opt,name=rejected,proto3" json:"rejected,omitempty"`
+	Rejections []*RuuviRejection      `protobuf:"bytes,4,rep,name=rejections,proto3" json:"rejections,omitempty"`
+	// Batch had already been received and wasn't stored again.
+	Duplicate     bool `protobuf:"varint,5,opt,name=duplicate,proto3" json:"duplicate,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RuuviBatchAck) Reset() {
+	*x = RuuviBatchAck{}
+	mi := &file_ruuvi_v1_ruuvi_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RuuviBatchAck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RuuviBatchAck) ProtoMessage() {}
+
+func (x *RuuviBatchAck) ProtoReflect() protoreflect.Message {
+	mi := &file_ruuvi_v1_ruuvi_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RuuviBatchAck.ProtoReflect.Descriptor instead.
+func (*RuuviBatchAck) Descriptor() ([]byte, []int) {
+	return file_ruuvi_v1_ruuvi_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *RuuviBatchAck) GetBatchId() uint64 {
+	if x != nil {
+		return x.BatchId
+	}
+	return 0
+}
+
+func (x *RuuviBatchAck) GetAccepted() uint32 {
+	if x != nil {
+		return x.Accepted
+	}
+	return 0
+}
+
+func (x *RuuviBatchAck) GetRejected() uint32 {
+	if x != nil {
+		return x.Rejected
+	}
+	return 0
+}
+
+func (x *RuuviBatchAck) GetRejections() []*RuuviRejection {
+	if x != nil {
+		return x.Rejections
+	}
+	return nil
+}
+
+func (x *RuuviBatchAck) GetDuplicate() bool {
+	if x != nil {
+		return x.Duplicate
+	}
+	return false
+}
+
+type RuuviSubscribeRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Device names or MAC addresses to receive measurements from.
+	Devices []string `protobuf:"bytes,1,rep,name=devices,proto3" json:"devices,omitempty"`
+	// Device groups to receive measurements from.
+	Groups        []string `protobuf:"bytes,2,rep,name=groups,proto3" json:"groups,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RuuviSubscribeRequest) Reset() {
+	*x = RuuviSubscribeRequest{}
+	mi := &file_ruuvi_v1_ruuvi_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RuuviSubscribeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RuuviSubscribeRequest) ProtoMessage() {}
+
+func (x *RuuviSubscribeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ruuvi_v1_ruuvi_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RuuviSubscribeRequest.ProtoReflect.Descriptor instead.
+func (*RuuviSubscribeRequest) Descriptor() ([]byte, []int) {
+	return file_ruuvi_v1_ruuvi_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *RuuviSubscribeRequest) GetDevices() []string {
+	if x != nil {
+		return x.Devices
+	}
+	return nil
+}
+
+func (x *RuuviSubscribeRequest) GetGroups() []string {
+	if x != nil {
+		return x.Groups
+	}
+	return nil
+}
+
+type RuuviServerInfoRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RuuviServerInfoRequest) Reset() {
+	*x = RuuviServerInfoRequest{}
+	mi := &file_ruuvi_v1_ruuvi_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RuuviServerInfoRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RuuviServerInfoRequest) ProtoMessage() {}
+
+func (x *RuuviServerInfoRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ruuvi_v1_ruuvi_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RuuviServerInfoRequest.ProtoReflect.Descriptor instead.
+func (*RuuviServerInfoRequest) Descriptor() ([]byte, []int) {
+	return file_ruuvi_v1_ruuvi_proto_rawDescGZIP(), []int{8}
+}
+
+type RuuviServerInfoResponse struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Version          string                 `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"`
+	BuildTime        string                 `protobuf:"bytes,2,opt,name=build_time,json=buildTime,proto3" json:"build_time,omitempty"`
+	Started          *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=started,proto3" json:"started,omitempty"`
+	Uptime           *durationpb.Duration   `protobuf:"bytes,4,opt,name=uptime,proto3" json:"uptime,omitempty"`
+	DeviceCount      uint32                 `protobuf:"varint,5,opt,name=device_count,json=deviceCount,proto3" json:"device_count,omitempty"`
+	MeasurementCount uint64                 `protobuf:"varint,6,opt,name=measurement_count,json=measurementCount,proto3" json:"measurement_count,omitempty"`
+	// Measurements refused by the rate limits since start
+	ThrottledMeasurements uint64 `protobuf:"varint,7,opt,name=throttled_measurements,json=throttledMeasurements,proto3" json:"throttled_measurements,omitempty"`
+	// Measurements evicted to stay within the memory budget since start
+	EvictedMeasurements uint64 `protobuf:"varint,8,opt,name=evicted_measurements,json=evictedMeasurements,proto3" json:"evicted_measurements,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *RuuviServerInfoResponse) Reset() {
+	*x = RuuviServerInfoResponse{}
+	mi := &file_ruuvi_v1_ruuvi_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RuuviServerInfoResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RuuviServerInfoResponse) ProtoMessage() {}
+
+func (x *RuuviServerInfoResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ruuvi_v1_ruuvi_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RuuviServerInfoResponse.ProtoReflect.Descriptor instead.
+func (*RuuviServerInfoResponse) Descriptor() ([]byte, []int) {
+	return file_ruuvi_v1_ruuvi_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *RuuviServerInfoResponse) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *RuuviServerInfoResponse) GetBuildTime() string {
+	if x != nil {
+		return x.BuildTime
+	}
+	return ""
+}
+
+func (x *RuuviServerInfoResponse) GetStarted() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Started
+	}
+	return nil
+}
+
+func (x *RuuviServerInfoResponse) GetUptime() *durationpb.Duration {
+	if x != nil {
+		return x.Uptime
+	}
+	return nil
+}
+
+func (x *RuuviServerInfoResponse) GetDeviceCount() uint32 {
+	if x != nil {
+		return x.DeviceCount
+	}
+	return 0
+}
+
+func (x *RuuviServerInfoResponse) GetMeasurementCount() uint64 {
+	if x != nil {
+		return x.MeasurementCount
+	}
+	return 0
+}
+
+func (x *RuuviServerInfoResponse) GetThrottledMeasurements() uint64 {
+	if x != nil {
+		return x.ThrottledMeasurements
+	}
+	return 0
+}
+
+func (x *RuuviServerInfoResponse) GetEvictedMeasurements() uint64 {
+	if x != nil {
+		return x.EvictedMeasurements
+	}
+	return 0
+}
+
+type RuuviPushMeasurementsRequest struct {
+	state         protoimpl.MessageState    `protogen:"open.v1"`
+	Measurements  []*RuuviStreamDataRequest `protobuf:"bytes,1,rep,name=measurements,proto3" json:"measurements,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RuuviPushMeasurementsRequest) Reset() {
+	*x = RuuviPushMeasurementsRequest{}
+	mi := &file_ruuvi_v1_ruuvi_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RuuviPushMeasurementsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RuuviPushMeasurementsRequest) ProtoMessage() {}
+
+func (x *RuuviPushMeasurementsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ruuvi_v1_ruuvi_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RuuviPushMeasurementsRequest.ProtoReflect.Descriptor instead.
+func (*RuuviPushMeasurementsRequest) Descriptor() ([]byte, []int) {
+	return file_ruuvi_v1_ruuvi_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *RuuviPushMeasurementsRequest) GetMeasurements() []*RuuviStreamDataRequest {
+	if x != nil {
+		return x.Measurements
+	}
+	return nil
+}
+
+type RuuviGetMeasurementsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Device names or MAC addresses, empty matches every device.
+	Devices []string               `protobuf:"bytes,1,rep,name=devices,proto3" json:"devices,omitempty"`
+	Since   *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=since,proto3" json:"since,omitempty"`
+	Until   *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=until,proto3" json:"until,omitempty"`
+	// Return only the most recent measurement of each device.
+	LatestOnly    bool `protobuf:"varint,4,opt,name=latest_only,json=latestOnly,proto3" json:"latest_only,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RuuviGetMeasurementsRequest) Reset() {
+	*x = RuuviGetMeasurementsRequest{}
+	mi := &file_ruuvi_v1_ruuvi_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RuuviGetMeasurementsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RuuviGetMeasurementsRequest) ProtoMessage() {}
+
+func (x *RuuviGetMeasurementsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ruuvi_v1_ruuvi_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RuuviGetMeasurementsRequest.ProtoReflect.Descriptor instead.
+func (*RuuviGetMeasurementsRequest) Descriptor() ([]byte, []int) {
+	return file_ruuvi_v1_ruuvi_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *RuuviGetMeasurementsRequest) GetDevices() []string {
+	if x != nil {
+		return x.Devices
+	}
+	return nil
+}
+
+func (x *RuuviGetMeasurementsRequest) GetSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Since
+	}
+	return nil
+}
+
+func (x *RuuviGetMeasurementsRequest) GetUntil() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Until
+	}
+	return nil
+}
+
+func (x *RuuviGetMeasurementsRequest) GetLatestOnly() bool {
+	if x != nil {
+		return x.LatestOnly
+	}
+	return false
+}
+
+type RuuviGetMeasurementsResponse struct {
+	state         protoimpl.MessageState    `protogen:"open.v1"`
+	Measurements  []*RuuviStreamDataRequest `protobuf:"bytes,1,rep,name=measurements,proto3" json:"measurements,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RuuviGetMeasurementsResponse) Reset() {
+	*x = RuuviGetMeasurementsResponse{}
+	mi := &file_ruuvi_v1_ruuvi_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RuuviGetMeasurementsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RuuviGetMeasurementsResponse) ProtoMessage() {}
+
+func (x *RuuviGetMeasurementsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ruuvi_v1_ruuvi_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RuuviGetMeasurementsResponse.ProtoReflect.Descriptor instead.
+func (*RuuviGetMeasurementsResponse) Descriptor() ([]byte, []int) {
+	return file_ruuvi_v1_ruuvi_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *RuuviGetMeasurementsResponse) GetMeasurements() []*RuuviStreamDataRequest {
+	if x != nil {
+		return x.Measurements
+	}
+	return nil
+}
+
+type RuuviCollectorStatus struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Collector    string                 `protobuf:"bytes,1,opt,name=collector,proto3" json:"collector,omitempty"`
+	Session      string                 `protobuf:"bytes,2,opt,name=session,proto3" json:"session,omitempty"`
+	Version      string                 `protobuf:"bytes,3,opt,name=version,proto3" json:"version,omitempty"`
+	Uptime       *durationpb.Duration   `protobuf:"bytes,4,opt,name=uptime,proto3" json:"uptime,omitempty"`
+	AdapterState RuuviAdapterState      `protobuf:"varint,5,opt,name=adapter_state,json=adapterState,proto3,enum=ruuvi.v1.RuuviAdapterState" json:"adapter_state,omitempty"`
+	// Advertisements received from any Bluetooth device since start.
+	AdvertisementsSeen uint64 `protobuf:"varint,6,opt,name=advertisements_seen,json=advertisementsSeen,proto3" json:"advertisements_seen,omitempty"`
+	// Advertisements of known tags which couldn't be parsed since start.
+	ParseErrors uint64 `protobuf:"varint,7,opt,name=parse_errors,json=parseErrors,proto3" json:"parse_errors,omitempty"`
+	// Batches waiting for the server's acknowledgement.
+	QueuedBatches uint32                 `protobuf:"varint,8,opt,name=queued_batches,json=queuedBatches,proto3" json:"queued_batches,omitempty"`
+	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	// Times the connection to the server has been reestablished since start.
+	Reconnects    uint64 `protobuf:"varint,10,opt,name=reconnects,proto3" json:"reconnects,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RuuviCollectorStatus) Reset() {
+	*x = RuuviCollectorStatus{}
+	mi := &file_ruuvi_v1_ruuvi_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RuuviCollectorStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RuuviCollectorStatus) ProtoMessage() {}
+
+func (x *RuuviCollectorStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_ruuvi_v1_ruuvi_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RuuviCollectorStatus.ProtoReflect.Descriptor instead.
+func (*RuuviCollectorStatus) Descriptor() ([]byte, []int) {
+	return file_ruuvi_v1_ruuvi_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *RuuviCollectorStatus) GetCollector() string {
+	if x != nil {
+		return x.Collector
+	}
+	return ""
+}
+
+func (x *RuuviCollectorStatus) GetSession() string {
+	if x != nil {
+		return x.Session
+	}
+	return ""
+}
+
+func (x *RuuviCollectorStatus) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *RuuviCollectorStatus) GetUptime() *durationpb.Duration {
+	if x != nil {
+		return x.Uptime
+	}
+	return nil
+}
+
+func (x *RuuviCollectorStatus) GetAdapterState() RuuviAdapterState {
+	if x != nil {
+		return x.AdapterState
+	}
+	return RuuviAdapterState_RUUVI_ADAPTER_STATE_UNSPECIFIED
+}
+
+func (x *RuuviCollectorStatus) GetAdvertisementsSeen() uint64 {
+	if x != nil {
+		return x.AdvertisementsSeen
+	}
+	return 0
+}
+
+func (x *RuuviCollectorStatus) GetParseErrors() uint64 {
+	if x != nil {
+		return x.ParseErrors
+	}
+	return 0
+}
+
+func (x *RuuviCollectorStatus) GetQueuedBatches() uint32 {
+	if x != nil {
+		return x.QueuedBatches
+	}
+	return 0
+}
+
+func (x *RuuviCollectorStatus) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *RuuviCollectorStatus) GetReconnects() uint64 {
+	if x != nil {
+		return x.Reconnects
+	}
+	return 0
+}
+
+type RuuviReportStatusResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RuuviReportStatusResponse) Reset() {
+	*x = RuuviReportStatusResponse{}
+	mi := &file_ruuvi_v1_ruuvi_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RuuviReportStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RuuviReportStatusResponse) ProtoMessage() {}
+
+func (x *RuuviReportStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ruuvi_v1_ruuvi_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RuuviReportStatusResponse.ProtoReflect.Descriptor instead.
+func (*RuuviReportStatusResponse) Descriptor() ([]byte, []int) {
+	return file_ruuvi_v1_ruuvi_proto_rawDescGZIP(), []int{14}
+}
+
+type RuuviConfigureRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Collector string                 `protobuf:"bytes,1,opt,name=collector,proto3" json:"collector,omitempty"`
+	// Zero if the collector hasn't got any configuration from the server.
+	AppliedVersion uint64 `protobuf:"varint,2,opt,name=applied_version,json=appliedVersion,proto3" json:"applied_version,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *RuuviConfigureRequest) Reset() {
+	*x = RuuviConfigureRequest{}
+	mi := &file_ruuvi_v1_ruuvi_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RuuviConfigureRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RuuviConfigureRequest) ProtoMessage() {}
+
+func (x *RuuviConfigureRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ruuvi_v1_ruuvi_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RuuviConfigureRequest.ProtoReflect.Descriptor instead.
+func (*RuuviConfigureRequest) Descriptor() ([]byte, []int) {
+	return file_ruuvi_v1_ruuvi_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *RuuviConfigureRequest) GetCollector() string {
+	if x != nil {
+		return x.Collector
+	}
+	return ""
+}
+
+func (x *RuuviConfigureRequest) GetAppliedVersion() uint64 {
+	if x != nil {
+		return x.AppliedVersion
+	}
+	return 0
+}
+
+type RuuviCollectorConfig struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Hash of the other fields, changes whenever any of them do.
+	Version uint64 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	// Devices to collect and their names.
+	Devices []*RuuviDevice `protobuf:"bytes,2,rep,name=devices,proto3" json:"devices,omitempty"`
+	// How often measurements are sent, unset keeps collector's own setting.
+	SendInterval *durationpb.Duration `protobuf:"bytes,3,opt,name=send_interval,json=sendInterval,proto3" json:"send_interval,omitempty"`
+	// Scanning lasts scan_window in every scan_interval, unset window scans continuously.
+	ScanWindow    *durationpb.Duration `protobuf:"bytes,4,opt,name=scan_window,json=scanWindow,proto3" json:"scan_window,omitempty"`
+	ScanInterval  *durationpb.Duration `protobuf:"bytes,5,opt,name=scan_interval,json=scanInterval,proto3" json:"scan_interval,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RuuviCollectorConfig) Reset() {
+	*x = RuuviCollectorConfig{}
+	mi := &file_ruuvi_v1_ruuvi_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RuuviCollectorConfig) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RuuviCollectorConfig) ProtoMessage() {}
+
+func (x *RuuviCollectorConfig) ProtoReflect() protoreflect.Message {
+	mi := &file_ruuvi_v1_ruuvi_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RuuviCollectorConfig.ProtoReflect.Descriptor instead.
+func (*RuuviCollectorConfig) Descriptor() ([]byte, []int) {
+	return file_ruuvi_v1_ruuvi_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *RuuviCollectorConfig) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *RuuviCollectorConfig) GetDevices() []*RuuviDevice {
+	if x != nil {
+		return x.Devices
+	}
+	return nil
+}
+
+func (x *RuuviCollectorConfig) GetSendInterval() *durationpb.Duration {
+	if x != nil {
+		return x.SendInterval
+	}
+	return nil
+}
+
+func (x *RuuviCollectorConfig) GetScanWindow() *durationpb.Duration {
+	if x != nil {
+		return x.ScanWindow
+	}
+	return nil
+}
+
+func (x *RuuviCollectorConfig) GetScanInterval() *durationpb.Duration {
+	if x != nil {
+		return x.ScanInterval
+	}
+	return nil
+}
+
+var File_ruuvi_v1_ruuvi_proto protoreflect.FileDescriptor
+
+const file_ruuvi_v1_ruuvi_proto_rawDesc = "" +
+	"\n" +
+	"\x14ruuvi/v1/ruuvi.proto\x12\bruuvi.v1\x1a\x1egoogle/protobuf/duration.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\x9c\x02\n" +
+	"\x16RuuviStreamDataRequest\x12\x16\n" +
+	"\x06device\x18\x01 \x01(\tR\x06device\x12\x1f\n" +
+	"\vmac_address\x18\x02 \x01(\tR\n" +
+	"macAddress\x12 \n" +
+	"\vtemperature\x18\x03 \x01(\x02R\vtemperature\x12\x1a\n" +
+	"\bhumidity\x18\x04 \x01(\x02R\bhumidity\x12\x1a\n" +
+	"\bpressure\x18\x05 \x01(\x02R\bpressure\x12!\n" +
+	"\fbatter_volts\x18\x06 \x01(\x02R\vbatterVolts\x12\x12\n" +
+	"\x04rssi\x18\a \x01(\x05R\x04rssi\x128\n" +
+	"\ttimestamp\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\"\xa5\x01\n" +
+	"\x17RuuviStreamDataResponse\x12\x18\n" +
+	"\amessage\x18\x01 \x01(\tR\amessage\x12\x1a\n" +
+	"\baccepted\x18\x02 \x01(\rR\baccepted\x12\x1a\n" +
+	"\brejected\x18\x03 \x01(\rR\brejected\x128\n" +
+	"\n" +
+	"rejections\x18\x04 \x03(\v2\x18.ruuvi.v1.RuuviRejectionR\n" +
+	"rejections\">\n" +
+	"\x0eRuuviRejection\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\rR\x05index\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\"\xfa\x02\n" +
+	"\x15RuuviMeasurementBatch\x12\x1c\n" +
+	"\tcollector\x18\x01 \x01(\tR\tcollector\x12\x18\n" +
+	"\asession\x18\x02 \x01(\tR\asession\x12\x19\n" +
+	"\bbatch_id\x18\x03 \x01(\x04R\abatchId\x12D\n" +
+	"\fmeasurements\x18\x04 \x03(\v2 .ruuvi.v1.RuuviStreamDataRequestR\fmeasurements\x12/\n" +
+	"\adevices\x18\x05 \x03(\v2\x15.ruuvi.v1.RuuviDeviceR\adevices\x12T\n" +
+	"\x14compact_measurements\x18\x06 \x03(\v2!.ruuvi.v1.RuuviCompactMeasurementR\x13compactMeasurements\x12A\n" +
+	"\x0ebase_timestamp\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\rbaseTimestamp\"B\n" +
+	"\vRuuviDevice\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1f\n" +
+	"\vmac_address\x18\x02 \x01(\tR\n" +
+	"macAddress\"\xf4\x01\n" +
+	"\x17RuuviCompactMeasurement\x12\x16\n" +
+	"\x06device\x18\x01 \x01(\rR\x06device\x12 \n" +
+	"\vtemperature\x18\x02 \x01(\x02R\vtemperature\x12\x1a\n" +
+	"\bhumidity\x18\x03 \x01(\x02R\bhumidity\x12\x1a\n" +
+	"\bpressure\x18\x04 \x01(\x02R\bpressure\x12#\n" +
+	"\rbattery_volts\x18\x05 \x01(\x02R\fbatteryVolts\x12\x12\n" +
+	"\x04rssi\x18\x06 \x01(\x11R\x04rssi\x12.\n" +
+	"\x13timestamp_offset_ms\x18\a \x01(\x12R\x11timestampOffsetMs\"\xba\x01\n" +
+	"\rRuuviBatchAck\x12\x19\n" +
+	"\bbatch_id\x18\x01 \x01(\x04R\abatchId\x12\x1a\n" +
+	"\baccepted\x18\x02 \x01(\rR\baccepted\x12\x1a\n" +
+	"\brejected\x18\x03 \x01(\rR\brejected\x128\n" +
+	"\n" +
+	"rejections\x18\x04 \x03(\v2\x18.ruuvi.v1.RuuviRejectionR\n" +
+	"rejections\x12\x1c\n" +
+	"\tduplicate\x18\x05 \x01(\bR\tduplicate\"I\n" +
+	"\x15RuuviSubscribeRequest\x12\x18\n" +
+	"\adevices\x18\x01 \x03(\tR\adevices\x12\x16\n" +
+	"\x06groups\x18\x02 \x03(\tR\x06groups\"\x18\n" +
+	"\x16RuuviServerInfoRequest\"\xf5\x02\n" +
+	"\x17RuuviServerInfoResponse\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\tR\aversion\x12\x1d\n" +
+	"\n" +
+	"build_time\x18\x02 \x01(\tR\tbuildTime\x124\n" +
+	"\astarted\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\astarted\x121\n" +
+	"\x06uptime\x18\x04 \x01(\v2\x19.google.protobuf.DurationR\x06uptime\x12!\n" +
+	"\fdevice_count\x18\x05 \x01(\rR\vdeviceCount\x12+\n" +
+	"\x11measurement_count\x18\x06 \x01(\x04R\x10measurementCount\x125\n" +
+	"\x16throttled_measurements\x18\a \x01(\x04R\x15throttledMeasurements\x121\n" +
+	"\x14evicted_measurements\x18\b \x01(\x04R\x13evictedMeasurements\"d\n" +
+	"\x1cRuuviPushMeasurementsRequest\x12D\n" +
+	"\fmeasurements\x18\x01 \x03(\v2 .ruuvi.v1.RuuviStreamDataRequestR\fmeasurements\"\xbc\x01\n" +
+	"\x1bRuuviGetMeasurementsRequest\x12\x18\n" +
+	"\adevices\x18\x01 \x03(\tR\adevices\x120\n" +
+	"\x05since\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x05since\x120\n" +
+	"\x05until\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x05until\x12\x1f\n" +
+	"\vlatest_only\x18\x04 \x01(\bR\n" +
+	"latestOnly\"d\n" +
+	"\x1cRuuviGetMeasurementsResponse\x12D\n" +
+	"\fmeasurements\x18\x01 \x03(\v2 .ruuvi.v1.RuuviStreamDataRequestR\fmeasurements\"\xb2\x03\n" +
+	"\x14RuuviCollectorStatus\x12\x1c\n" +
+	"\tcollector\x18\x01 \x01(\tR\tcollector\x12\x18\n" +
+	"\asession\x18\x02 \x01(\tR\asession\x12\x18\n" +
+	"\aversion\x18\x03 \x01(\tR\aversion\x121\n" +
+	"\x06uptime\x18\x04 \x01(\v2\x19.google.protobuf.DurationR\x06uptime\x12@\n" +
+	"\radapter_state\x18\x05 \x01(\x0e2\x1b.ruuvi.v1.RuuviAdapterStateR\fadapterState\x12/\n" +
+	"\x13advertisements_seen\x18\x06 \x01(\x04R\x12advertisementsSeen\x12!\n" +
+	"\fparse_errors\x18\a \x01(\x04R\vparseErrors\x12%\n" +
+	"\x0equeued_batches\x18\b \x01(\rR\rqueuedBatches\x128\n" +
+	"\ttimestamp\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12\x1e\n" +
+	"\n" +
+	"reconnects\x18\n" +
+	" \x01(\x04R\n" +
+	"reconnects\"\x1b\n" +
+	"\x19RuuviReportStatusResponse\"^\n" +
+	"\x15RuuviConfigureRequest\x12\x1c\n" +
+	"\tcollector\x18\x01 \x01(\tR\tcollector\x12'\n" +
+	"\x0fapplied_version\x18\x02 \x01(\x04R\x0eappliedVersion\"\x9d\x02\n" +
+	"\x14RuuviCollectorConfig\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\x04R\aversion\x12/\n" +
+	"\adevices\x18\x02 \x03(\v2\x15.ruuvi.v1.RuuviDeviceR\adevices\x12>\n" +
+	"\rsend_interval\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\fsendInterval\x12:\n" +
+	"\vscan_window\x18\x04 \x01(\v2\x19.google.protobuf.DurationR\n" +
+	"scanWindow\x12>\n" +
+	"\rscan_interval\x18\x05 \x01(\v2\x19.google.protobuf.DurationR\fscanInterval*\x98\x01\n" +
+	"\x11RuuviAdapterState\x12#\n" +
+	"\x1fRUUVI_ADAPTER_STATE_UNSPECIFIED\x10\x00\x12\x1c\n" +
+	"\x18RUUVI_ADAPTER_STATE_DOWN\x10\x01\x12 \n" +
+	"\x1cRUUVI_ADAPTER_STATE_SCANNING\x10\x02\x12\x1e\n" +
+	"\x1aRUUVI_ADAPTER_STATE_FAILED\x10\x032\xc3\x05\n" +
 	"\x05Ruuvi\x12S\n" +
 	"\n" +
-	"StreamData\x12 .ruuvi.v1.RuuviStreamDataRequest\x1a!.ruuvi.v1.RuuviStreamDataResponse(\x01B\x93\x01\n" +
+	"StreamData\x12 .ruuvi.v1.RuuviStreamDataRequest\x1a!.ruuvi.v1.RuuviStreamDataResponse(\x01\x12K\n" +
+	"\vSendBatches\x12\x1f.ruuvi.v1.RuuviMeasurementBatch\x1a\x17.ruuvi.v1.RuuviBatchAck(\x010\x01\x12P\n" +
+	"\tSubscribe\x12\x1f.ruuvi.v1.RuuviSubscribeRequest\x1a .ruuvi.v1.RuuviStreamDataRequest0\x01\x12Y\n" +
+	"\rGetServerInfo\x12 .ruuvi.v1.RuuviServerInfoRequest\x1a!.ruuvi.v1.RuuviServerInfoResponse\"\x03\x90\x02\x01\x12]\n" +
+	"\x10PushMeasurements\x12&.ruuvi.v1.RuuviPushMeasurementsRequest\x1a!.ruuvi.v1.RuuviStreamDataResponse\x12e\n" +
+	"\x0fGetMeasurements\x12%.ruuvi.v1.RuuviGetMeasurementsRequest\x1a&.ruuvi.v1.RuuviGetMeasurementsResponse\"\x03\x90\x02\x01\x12S\n" +
+	"\fReportStatus\x12\x1e.ruuvi.v1.RuuviCollectorStatus\x1a#.ruuvi.v1.RuuviReportStatusResponse\x12P\n" +
+	"\tConfigure\x12\x1f.ruuvi.v1.RuuviConfigureRequest\x1a\x1e.ruuvi.v1.RuuviCollectorConfig(\x010\x01B\x93\x01\n" +
 	"\fcom.ruuvi.v1B\n" +
 	"RuuviProtoP\x01Z6weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1;ruuviv1\xa2\x02\x03RXX\xaa\x02\bRuuvi.V1\xca\x02\bRuuvi\\V1\xe2\x02\x14Ruuvi\\V1\\GPBMetadata\xea\x02\tRuuvi::V1b\x06proto3"
 
@@ -201,21 +1392,72 @@ func file_ruuvi_v1_ruuvi_proto_rawDescGZIP() []byte {
 	return file_ruuvi_v1_ruuvi_proto_rawDescData
 }
 
-var file_ruuvi_v1_ruuvi_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_ruuvi_v1_ruuvi_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_ruuvi_v1_ruuvi_proto_msgTypes = make([]protoimpl.MessageInfo, 17)
 var file_ruuvi_v1_ruuvi_proto_goTypes = []any{
-	(*RuuviStreamDataRequest)(nil),  // 0: ruuvi.v1.RuuviStreamDataRequest
-	(*RuuviStreamDataResponse)(nil), // 1: ruuvi.v1.RuuviStreamDataResponse
-	(*timestamppb.Timestamp)(nil),   // 2: google.protobuf.Timestamp
+	(RuuviAdapterState)(0),               // 0: ruuvi.v1.RuuviAdapterState
+	(*RuuviStreamDataRequest)(nil),       // 1: ruuvi.v1.RuuviStreamDataRequest
+	(*RuuviStreamDataResponse)(nil),      // 2: ruuvi.v1.RuuviStreamDataResponse
+	(*RuuviRejection)(nil),               // 3: ruuvi.v1.RuuviRejection
+	(*RuuviMeasurementBatch)(nil),        // 4: ruuvi.v1.RuuviMeasurementBatch
+	(*RuuviDevice)(nil),                  // 5: ruuvi.v1.RuuviDevice
+	(*RuuviCompactMeasurement)(nil),      // 6: ruuvi.v1.RuuviCompactMeasurement
+	(*RuuviBatchAck)(nil),                // 7: ruuvi.v1.RuuviBatchAck
+	(*RuuviSubscribeRequest)(nil),        // 8: ruuvi.v1.RuuviSubscribeRequest
+	(*RuuviServerInfoRequest)(nil),       // 9: ruuvi.v1.RuuviServerInfoRequest
+	(*RuuviServerInfoResponse)(nil),      // 10: ruuvi.v1.RuuviServerInfoResponse
+	(*RuuviPushMeasurementsRequest)(nil), // 11: ruuvi.v1.RuuviPushMeasurementsRequest
+	(*RuuviGetMeasurementsRequest)(nil),  // 12: ruuvi.v1.RuuviGetMeasurementsRequest
+	(*RuuviGetMeasurementsResponse)(nil), // 13: ruuvi.v1.RuuviGetMeasurementsResponse
+	(*RuuviCollectorStatus)(nil),         // 14: ruuvi.v1.RuuviCollectorStatus
+	(*RuuviReportStatusResponse)(nil),    // 15: ruuvi.v1.RuuviReportStatusResponse
+	(*RuuviConfigureRequest)(nil),        // 16: ruuvi.v1.RuuviConfigureRequest
+	(*RuuviCollectorConfig)(nil),         // 17: ruuvi.v1.RuuviCollectorConfig
+	(*timestamppb.Timestamp)(nil),        // 18: google.protobuf.Timestamp
+	(*durationpb.Duration)(nil),          // 19: google.protobuf.Duration
 }
 var file_ruuvi_v1_ruuvi_proto_depIdxs = []int32{
-	2, // 0: ruuvi.v1.RuuviStreamDataRequest.timestamp:type_name -> google.protobuf.Timestamp
-	0, // 1: ruuvi.v1.Ruuvi.StreamData:input_type -> ruuvi.v1.RuuviStreamDataRequest
-	1, // 2: ruuvi.v1.Ruuvi.StreamData:output_type -> ruuvi.v1.RuuviStreamDataResponse
-	2, // [2:3] is the sub-list for method output_type
-	1, // [1:2] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	18, // 0: ruuvi.v1.RuuviStreamDataRequest.timestamp:type_name -> google.protobuf.Timestamp
+	3,  // 1: ruuvi.v1.RuuviStreamDataResponse.rejections:type_name -> ruuvi.v1.RuuviRejection
+	1,  // 2: ruuvi.v1.RuuviMeasurementBatch.measurements:type_name -> ruuvi.v1.RuuviStreamDataRequest
+	5,  // 3: ruuvi.v1.RuuviMeasurementBatch.devices:type_name -> ruuvi.v1.RuuviDevice
+	6,  // 4: ruuvi.v1.RuuviMeasurementBatch.compact_measurements:type_name -> ruuvi.v1.RuuviCompactMeasurement
+	18, // 5: ruuvi.v1.RuuviMeasurementBatch.base_timestamp:type_name -> google.protobuf.Timestamp
+	3,  // 6: ruuvi.v1.RuuviBatchAck.rejections:type_name -> ruuvi.v1.RuuviRejection
+	18, // 7: ruuvi.v1.RuuviServerInfoResponse.started:type_name -> google.protobuf.Timestamp
+	19, // 8: ruuvi.v1.RuuviServerInfoResponse.uptime:type_name -> google.protobuf.Duration
+	1,  // 9: ruuvi.v1.RuuviPushMeasurementsRequest.measurements:type_name -> ruuvi.v1.RuuviStreamDataRequest
+	18, // 10: ruuvi.v1.RuuviGetMeasurementsRequest.since:type_name -> google.protobuf.Timestamp
+	18, // 11: ruuvi.v1.RuuviGetMeasurementsRequest.until:type_name -> google.protobuf.Timestamp
+	1,  // 12: ruuvi.v1.RuuviGetMeasurementsResponse.measurements:type_name -> ruuvi.v1.RuuviStreamDataRequest
+	19, // 13: ruuvi.v1.RuuviCollectorStatus.uptime:type_name -> google.protobuf.Duration
+	0,  // 14: ruuvi.v1.RuuviCollectorStatus.adapter_state:type_name -> ruuvi.v1.RuuviAdapterState
+	18, // 15: ruuvi.v1.RuuviCollectorStatus.timestamp:type_name -> google.protobuf.Timestamp
+	5,  // 16: ruuvi.v1.RuuviCollectorConfig.devices:type_name -> ruuvi.v1.RuuviDevice
+	19, // 17: ruuvi.v1.RuuviCollectorConfig.send_interval:type_name -> google.protobuf.Duration
+	19, // 18: ruuvi.v1.RuuviCollectorConfig.scan_window:type_name -> google.protobuf.Duration
+	19, // 19: ruuvi.v1.RuuviCollectorConfig.scan_interval:type_name -> google.protobuf.Duration
+	1,  // 20: ruuvi.v1.Ruuvi.StreamData:input_type -> ruuvi.v1.RuuviStreamDataRequest
+	4,  // 21: ruuvi.v1.Ruuvi.SendBatches:input_type -> ruuvi.v1.RuuviMeasurementBatch
+	8,  // 22: ruuvi.v1.Ruuvi.Subscribe:input_type -> ruuvi.v1.RuuviSubscribeRequest
+	9,  // 23: ruuvi.v1.Ruuvi.GetServerInfo:input_type -> ruuvi.v1.RuuviServerInfoRequest
+	11, // 24: ruuvi.v1.Ruuvi.PushMeasurements:input_type -> ruuvi.v1.RuuviPushMeasurementsRequest
+	12, // 25: ruuvi.v1.Ruuvi.GetMeasurements:input_type -> ruuvi.v1.RuuviGetMeasurementsRequest
+	14, // 26: ruuvi.v1.Ruuvi.ReportStatus:input_type -> ruuvi.v1.RuuviCollectorStatus
+	16, // 27: ruuvi.v1.Ruuvi.Configure:input_type -> ruuvi.v1.RuuviConfigureRequest
+	2,  // 28: ruuvi.v1.Ruuvi.StreamData:output_type -> ruuvi.v1.RuuviStreamDataResponse
+	7,  // 29: ruuvi.v1.Ruuvi.SendBatches:output_type -> ruuvi.v1.RuuviBatchAck
+	1,  // 30: ruuvi.v1.Ruuvi.Subscribe:output_type -> ruuvi.v1.RuuviStreamDataRequest
+	10, // 31: ruuvi.v1.Ruuvi.GetServerInfo:output_type -> ruuvi.v1.RuuviServerInfoResponse
+	2,  // 32: ruuvi.v1.Ruuvi.PushMeasurements:output_type -> ruuvi.v1.RuuviStreamDataResponse
+	13, // 33: ruuvi.v1.Ruuvi.GetMeasurements:output_type -> ruuvi.v1.RuuviGetMeasurementsResponse
+	15, // 34: ruuvi.v1.Ruuvi.ReportStatus:output_type -> ruuvi.v1.RuuviReportStatusResponse
+	17, // 35: ruuvi.v1.Ruuvi.Configure:output_type -> ruuvi.v1.RuuviCollectorConfig
+	28, // [28:36] is the sub-list for method output_type
+	20, // [20:28] is the sub-list for method input_type
+	20, // [20:20] is the sub-list for extension type_name
+	20, // [20:20] is the sub-list for extension extendee
+	0,  // [0:20] is the sub-list for field type_name
 }
 
 func init() { file_ruuvi_v1_ruuvi_proto_init() }
@@ -228,13 +1470,14 @@ func file_ruuvi_v1_ruuvi_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_ruuvi_v1_ruuvi_proto_rawDesc), len(file_ruuvi_v1_ruuvi_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   2,
+			NumEnums:      1,
+			NumMessages:   17,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_ruuvi_v1_ruuvi_proto_goTypes,
 		DependencyIndexes: file_ruuvi_v1_ruuvi_proto_depIdxs,
+		EnumInfos:         file_ruuvi_v1_ruuvi_proto_enumTypes,
 		MessageInfos:      file_ruuvi_v1_ruuvi_proto_msgTypes,
 	}.Build()
 	File_ruuvi_v1_ruuvi_proto = out.File
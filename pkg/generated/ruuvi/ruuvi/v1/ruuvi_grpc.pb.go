@@ -19,7 +19,14 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	Ruuvi_StreamData_FullMethodName = "/ruuvi.v1.Ruuvi/StreamData"
+	Ruuvi_StreamData_FullMethodName       = "/ruuvi.v1.Ruuvi/StreamData"
+	Ruuvi_SendBatches_FullMethodName      = "/ruuvi.v1.Ruuvi/SendBatches"
+	Ruuvi_Subscribe_FullMethodName        = "/ruuvi.v1.Ruuvi/Subscribe"
+	Ruuvi_GetServerInfo_FullMethodName    = "/ruuvi.v1.Ruuvi/GetServerInfo"
+	Ruuvi_PushMeasurements_FullMethodName = "/ruuvi.v1.Ruuvi/PushMeasurements"
+	Ruuvi_GetMeasurements_FullMethodName  = "/ruuvi.v1.Ruuvi/GetMeasurements"
+	Ruuvi_ReportStatus_FullMethodName     = "/ruuvi.v1.Ruuvi/ReportStatus"
+	Ruuvi_Configure_FullMethodName        = "/ruuvi.v1.Ruuvi/Configure"
 )
 
 // RuuviClient is the client API for Ruuvi service.
@@ -27,6 +34,23 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type RuuviClient interface {
 	StreamData(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[RuuviStreamDataRequest, RuuviStreamDataResponse], error)
+	// SendBatches acknowledges every received batch. Batches are deduplicated by
+	// collector, session and batch ID, hence resending unacknowledged ones is safe.
+	SendBatches(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[RuuviMeasurementBatch, RuuviBatchAck], error)
+	// Subscribe streams measurements as soon as the server has accepted them.
+	// Empty filters match every device.
+	Subscribe(ctx context.Context, in *RuuviSubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RuuviStreamDataRequest], error)
+	GetServerInfo(ctx context.Context, in *RuuviServerInfoRequest, opts ...grpc.CallOption) (*RuuviServerInfoResponse, error)
+	// PushMeasurements stores measurements sent in a single request. Meant for
+	// clients which can't stream, e.g. shell scripts using Connect's JSON protocol.
+	PushMeasurements(ctx context.Context, in *RuuviPushMeasurementsRequest, opts ...grpc.CallOption) (*RuuviStreamDataResponse, error)
+	GetMeasurements(ctx context.Context, in *RuuviGetMeasurementsRequest, opts ...grpc.CallOption) (*RuuviGetMeasurementsResponse, error)
+	// ReportStatus is called periodically by collectors, so that a collector
+	// hearing no tags can be told apart from a dead one.
+	ReportStatus(ctx context.Context, in *RuuviCollectorStatus, opts ...grpc.CallOption) (*RuuviReportStatusResponse, error)
+	// Configure pushes collector configuration whenever it changes. Collector sends
+	// the version it has applied, initially the one it had cached from earlier.
+	Configure(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[RuuviConfigureRequest, RuuviCollectorConfig], error)
 }
 
 type ruuviClient struct {
@@ -50,11 +74,113 @@ func (c *ruuviClient) StreamData(ctx context.Context, opts ...grpc.CallOption) (
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type Ruuvi_StreamDataClient = grpc.ClientStreamingClient[RuuviStreamDataRequest, RuuviStreamDataResponse]
 
+func (c *ruuviClient) SendBatches(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[RuuviMeasurementBatch, RuuviBatchAck], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Ruuvi_ServiceDesc.Streams[1], Ruuvi_SendBatches_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[RuuviMeasurementBatch, RuuviBatchAck]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Ruuvi_SendBatchesClient = grpc.BidiStreamingClient[RuuviMeasurementBatch, RuuviBatchAck]
+
+func (c *ruuviClient) Subscribe(ctx context.Context, in *RuuviSubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RuuviStreamDataRequest], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Ruuvi_ServiceDesc.Streams[2], Ruuvi_Subscribe_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[RuuviSubscribeRequest, RuuviStreamDataRequest]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Ruuvi_SubscribeClient = grpc.ServerStreamingClient[RuuviStreamDataRequest]
+
+func (c *ruuviClient) GetServerInfo(ctx context.Context, in *RuuviServerInfoRequest, opts ...grpc.CallOption) (*RuuviServerInfoResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RuuviServerInfoResponse)
+	err := c.cc.Invoke(ctx, Ruuvi_GetServerInfo_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ruuviClient) PushMeasurements(ctx context.Context, in *RuuviPushMeasurementsRequest, opts ...grpc.CallOption) (*RuuviStreamDataResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RuuviStreamDataResponse)
+	err := c.cc.Invoke(ctx, Ruuvi_PushMeasurements_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ruuviClient) GetMeasurements(ctx context.Context, in *RuuviGetMeasurementsRequest, opts ...grpc.CallOption) (*RuuviGetMeasurementsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RuuviGetMeasurementsResponse)
+	err := c.cc.Invoke(ctx, Ruuvi_GetMeasurements_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ruuviClient) ReportStatus(ctx context.Context, in *RuuviCollectorStatus, opts ...grpc.CallOption) (*RuuviReportStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RuuviReportStatusResponse)
+	err := c.cc.Invoke(ctx, Ruuvi_ReportStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ruuviClient) Configure(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[RuuviConfigureRequest, RuuviCollectorConfig], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Ruuvi_ServiceDesc.Streams[3], Ruuvi_Configure_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[RuuviConfigureRequest, RuuviCollectorConfig]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Ruuvi_ConfigureClient = grpc.BidiStreamingClient[RuuviConfigureRequest, RuuviCollectorConfig]
+
 // RuuviServer is the server API for Ruuvi service.
 // All implementations must embed UnimplementedRuuviServer
 // for forward compatibility.
 type RuuviServer interface {
 	StreamData(grpc.ClientStreamingServer[RuuviStreamDataRequest, RuuviStreamDataResponse]) error
+	// SendBatches acknowledges every received batch. Batches are deduplicated by
+	// collector, session and batch ID, hence resending unacknowledged ones is safe.
+	SendBatches(grpc.BidiStreamingServer[RuuviMeasurementBatch, RuuviBatchAck]) error
+	// Subscribe streams measurements as soon as the server has accepted them.
+	// Empty filters match every device.
+	Subscribe(*RuuviSubscribeRequest, grpc.ServerStreamingServer[RuuviStreamDataRequest]) error
+	GetServerInfo(context.Context, *RuuviServerInfoRequest) (*RuuviServerInfoResponse, error)
+	// PushMeasurements stores measurements sent in a single request. Meant for
+	// clients which can't stream, e.g. shell scripts using Connect's JSON protocol.
+	PushMeasurements(context.Context, *RuuviPushMeasurementsRequest) (*RuuviStreamDataResponse, error)
+	GetMeasurements(context.Context, *RuuviGetMeasurementsRequest) (*RuuviGetMeasurementsResponse, error)
+	// ReportStatus is called periodically by collectors, so that a collector
+	// hearing no tags can be told apart from a dead one.
+	ReportStatus(context.Context, *RuuviCollectorStatus) (*RuuviReportStatusResponse, error)
+	// Configure pushes collector configuration whenever it changes. Collector sends
+	// the version it has applied, initially the one it had cached from earlier.
+	Configure(grpc.BidiStreamingServer[RuuviConfigureRequest, RuuviCollectorConfig]) error
 	mustEmbedUnimplementedRuuviServer()
 }
 
@@ -68,6 +194,27 @@ type UnimplementedRuuviServer struct{}
 func (UnimplementedRuuviServer) StreamData(grpc.ClientStreamingServer[RuuviStreamDataRequest, RuuviStreamDataResponse]) error {
 	return status.Errorf(codes.Unimplemented, "method StreamData not implemented")
 }
+func (UnimplementedRuuviServer) SendBatches(grpc.BidiStreamingServer[RuuviMeasurementBatch, RuuviBatchAck]) error {
+	return status.Errorf(codes.Unimplemented, "method SendBatches not implemented")
+}
+func (UnimplementedRuuviServer) Subscribe(*RuuviSubscribeRequest, grpc.ServerStreamingServer[RuuviStreamDataRequest]) error {
+	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
+func (UnimplementedRuuviServer) GetServerInfo(context.Context, *RuuviServerInfoRequest) (*RuuviServerInfoResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetServerInfo not implemented")
+}
+func (UnimplementedRuuviServer) PushMeasurements(context.Context, *RuuviPushMeasurementsRequest) (*RuuviStreamDataResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PushMeasurements not implemented")
+}
+func (UnimplementedRuuviServer) GetMeasurements(context.Context, *RuuviGetMeasurementsRequest) (*RuuviGetMeasurementsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMeasurements not implemented")
+}
+func (UnimplementedRuuviServer) ReportStatus(context.Context, *RuuviCollectorStatus) (*RuuviReportStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportStatus not implemented")
+}
+func (UnimplementedRuuviServer) Configure(grpc.BidiStreamingServer[RuuviConfigureRequest, RuuviCollectorConfig]) error {
+	return status.Errorf(codes.Unimplemented, "method Configure not implemented")
+}
 func (UnimplementedRuuviServer) mustEmbedUnimplementedRuuviServer() {}
 func (UnimplementedRuuviServer) testEmbeddedByValue()               {}
 
@@ -96,19 +243,150 @@ func _Ruuvi_StreamData_Handler(srv interface{}, stream grpc.ServerStream) error
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type Ruuvi_StreamDataServer = grpc.ClientStreamingServer[RuuviStreamDataRequest, RuuviStreamDataResponse]
 
+func _Ruuvi_SendBatches_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(RuuviServer).SendBatches(&grpc.GenericServerStream[RuuviMeasurementBatch, RuuviBatchAck]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Ruuvi_SendBatchesServer = grpc.BidiStreamingServer[RuuviMeasurementBatch, RuuviBatchAck]
+
+func _Ruuvi_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RuuviSubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RuuviServer).Subscribe(m, &grpc.GenericServerStream[RuuviSubscribeRequest, RuuviStreamDataRequest]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Ruuvi_SubscribeServer = grpc.ServerStreamingServer[RuuviStreamDataRequest]
+
+func _Ruuvi_GetServerInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RuuviServerInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuuviServer).GetServerInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Ruuvi_GetServerInfo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuuviServer).GetServerInfo(ctx, req.(*RuuviServerInfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ruuvi_PushMeasurements_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RuuviPushMeasurementsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuuviServer).PushMeasurements(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Ruuvi_PushMeasurements_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuuviServer).PushMeasurements(ctx, req.(*RuuviPushMeasurementsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ruuvi_GetMeasurements_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RuuviGetMeasurementsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuuviServer).GetMeasurements(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Ruuvi_GetMeasurements_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuuviServer).GetMeasurements(ctx, req.(*RuuviGetMeasurementsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ruuvi_ReportStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RuuviCollectorStatus)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuuviServer).ReportStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Ruuvi_ReportStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuuviServer).ReportStatus(ctx, req.(*RuuviCollectorStatus))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ruuvi_Configure_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(RuuviServer).Configure(&grpc.GenericServerStream[RuuviConfigureRequest, RuuviCollectorConfig]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Ruuvi_ConfigureServer = grpc.BidiStreamingServer[RuuviConfigureRequest, RuuviCollectorConfig]
+
 // Ruuvi_ServiceDesc is the grpc.ServiceDesc for Ruuvi service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
 var Ruuvi_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "ruuvi.v1.Ruuvi",
 	HandlerType: (*RuuviServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetServerInfo",
+			Handler:    _Ruuvi_GetServerInfo_Handler,
+		},
+		{
+			MethodName: "PushMeasurements",
+			Handler:    _Ruuvi_PushMeasurements_Handler,
+		},
+		{
+			MethodName: "GetMeasurements",
+			Handler:    _Ruuvi_GetMeasurements_Handler,
+		},
+		{
+			MethodName: "ReportStatus",
+			Handler:    _Ruuvi_ReportStatus_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "StreamData",
 			Handler:       _Ruuvi_StreamData_Handler,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "SendBatches",
+			Handler:       _Ruuvi_SendBatches_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Subscribe",
+			Handler:       _Ruuvi_Subscribe_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Configure",
+			Handler:       _Ruuvi_Configure_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "ruuvi/v1/ruuvi.proto",
 }
@@ -0,0 +1,328 @@
+// Code generated by protoc-gen-connect-go. DO NOT EDIT.
+//
+// Source: ruuvi/v1/ruuvi.proto
+
+package ruuviv1connect
+
+import (
+	connect "connectrpc.com/connect"
+	context "context"
+	errors "errors"
+	http "net/http"
+	strings "strings"
+	v1 "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+)
+
+// This is a compile-time assertion to ensure that this generated file and the connect package are
+// compatible. If you get a compiler error that this constant is not defined, this code was
+// generated with a version of connect newer than the one compiled into your binary. You can fix the
+// problem by either regenerating this code with an older version of connect or updating the connect
+// version compiled into your binary.
+const _ = connect.IsAtLeastVersion1_13_0
+
+const (
+	// RuuviName is the fully-qualified name of the Ruuvi service.
+	RuuviName = "ruuvi.v1.Ruuvi"
+)
+
+// These constants are the fully-qualified names of the RPCs defined in this package. They're
+// exposed at runtime as Spec.Procedure and as the final two segments of the HTTP route.
+//
+// Note that these are different from the fully-qualified method names used by
+// google.golang.org/protobuf/reflect/protoreflect. To convert from these constants to
+// reflection-formatted method names, remove the leading slash and convert the remaining slash to a
+// period.
+const (
+	// RuuviStreamDataProcedure is the fully-qualified name of the Ruuvi's StreamData RPC.
+	RuuviStreamDataProcedure = "/ruuvi.v1.Ruuvi/StreamData"
+	// RuuviSendBatchesProcedure is the fully-qualified name of the Ruuvi's SendBatches RPC.
+	RuuviSendBatchesProcedure = "/ruuvi.v1.Ruuvi/SendBatches"
+	// RuuviSubscribeProcedure is the fully-qualified name of the Ruuvi's Subscribe RPC.
+	RuuviSubscribeProcedure = "/ruuvi.v1.Ruuvi/Subscribe"
+	// RuuviGetServerInfoProcedure is the fully-qualified name of the Ruuvi's GetServerInfo RPC.
+	RuuviGetServerInfoProcedure = "/ruuvi.v1.Ruuvi/GetServerInfo"
+	// RuuviPushMeasurementsProcedure is the fully-qualified name of the Ruuvi's PushMeasurements RPC.
+	RuuviPushMeasurementsProcedure = "/ruuvi.v1.Ruuvi/PushMeasurements"
+	// RuuviGetMeasurementsProcedure is the fully-qualified name of the Ruuvi's GetMeasurements RPC.
+	RuuviGetMeasurementsProcedure = "/ruuvi.v1.Ruuvi/GetMeasurements"
+	// RuuviReportStatusProcedure is the fully-qualified name of the Ruuvi's ReportStatus RPC.
+	RuuviReportStatusProcedure = "/ruuvi.v1.Ruuvi/ReportStatus"
+	// RuuviConfigureProcedure is the fully-qualified name of the Ruuvi's Configure RPC.
+	RuuviConfigureProcedure = "/ruuvi.v1.Ruuvi/Configure"
+)
+
+// RuuviClient is a client for the ruuvi.v1.Ruuvi service.
+type RuuviClient interface {
+	StreamData(context.Context) *connect.ClientStreamForClient[v1.RuuviStreamDataRequest, v1.RuuviStreamDataResponse]
+	// SendBatches acknowledges every received batch. Batches are deduplicated by
+	// collector, session and batch ID, hence resending unacknowledged ones is safe.
+	SendBatches(context.Context) *connect.BidiStreamForClient[v1.RuuviMeasurementBatch, v1.RuuviBatchAck]
+	// Subscribe streams measurements as soon as the server has accepted them.
+	// Empty filters match every device.
+	Subscribe(context.Context, *connect.Request[v1.RuuviSubscribeRequest]) (*connect.ServerStreamForClient[v1.RuuviStreamDataRequest], error)
+	GetServerInfo(context.Context, *connect.Request[v1.RuuviServerInfoRequest]) (*connect.Response[v1.RuuviServerInfoResponse], error)
+	// PushMeasurements stores measurements sent in a single request. Meant for
+	// clients which can't stream, e.g. shell scripts using Connect's JSON protocol.
+	PushMeasurements(context.Context, *connect.Request[v1.RuuviPushMeasurementsRequest]) (*connect.Response[v1.RuuviStreamDataResponse], error)
+	GetMeasurements(context.Context, *connect.Request[v1.RuuviGetMeasurementsRequest]) (*connect.Response[v1.RuuviGetMeasurementsResponse], error)
+	// ReportStatus is called periodically by collectors, so that a collector
+	// hearing no tags can be told apart from a dead one.
+	ReportStatus(context.Context, *connect.Request[v1.RuuviCollectorStatus]) (*connect.Response[v1.RuuviReportStatusResponse], error)
+	// Configure pushes collector configuration whenever it changes. Collector sends
+	// the version it has applied, initially the one it had cached from earlier.
+	Configure(context.Context) *connect.BidiStreamForClient[v1.RuuviConfigureRequest, v1.RuuviCollectorConfig]
+}
+
+// NewRuuviClient constructs a client for the ruuvi.v1.Ruuvi service. By default, it uses the
+// Connect protocol with the binary Protobuf Codec, asks for gzipped responses, and sends
+// uncompressed requests. To use the gRPC or gRPC-Web protocols, supply the connect.WithGRPC() or
+// connect.WithGRPCWeb() options.
+//
+// The URL supplied here should be the base URL for the Connect or gRPC server (for example,
+// http://api.acme.com or https://acme.com/grpc).
+func NewRuuviClient(httpClient connect.HTTPClient, baseURL string, opts ...connect.ClientOption) RuuviClient {
+	baseURL = strings.TrimRight(baseURL, "/")
+	ruuviMethods := v1.File_ruuvi_v1_ruuvi_proto.Services().ByName("Ruuvi").Methods()
+	return &ruuviClient{
+		streamData: connect.NewClient[v1.RuuviStreamDataRequest, v1.RuuviStreamDataResponse](
+			httpClient,
+			baseURL+RuuviStreamDataProcedure,
+			connect.WithSchema(ruuviMethods.ByName("StreamData")),
+			connect.WithClientOptions(opts...),
+		),
+		sendBatches: connect.NewClient[v1.RuuviMeasurementBatch, v1.RuuviBatchAck](
+			httpClient,
+			baseURL+RuuviSendBatchesProcedure,
+			connect.WithSchema(ruuviMethods.ByName("SendBatches")),
+			connect.WithClientOptions(opts...),
+		),
+		subscribe: connect.NewClient[v1.RuuviSubscribeRequest, v1.RuuviStreamDataRequest](
+			httpClient,
+			baseURL+RuuviSubscribeProcedure,
+			connect.WithSchema(ruuviMethods.ByName("Subscribe")),
+			connect.WithClientOptions(opts...),
+		),
+		getServerInfo: connect.NewClient[v1.RuuviServerInfoRequest, v1.RuuviServerInfoResponse](
+			httpClient,
+			baseURL+RuuviGetServerInfoProcedure,
+			connect.WithSchema(ruuviMethods.ByName("GetServerInfo")),
+			connect.WithIdempotency(connect.IdempotencyNoSideEffects),
+			connect.WithClientOptions(opts...),
+		),
+		pushMeasurements: connect.NewClient[v1.RuuviPushMeasurementsRequest, v1.RuuviStreamDataResponse](
+			httpClient,
+			baseURL+RuuviPushMeasurementsProcedure,
+			connect.WithSchema(ruuviMethods.ByName("PushMeasurements")),
+			connect.WithClientOptions(opts...),
+		),
+		getMeasurements: connect.NewClient[v1.RuuviGetMeasurementsRequest, v1.RuuviGetMeasurementsResponse](
+			httpClient,
+			baseURL+RuuviGetMeasurementsProcedure,
+			connect.WithSchema(ruuviMethods.ByName("GetMeasurements")),
+			connect.WithIdempotency(connect.IdempotencyNoSideEffects),
+			connect.WithClientOptions(opts...),
+		),
+		reportStatus: connect.NewClient[v1.RuuviCollectorStatus, v1.RuuviReportStatusResponse](
+			httpClient,
+			baseURL+RuuviReportStatusProcedure,
+			connect.WithSchema(ruuviMethods.ByName("ReportStatus")),
+			connect.WithClientOptions(opts...),
+		),
+		configure: connect.NewClient[v1.RuuviConfigureRequest, v1.RuuviCollectorConfig](
+			httpClient,
+			baseURL+RuuviConfigureProcedure,
+			connect.WithSchema(ruuviMethods.ByName("Configure")),
+			connect.WithClientOptions(opts...),
+		),
+	}
+}
+
+// ruuviClient implements RuuviClient.
+type ruuviClient struct {
+	streamData       *connect.Client[v1.RuuviStreamDataRequest, v1.RuuviStreamDataResponse]
+	sendBatches      *connect.Client[v1.RuuviMeasurementBatch, v1.RuuviBatchAck]
+	subscribe        *connect.Client[v1.RuuviSubscribeRequest, v1.RuuviStreamDataRequest]
+	getServerInfo    *connect.Client[v1.RuuviServerInfoRequest, v1.RuuviServerInfoResponse]
+	pushMeasurements *connect.Client[v1.RuuviPushMeasurementsRequest, v1.RuuviStreamDataResponse]
+	getMeasurements  *connect.Client[v1.RuuviGetMeasurementsRequest, v1.RuuviGetMeasurementsResponse]
+	reportStatus     *connect.Client[v1.RuuviCollectorStatus, v1.RuuviReportStatusResponse]
+	configure        *connect.Client[v1.RuuviConfigureRequest, v1.RuuviCollectorConfig]
+}
+
+// StreamData calls ruuvi.v1.Ruuvi.StreamData.
+func (c *ruuviClient) StreamData(ctx context.Context) *connect.ClientStreamForClient[v1.RuuviStreamDataRequest, v1.RuuviStreamDataResponse] {
+	return c.streamData.CallClientStream(ctx)
+}
+
+// SendBatches calls ruuvi.v1.Ruuvi.SendBatches.
+func (c *ruuviClient) SendBatches(ctx context.Context) *connect.BidiStreamForClient[v1.RuuviMeasurementBatch, v1.RuuviBatchAck] {
+	return c.sendBatches.CallBidiStream(ctx)
+}
+
+// Subscribe calls ruuvi.v1.Ruuvi.Subscribe.
+func (c *ruuviClient) Subscribe(ctx context.Context, req *connect.Request[v1.RuuviSubscribeRequest]) (*connect.ServerStreamForClient[v1.RuuviStreamDataRequest], error) {
+	return c.subscribe.CallServerStream(ctx, req)
+}
+
+// GetServerInfo calls ruuvi.v1.Ruuvi.GetServerInfo.
+func (c *ruuviClient) GetServerInfo(ctx context.Context, req *connect.Request[v1.RuuviServerInfoRequest]) (*connect.Response[v1.RuuviServerInfoResponse], error) {
+	return c.getServerInfo.CallUnary(ctx, req)
+}
+
+// PushMeasurements calls ruuvi.v1.Ruuvi.PushMeasurements.
+func (c *ruuviClient) PushMeasurements(ctx context.Context, req *connect.Request[v1.RuuviPushMeasurementsRequest]) (*connect.Response[v1.RuuviStreamDataResponse], error) {
+	return c.pushMeasurements.CallUnary(ctx, req)
+}
+
+// GetMeasurements calls ruuvi.v1.Ruuvi.GetMeasurements.
+func (c *ruuviClient) GetMeasurements(ctx context.Context, req *connect.Request[v1.RuuviGetMeasurementsRequest]) (*connect.Response[v1.RuuviGetMeasurementsResponse], error) {
+	return c.getMeasurements.CallUnary(ctx, req)
+}
+
+// ReportStatus calls ruuvi.v1.Ruuvi.ReportStatus.
+func (c *ruuviClient) ReportStatus(ctx context.Context, req *connect.Request[v1.RuuviCollectorStatus]) (*connect.Response[v1.RuuviReportStatusResponse], error) {
+	return c.reportStatus.CallUnary(ctx, req)
+}
+
+// Configure calls ruuvi.v1.Ruuvi.Configure.
+func (c *ruuviClient) Configure(ctx context.Context) *connect.BidiStreamForClient[v1.RuuviConfigureRequest, v1.RuuviCollectorConfig] {
+	return c.configure.CallBidiStream(ctx)
+}
+
+// RuuviHandler is an implementation of the ruuvi.v1.Ruuvi service.
+type RuuviHandler interface {
+	StreamData(context.Context, *connect.ClientStream[v1.RuuviStreamDataRequest]) (*connect.Response[v1.RuuviStreamDataResponse], error)
+	// SendBatches acknowledges every received batch. Batches are deduplicated by
+	// collector, session and batch ID, hence resending unacknowledged ones is safe.
+	SendBatches(context.Context, *connect.BidiStream[v1.RuuviMeasurementBatch, v1.RuuviBatchAck]) error
+	// Subscribe streams measurements as soon as the server has accepted them.
+	// Empty filters match every device.
+	Subscribe(context.Context, *connect.Request[v1.RuuviSubscribeRequest], *connect.ServerStream[v1.RuuviStreamDataRequest]) error
+	GetServerInfo(context.Context, *connect.Request[v1.RuuviServerInfoRequest]) (*connect.Response[v1.RuuviServerInfoResponse], error)
+	// PushMeasurements stores measurements sent in a single request. Meant for
+	// clients which can't stream, e.g. shell scripts using Connect's JSON protocol.
+	PushMeasurements(context.Context, *connect.Request[v1.RuuviPushMeasurementsRequest]) (*connect.Response[v1.RuuviStreamDataResponse], error)
+	GetMeasurements(context.Context, *connect.Request[v1.RuuviGetMeasurementsRequest]) (*connect.Response[v1.RuuviGetMeasurementsResponse], error)
+	// ReportStatus is called periodically by collectors, so that a collector
+	// hearing no tags can be told apart from a dead one.
+	ReportStatus(context.Context, *connect.Request[v1.RuuviCollectorStatus]) (*connect.Response[v1.RuuviReportStatusResponse], error)
+	// Configure pushes collector configuration whenever it changes. Collector sends
+	// the version it has applied, initially the one it had cached from earlier.
+	Configure(context.Context, *connect.BidiStream[v1.RuuviConfigureRequest, v1.RuuviCollectorConfig]) error
+}
+
+// NewRuuviHandler builds an HTTP handler from the service implementation. It returns the path on
+// which to mount the handler and the handler itself.
+//
+// By default, handlers support the Connect, gRPC, and gRPC-Web protocols with the binary Protobuf
+// and JSON codecs. They also support gzip compression.
+func NewRuuviHandler(svc RuuviHandler, opts ...connect.HandlerOption) (string, http.Handler) {
+	ruuviMethods := v1.File_ruuvi_v1_ruuvi_proto.Services().ByName("Ruuvi").Methods()
+	ruuviStreamDataHandler := connect.NewClientStreamHandler(
+		RuuviStreamDataProcedure,
+		svc.StreamData,
+		connect.WithSchema(ruuviMethods.ByName("StreamData")),
+		connect.WithHandlerOptions(opts...),
+	)
+	ruuviSendBatchesHandler := connect.NewBidiStreamHandler(
+		RuuviSendBatchesProcedure,
+		svc.SendBatches,
+		connect.WithSchema(ruuviMethods.ByName("SendBatches")),
+		connect.WithHandlerOptions(opts...),
+	)
+	ruuviSubscribeHandler := connect.NewServerStreamHandler(
+		RuuviSubscribeProcedure,
+		svc.Subscribe,
+		connect.WithSchema(ruuviMethods.ByName("Subscribe")),
+		connect.WithHandlerOptions(opts...),
+	)
+	ruuviGetServerInfoHandler := connect.NewUnaryHandler(
+		RuuviGetServerInfoProcedure,
+		svc.GetServerInfo,
+		connect.WithSchema(ruuviMethods.ByName("GetServerInfo")),
+		connect.WithIdempotency(connect.IdempotencyNoSideEffects),
+		connect.WithHandlerOptions(opts...),
+	)
+	ruuviPushMeasurementsHandler := connect.NewUnaryHandler(
+		RuuviPushMeasurementsProcedure,
+		svc.PushMeasurements,
+		connect.WithSchema(ruuviMethods.ByName("PushMeasurements")),
+		connect.WithHandlerOptions(opts...),
+	)
+	ruuviGetMeasurementsHandler := connect.NewUnaryHandler(
+		RuuviGetMeasurementsProcedure,
+		svc.GetMeasurements,
+		connect.WithSchema(ruuviMethods.ByName("GetMeasurements")),
+		connect.WithIdempotency(connect.IdempotencyNoSideEffects),
+		connect.WithHandlerOptions(opts...),
+	)
+	ruuviReportStatusHandler := connect.NewUnaryHandler(
+		RuuviReportStatusProcedure,
+		svc.ReportStatus,
+		connect.WithSchema(ruuviMethods.ByName("ReportStatus")),
+		connect.WithHandlerOptions(opts...),
+	)
+	ruuviConfigureHandler := connect.NewBidiStreamHandler(
+		RuuviConfigureProcedure,
+		svc.Configure,
+		connect.WithSchema(ruuviMethods.ByName("Configure")),
+		connect.WithHandlerOptions(opts...),
+	)
+	return "/ruuvi.v1.Ruuvi/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case RuuviStreamDataProcedure:
+			ruuviStreamDataHandler.ServeHTTP(w, r)
+		case RuuviSendBatchesProcedure:
+			ruuviSendBatchesHandler.ServeHTTP(w, r)
+		case RuuviSubscribeProcedure:
+			ruuviSubscribeHandler.ServeHTTP(w, r)
+		case RuuviGetServerInfoProcedure:
+			ruuviGetServerInfoHandler.ServeHTTP(w, r)
+		case RuuviPushMeasurementsProcedure:
+			ruuviPushMeasurementsHandler.ServeHTTP(w, r)
+		case RuuviGetMeasurementsProcedure:
+			ruuviGetMeasurementsHandler.ServeHTTP(w, r)
+		case RuuviReportStatusProcedure:
+			ruuviReportStatusHandler.ServeHTTP(w, r)
+		case RuuviConfigureProcedure:
+			ruuviConfigureHandler.ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// UnimplementedRuuviHandler returns CodeUnimplemented from all methods.
+type UnimplementedRuuviHandler struct{}
+
+func (UnimplementedRuuviHandler) StreamData(context.Context, *connect.ClientStream[v1.RuuviStreamDataRequest]) (*connect.Response[v1.RuuviStreamDataResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("ruuvi.v1.Ruuvi.StreamData is not implemented"))
+}
+
+func (UnimplementedRuuviHandler) SendBatches(context.Context, *connect.BidiStream[v1.RuuviMeasurementBatch, v1.RuuviBatchAck]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("ruuvi.v1.Ruuvi.SendBatches is not implemented"))
+}
+
+func (UnimplementedRuuviHandler) Subscribe(context.Context, *connect.Request[v1.RuuviSubscribeRequest], *connect.ServerStream[v1.RuuviStreamDataRequest]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("ruuvi.v1.Ruuvi.Subscribe is not implemented"))
+}
+
+func (UnimplementedRuuviHandler) GetServerInfo(context.Context, *connect.Request[v1.RuuviServerInfoRequest]) (*connect.Response[v1.RuuviServerInfoResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("ruuvi.v1.Ruuvi.GetServerInfo is not implemented"))
+}
+
+func (UnimplementedRuuviHandler) PushMeasurements(context.Context, *connect.Request[v1.RuuviPushMeasurementsRequest]) (*connect.Response[v1.RuuviStreamDataResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("ruuvi.v1.Ruuvi.PushMeasurements is not implemented"))
+}
+
+func (UnimplementedRuuviHandler) GetMeasurements(context.Context, *connect.Request[v1.RuuviGetMeasurementsRequest]) (*connect.Response[v1.RuuviGetMeasurementsResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("ruuvi.v1.Ruuvi.GetMeasurements is not implemented"))
+}
+
+func (UnimplementedRuuviHandler) ReportStatus(context.Context, *connect.Request[v1.RuuviCollectorStatus]) (*connect.Response[v1.RuuviReportStatusResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("ruuvi.v1.Ruuvi.ReportStatus is not implemented"))
+}
+
+func (UnimplementedRuuviHandler) Configure(context.Context, *connect.BidiStream[v1.RuuviConfigureRequest, v1.RuuviCollectorConfig]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("ruuvi.v1.Ruuvi.Configure is not implemented"))
+}
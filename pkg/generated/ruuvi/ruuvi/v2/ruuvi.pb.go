@@ -0,0 +1,587 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: ruuvi/v2/ruuvi.proto
+
+package ruuviv2
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Measurement carries a single reading. Units are part of the field names
+// and readings the tag didn't provide are left unset. Server decodes unset
+// readings from a RAWv2 raw_payload, and refuses measurements still missing
+// temperature, humidity, pressure or battery voltage.
+type Measurement struct {
+	state                   protoimpl.MessageState `protogen:"open.v1"`
+	Device                  string                 `protobuf:"bytes,1,opt,name=device,proto3" json:"device,omitempty"`
+	MacAddress              string                 `protobuf:"bytes,2,opt,name=mac_address,json=macAddress,proto3" json:"mac_address,omitempty"`
+	Timestamp               *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	TemperatureCelsius      *float64               `protobuf:"fixed64,4,opt,name=temperature_celsius,json=temperatureCelsius,proto3,oneof" json:"temperature_celsius,omitempty"`
+	RelativeHumidityPercent *float64               `protobuf:"fixed64,5,opt,name=relative_humidity_percent,json=relativeHumidityPercent,proto3,oneof" json:"relative_humidity_percent,omitempty"`
+	PressurePascals         *float64               `protobuf:"fixed64,6,opt,name=pressure_pascals,json=pressurePascals,proto3,oneof" json:"pressure_pascals,omitempty"`
+	BatteryVolts            *float64               `protobuf:"fixed64,7,opt,name=battery_volts,json=batteryVolts,proto3,oneof" json:"battery_volts,omitempty"`
+	RssiDbm                 *int32                 `protobuf:"zigzag32,8,opt,name=rssi_dbm,json=rssiDbm,proto3,oneof" json:"rssi_dbm,omitempty"`
+	TxPowerDbm              *int32                 `protobuf:"zigzag32,9,opt,name=tx_power_dbm,json=txPowerDbm,proto3,oneof" json:"tx_power_dbm,omitempty"`
+	AccelerationXG          *float64               `protobuf:"fixed64,10,opt,name=acceleration_x_g,json=accelerationXG,proto3,oneof" json:"acceleration_x_g,omitempty"`
+	AccelerationYG          *float64               `protobuf:"fixed64,11,opt,name=acceleration_y_g,json=accelerationYG,proto3,oneof" json:"acceleration_y_g,omitempty"`
+	AccelerationZG          *float64               `protobuf:"fixed64,12,opt,name=acceleration_z_g,json=accelerationZG,proto3,oneof" json:"acceleration_z_g,omitempty"`
+	MovementCounter         *uint32                `protobuf:"varint,13,opt,name=movement_counter,json=movementCounter,proto3,oneof" json:"movement_counter,omitempty"`
+	MeasurementSequence     *uint32                `protobuf:"varint,14,opt,name=measurement_sequence,json=measurementSequence,proto3,oneof" json:"measurement_sequence,omitempty"`
+	// Ruuvi data format of the advertisement, e.g. 5 for RAWv2.
+	DataFormat uint32 `protobuf:"varint,15,opt,name=data_format,json=dataFormat,proto3" json:"data_format,omitempty"`
+	// Manufacturer specific data of the advertisement as received.
+	RawPayload    []byte `protobuf:"bytes,16,opt,name=raw_payload,json=rawPayload,proto3" json:"raw_payload,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Measurement) Reset() {
+	*x = Measurement{}
+	mi := &file_ruuvi_v2_ruuvi_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Measurement) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Measurement) ProtoMessage() {}
+
+func (x *Measurement) ProtoReflect() protoreflect.Message {
+	mi := &file_ruuvi_v2_ruuvi_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Measurement.ProtoReflect.Descriptor instead.
+func (*Measurement) Descriptor() ([]byte, []int) {
+	return file_ruuvi_v2_ruuvi_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Measurement) GetDevice() string {
+	if x != nil {
+		return x.Device
+	}
+	return ""
+}
+
+func (x *Measurement) GetMacAddress() string {
+	if x != nil {
+		return x.MacAddress
+	}
+	return ""
+}
+
+func (x *Measurement) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *Measurement) GetTemperatureCelsius() float64 {
+	if x != nil && x.TemperatureCelsius != nil {
+		return *x.TemperatureCelsius
+	}
+	return 0
+}
+
+func (x *Measurement) GetRelativeHumidityPercent() float64 {
+	if x != nil && x.RelativeHumidityPercent != nil {
+		return *x.RelativeHumidityPercent
+	}
+	return 0
+}
+
+func (x *Measurement) GetPressurePascals() float64 {
+	if x != nil && x.PressurePascals != nil {
+		return *x.PressurePascals
+	}
+	return 0
+}
+
+func (x *Measurement) GetBatteryVolts() float64 {
+	if x != nil && x.BatteryVolts != nil {
+		return *x.BatteryVolts
+	}
+	return 0
+}
+
+func (x *Measurement) GetRssiDbm() int32 {
+	if x != nil && x.RssiDbm != nil {
+		return *x.RssiDbm
+	}
+	return 0
+}
+
+func (x *Measurement) GetTxPowerDbm() int32 {
+	if x != nil && x.TxPowerDbm != nil {
+		return *x.TxPowerDbm
+	}
+	return 0
+}
+
+func (x *Measurement) GetAccelerationXG() float64 {
+	if x != nil && x.AccelerationXG != nil {
+		return *x.AccelerationXG
+	}
+	return 0
+}
+
+func (x *Measurement) GetAccelerationYG() float64 {
+	if x != nil && x.AccelerationYG != nil {
+		return *x.AccelerationYG
+	}
+	return 0
+}
+
+func (x *Measurement) GetAccelerationZG() float64 {
+	if x != nil && x.AccelerationZG != nil {
+		return *x.AccelerationZG
+	}
+	return 0
+}
+
+func (x *Measurement) GetMovementCounter() uint32 {
+	if x != nil && x.MovementCounter != nil {
+		return *x.MovementCounter
+	}
+	return 0
+}
+
+func (x *Measurement) GetMeasurementSequence() uint32 {
+	if x != nil && x.MeasurementSequence != nil {
+		return *x.MeasurementSequence
+	}
+	return 0
+}
+
+func (x *Measurement) GetDataFormat() uint32 {
+	if x != nil {
+		return x.DataFormat
+	}
+	return 0
+}
+
+func (x *Measurement) GetRawPayload() []byte {
+	if x != nil {
+		return x.RawPayload
+	}
+	return nil
+}
+
+type CollectorMetadata struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Name  string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// Random identifier chosen by the collector on startup.
+	Session  string `protobuf:"bytes,2,opt,name=session,proto3" json:"session,omitempty"`
+	Version  string `protobuf:"bytes,3,opt,name=version,proto3" json:"version,omitempty"`
+	Hostname string `protobuf:"bytes,4,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	// Bluetooth adapter used for scanning, e.g. hci0.
+	Adapter       string `protobuf:"bytes,5,opt,name=adapter,proto3" json:"adapter,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CollectorMetadata) Reset() {
+	*x = CollectorMetadata{}
+	mi := &file_ruuvi_v2_ruuvi_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CollectorMetadata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CollectorMetadata) ProtoMessage() {}
+
+func (x *CollectorMetadata) ProtoReflect() protoreflect.Message {
+	mi := &file_ruuvi_v2_ruuvi_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CollectorMetadata.ProtoReflect.Descriptor instead.
+func (*CollectorMetadata) Descriptor() ([]byte, []int) {
+	return file_ruuvi_v2_ruuvi_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CollectorMetadata) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CollectorMetadata) GetSession() string {
+	if x != nil {
+		return x.Session
+	}
+	return ""
+}
+
+func (x *CollectorMetadata) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *CollectorMetadata) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+func (x *CollectorMetadata) GetAdapter() string {
+	if x != nil {
+		return x.Adapter
+	}
+	return ""
+}
+
+type SendBatchesRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Collector *CollectorMetadata     `protobuf:"bytes,1,opt,name=collector,proto3" json:"collector,omitempty"`
+	// Sequence number of the batch, increasing within the session.
+	BatchId       uint64         `protobuf:"varint,2,opt,name=batch_id,json=batchId,proto3" json:"batch_id,omitempty"`
+	Measurements  []*Measurement `protobuf:"bytes,3,rep,name=measurements,proto3" json:"measurements,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SendBatchesRequest) Reset() {
+	*x = SendBatchesRequest{}
+	mi := &file_ruuvi_v2_ruuvi_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SendBatchesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendBatchesRequest) ProtoMessage() {}
+
+func (x *SendBatchesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ruuvi_v2_ruuvi_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendBatchesRequest.ProtoReflect.Descriptor instead.
+func (*SendBatchesRequest) Descriptor() ([]byte, []int) {
+	return file_ruuvi_v2_ruuvi_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SendBatchesRequest) GetCollector() *CollectorMetadata {
+	if x != nil {
+		return x.Collector
+	}
+	return nil
+}
+
+func (x *SendBatchesRequest) GetBatchId() uint64 {
+	if x != nil {
+		return x.BatchId
+	}
+	return 0
+}
+
+func (x *SendBatchesRequest) GetMeasurements() []*Measurement {
+	if x != nil {
+		return x.Measurements
+	}
+	return nil
+}
+
+type Rejection struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Position of the rejected measurement in the batch.
+	Index         uint32 `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Reason        string `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Rejection) Reset() {
+	*x = Rejection{}
+	mi := &file_ruuvi_v2_ruuvi_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Rejection) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Rejection) ProtoMessage() {}
+
+func (x *Rejection) ProtoReflect() protoreflect.Message {
+	mi := &file_ruuvi_v2_ruuvi_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Rejection.ProtoReflect.Descriptor instead.
+func (*Rejection) Descriptor() ([]byte, []int) {
+	return file_ruuvi_v2_ruuvi_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Rejection) GetIndex() uint32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *Rejection) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type SendBatchesResponse struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	BatchId    uint64                 `protobuf:"varint,1,opt,name=batch_id,json=batchId,proto3" json:"batch_id,omitempty"`
+	Accepted   uint32                 `protobuf:"varint,2,opt,name=accepted,proto3" json:"accepted,omitempty"`
+	Rejected   uint32                 `protobuf:"varint,3,opt,name=rejected,proto3" json:"rejected,omitempty"`
+	Rejections []*Rejection           `protobuf:"bytes,4,rep,name=rejections,proto3" json:"rejections,omitempty"`
+	// Batch had already been received and wasn't stored again.
+	Duplicate     bool `protobuf:"varint,5,opt,name=duplicate,proto3" json:"duplicate,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SendBatchesResponse) Reset() {
+	*x = SendBatchesResponse{}
+	mi := &file_ruuvi_v2_ruuvi_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SendBatchesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendBatchesResponse) ProtoMessage() {}
+
+func (x *SendBatchesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ruuvi_v2_ruuvi_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendBatchesResponse.ProtoReflect.Descriptor instead.
+func (*SendBatchesResponse) Descriptor() ([]byte, []int) {
+	return file_ruuvi_v2_ruuvi_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *SendBatchesResponse) GetBatchId() uint64 {
+	if x != nil {
+		return x.BatchId
+	}
+	return 0
+}
+
+func (x *SendBatchesResponse) GetAccepted() uint32 {
+	if x != nil {
+		return x.Accepted
+	}
+	return 0
+}
+
+func (x *SendBatchesResponse) GetRejected() uint32 {
+	if x != nil {
+		return x.Rejected
+	}
+	return 0
+}
+
+func (x *SendBatchesResponse) GetRejections() []*Rejection {
+	if x != nil {
+		return x.Rejections
+	}
+	return nil
+}
+
+func (x *SendBatchesResponse) GetDuplicate() bool {
+	if x != nil {
+		return x.Duplicate
+	}
+	return false
+}
+
+var File_ruuvi_v2_ruuvi_proto protoreflect.FileDescriptor
+
+const file_ruuvi_v2_ruuvi_proto_rawDesc = "" +
+	"\n" +
+	"\x14ruuvi/v2/ruuvi.proto\x12\bruuvi.v2\x1a\x1fgoogle/protobuf/timestamp.proto\"\xb7\a\n" +
+	"\vMeasurement\x12\x16\n" +
+	"\x06device\x18\x01 \x01(\tR\x06device\x12\x1f\n" +
+	"\vmac_address\x18\x02 \x01(\tR\n" +
+	"macAddress\x128\n" +
+	"\ttimestamp\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x124\n" +
+	"\x13temperature_celsius\x18\x04 \x01(\x01H\x00R\x12temperatureCelsius\x88\x01\x01\x12?\n" +
+	"\x19relative_humidity_percent\x18\x05 \x01(\x01H\x01R\x17relativeHumidityPercent\x88\x01\x01\x12.\n" +
+	"\x10pressure_pascals\x18\x06 \x01(\x01H\x02R\x0fpressurePascals\x88\x01\x01\x12(\n" +
+	"\rbattery_volts\x18\a \x01(\x01H\x03R\fbatteryVolts\x88\x01\x01\x12\x1e\n" +
+	"\brssi_dbm\x18\b \x01(\x11H\x04R\arssiDbm\x88\x01\x01\x12%\n" +
+	"\ftx_power_dbm\x18\t \x01(\x11H\x05R\n" +
+	"txPowerDbm\x88\x01\x01\x12-\n" +
+	"\x10acceleration_x_g\x18\n" +
+	" \x01(\x01H\x06R\x0eaccelerationXG\x88\x01\x01\x12-\n" +
+	"\x10acceleration_y_g\x18\v \x01(\x01H\aR\x0eaccelerationYG\x88\x01\x01\x12-\n" +
+	"\x10acceleration_z_g\x18\f \x01(\x01H\bR\x0eaccelerationZG\x88\x01\x01\x12.\n" +
+	"\x10movement_counter\x18\r \x01(\rH\tR\x0fmovementCounter\x88\x01\x01\x126\n" +
+	"\x14measurement_sequence\x18\x0e \x01(\rH\n" +
+	"R\x13measurementSequence\x88\x01\x01\x12\x1f\n" +
+	"\vdata_format\x18\x0f \x01(\rR\n" +
+	"dataFormat\x12\x1f\n" +
+	"\vraw_payload\x18\x10 \x01(\fR\n" +
+	"rawPayloadB\x16\n" +
+	"\x14_temperature_celsiusB\x1c\n" +
+	"\x1a_relative_humidity_percentB\x13\n" +
+	"\x11_pressure_pascalsB\x10\n" +
+	"\x0e_battery_voltsB\v\n" +
+	"\t_rssi_dbmB\x0f\n" +
+	"\r_tx_power_dbmB\x13\n" +
+	"\x11_acceleration_x_gB\x13\n" +
+	"\x11_acceleration_y_gB\x13\n" +
+	"\x11_acceleration_z_gB\x13\n" +
+	"\x11_movement_counterB\x17\n" +
+	"\x15_measurement_sequence\"\x91\x01\n" +
+	"\x11CollectorMetadata\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x18\n" +
+	"\asession\x18\x02 \x01(\tR\asession\x12\x18\n" +
+	"\aversion\x18\x03 \x01(\tR\aversion\x12\x1a\n" +
+	"\bhostname\x18\x04 \x01(\tR\bhostname\x12\x18\n" +
+	"\aadapter\x18\x05 \x01(\tR\aadapter\"\xa5\x01\n" +
+	"\x12SendBatchesRequest\x129\n" +
+	"\tcollector\x18\x01 \x01(\v2\x1b.ruuvi.v2.CollectorMetadataR\tcollector\x12\x19\n" +
+	"\bbatch_id\x18\x02 \x01(\x04R\abatchId\x129\n" +
+	"\fmeasurements\x18\x03 \x03(\v2\x15.ruuvi.v2.MeasurementR\fmeasurements\"9\n" +
+	"\tRejection\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\rR\x05index\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\"\xbb\x01\n" +
+	"\x13SendBatchesResponse\x12\x19\n" +
+	"\bbatch_id\x18\x01 \x01(\x04R\abatchId\x12\x1a\n" +
+	"\baccepted\x18\x02 \x01(\rR\baccepted\x12\x1a\n" +
+	"\brejected\x18\x03 \x01(\rR\brejected\x123\n" +
+	"\n" +
+	"rejections\x18\x04 \x03(\v2\x13.ruuvi.v2.RejectionR\n" +
+	"rejections\x12\x1c\n" +
+	"\tduplicate\x18\x05 \x01(\bR\tduplicate2^\n" +
+	"\fRuuviService\x12N\n" +
+	"\vSendBatches\x12\x1c.ruuvi.v2.SendBatchesRequest\x1a\x1d.ruuvi.v2.SendBatchesResponse(\x010\x01B\x93\x01\n" +
+	"\fcom.ruuvi.v2B\n" +
+	"RuuviProtoP\x01Z6weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v2;ruuviv2\xa2\x02\x03RXX\xaa\x02\bRuuvi.V2\xca\x02\bRuuvi\\V2\xe2\x02\x14Ruuvi\\V2\\GPBMetadata\xea\x02\tRuuvi::V2b\x06proto3"
+
+var (
+	file_ruuvi_v2_ruuvi_proto_rawDescOnce sync.Once
+	file_ruuvi_v2_ruuvi_proto_rawDescData []byte
+)
+
+func file_ruuvi_v2_ruuvi_proto_rawDescGZIP() []byte {
+	file_ruuvi_v2_ruuvi_proto_rawDescOnce.Do(func() {
+		file_ruuvi_v2_ruuvi_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_ruuvi_v2_ruuvi_proto_rawDesc), len(file_ruuvi_v2_ruuvi_proto_rawDesc)))
+	})
+	return file_ruuvi_v2_ruuvi_proto_rawDescData
+}
+
+var file_ruuvi_v2_ruuvi_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_ruuvi_v2_ruuvi_proto_goTypes = []any{
+	(*Measurement)(nil),           // 0: ruuvi.v2.Measurement
+	(*CollectorMetadata)(nil),     // 1: ruuvi.v2.CollectorMetadata
+	(*SendBatchesRequest)(nil),    // 2: ruuvi.v2.SendBatchesRequest
+	(*Rejection)(nil),             // 3: ruuvi.v2.Rejection
+	(*SendBatchesResponse)(nil),   // 4: ruuvi.v2.SendBatchesResponse
+	(*timestamppb.Timestamp)(nil), // 5: google.protobuf.Timestamp
+}
+var file_ruuvi_v2_ruuvi_proto_depIdxs = []int32{
+	5, // 0: ruuvi.v2.Measurement.timestamp:type_name -> google.protobuf.Timestamp
+	1, // 1: ruuvi.v2.SendBatchesRequest.collector:type_name -> ruuvi.v2.CollectorMetadata
+	0, // 2: ruuvi.v2.SendBatchesRequest.measurements:type_name -> ruuvi.v2.Measurement
+	3, // 3: ruuvi.v2.SendBatchesResponse.rejections:type_name -> ruuvi.v2.Rejection
+	2, // 4: ruuvi.v2.RuuviService.SendBatches:input_type -> ruuvi.v2.SendBatchesRequest
+	4, // 5: ruuvi.v2.RuuviService.SendBatches:output_type -> ruuvi.v2.SendBatchesResponse
+	5, // [5:6] is the sub-list for method output_type
+	4, // [4:5] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_ruuvi_v2_ruuvi_proto_init() }
+func file_ruuvi_v2_ruuvi_proto_init() {
+	if File_ruuvi_v2_ruuvi_proto != nil {
+		return
+	}
+	file_ruuvi_v2_ruuvi_proto_msgTypes[0].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_ruuvi_v2_ruuvi_proto_rawDesc), len(file_ruuvi_v2_ruuvi_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_ruuvi_v2_ruuvi_proto_goTypes,
+		DependencyIndexes: file_ruuvi_v2_ruuvi_proto_depIdxs,
+		MessageInfos:      file_ruuvi_v2_ruuvi_proto_msgTypes,
+	}.Build()
+	File_ruuvi_v2_ruuvi_proto = out.File
+	file_ruuvi_v2_ruuvi_proto_goTypes = nil
+	file_ruuvi_v2_ruuvi_proto_depIdxs = nil
+}
@@ -0,0 +1,127 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: ruuvi/v2/ruuvi.proto
+
+package ruuviv2
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	RuuviService_SendBatches_FullMethodName = "/ruuvi.v2.RuuviService/SendBatches"
+)
+
+// RuuviServiceClient is the client API for RuuviService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// RuuviService is served side by side with ruuvi.v1.Ruuvi. Both store
+// measurements into the same place, hence collectors can be migrated one by one.
+// Only the server implements v2 so far, the bundled collector sends v1.
+type RuuviServiceClient interface {
+	// SendBatches acknowledges every received batch. Batches are deduplicated by
+	// collector, session and batch ID, hence resending unacknowledged ones is safe.
+	SendBatches(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SendBatchesRequest, SendBatchesResponse], error)
+}
+
+type ruuviServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRuuviServiceClient(cc grpc.ClientConnInterface) RuuviServiceClient {
+	return &ruuviServiceClient{cc}
+}
+
+func (c *ruuviServiceClient) SendBatches(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SendBatchesRequest, SendBatchesResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &RuuviService_ServiceDesc.Streams[0], RuuviService_SendBatches_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SendBatchesRequest, SendBatchesResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RuuviService_SendBatchesClient = grpc.BidiStreamingClient[SendBatchesRequest, SendBatchesResponse]
+
+// RuuviServiceServer is the server API for RuuviService service.
+// All implementations must embed UnimplementedRuuviServiceServer
+// for forward compatibility.
+//
+// RuuviService is served side by side with ruuvi.v1.Ruuvi. Both store
+// measurements into the same place, hence collectors can be migrated one by one.
+// Only the server implements v2 so far, the bundled collector sends v1.
+type RuuviServiceServer interface {
+	// SendBatches acknowledges every received batch. Batches are deduplicated by
+	// collector, session and batch ID, hence resending unacknowledged ones is safe.
+	SendBatches(grpc.BidiStreamingServer[SendBatchesRequest, SendBatchesResponse]) error
+	mustEmbedUnimplementedRuuviServiceServer()
+}
+
+// UnimplementedRuuviServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedRuuviServiceServer struct{}
+
+func (UnimplementedRuuviServiceServer) SendBatches(grpc.BidiStreamingServer[SendBatchesRequest, SendBatchesResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method SendBatches not implemented")
+}
+func (UnimplementedRuuviServiceServer) mustEmbedUnimplementedRuuviServiceServer() {}
+func (UnimplementedRuuviServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafeRuuviServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RuuviServiceServer will
+// result in compilation errors.
+type UnsafeRuuviServiceServer interface {
+	mustEmbedUnimplementedRuuviServiceServer()
+}
+
+func RegisterRuuviServiceServer(s grpc.ServiceRegistrar, srv RuuviServiceServer) {
+	// If the following call pancis, it indicates UnimplementedRuuviServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&RuuviService_ServiceDesc, srv)
+}
+
+func _RuuviService_SendBatches_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(RuuviServiceServer).SendBatches(&grpc.GenericServerStream[SendBatchesRequest, SendBatchesResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RuuviService_SendBatchesServer = grpc.BidiStreamingServer[SendBatchesRequest, SendBatchesResponse]
+
+// RuuviService_ServiceDesc is the grpc.ServiceDesc for RuuviService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RuuviService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ruuvi.v2.RuuviService",
+	HandlerType: (*RuuviServiceServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SendBatches",
+			Handler:       _RuuviService_SendBatches_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "ruuvi/v2/ruuvi.proto",
+}
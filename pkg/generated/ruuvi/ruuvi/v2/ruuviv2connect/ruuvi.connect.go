@@ -0,0 +1,113 @@
+// Code generated by protoc-gen-connect-go. DO NOT EDIT.
+//
+// Source: ruuvi/v2/ruuvi.proto
+
+package ruuviv2connect
+
+import (
+	connect "connectrpc.com/connect"
+	context "context"
+	errors "errors"
+	http "net/http"
+	strings "strings"
+	v2 "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v2"
+)
+
+// This is a compile-time assertion to ensure that this generated file and the connect package are
+// compatible. If you get a compiler error that this constant is not defined, this code was
+// generated with a version of connect newer than the one compiled into your binary. You can fix the
+// problem by either regenerating this code with an older version of connect or updating the connect
+// version compiled into your binary.
+const _ = connect.IsAtLeastVersion1_13_0
+
+const (
+	// RuuviServiceName is the fully-qualified name of the RuuviService service.
+	RuuviServiceName = "ruuvi.v2.RuuviService"
+)
+
+// These constants are the fully-qualified names of the RPCs defined in this package. They're
+// exposed at runtime as Spec.Procedure and as the final two segments of the HTTP route.
+//
+// Note that these are different from the fully-qualified method names used by
+// google.golang.org/protobuf/reflect/protoreflect. To convert from these constants to
+// reflection-formatted method names, remove the leading slash and convert the remaining slash to a
+// period.
+const (
+	// RuuviServiceSendBatchesProcedure is the fully-qualified name of the RuuviService's SendBatches
+	// RPC.
+	RuuviServiceSendBatchesProcedure = "/ruuvi.v2.RuuviService/SendBatches"
+)
+
+// RuuviServiceClient is a client for the ruuvi.v2.RuuviService service.
+type RuuviServiceClient interface {
+	// SendBatches acknowledges every received batch. Batches are deduplicated by
+	// collector, session and batch ID, hence resending unacknowledged ones is safe.
+	SendBatches(context.Context) *connect.BidiStreamForClient[v2.SendBatchesRequest, v2.SendBatchesResponse]
+}
+
+// NewRuuviServiceClient constructs a client for the ruuvi.v2.RuuviService service. By default, it
+// uses the Connect protocol with the binary Protobuf Codec, asks for gzipped responses, and sends
+// uncompressed requests. To use the gRPC or gRPC-Web protocols, supply the connect.WithGRPC() or
+// connect.WithGRPCWeb() options.
+//
+// The URL supplied here should be the base URL for the Connect or gRPC server (for example,
+// http://api.acme.com or https://acme.com/grpc).
+func NewRuuviServiceClient(httpClient connect.HTTPClient, baseURL string, opts ...connect.ClientOption) RuuviServiceClient {
+	baseURL = strings.TrimRight(baseURL, "/")
+	ruuviServiceMethods := v2.File_ruuvi_v2_ruuvi_proto.Services().ByName("RuuviService").Methods()
+	return &ruuviServiceClient{
+		sendBatches: connect.NewClient[v2.SendBatchesRequest, v2.SendBatchesResponse](
+			httpClient,
+			baseURL+RuuviServiceSendBatchesProcedure,
+			connect.WithSchema(ruuviServiceMethods.ByName("SendBatches")),
+			connect.WithClientOptions(opts...),
+		),
+	}
+}
+
+// ruuviServiceClient implements RuuviServiceClient.
+type ruuviServiceClient struct {
+	sendBatches *connect.Client[v2.SendBatchesRequest, v2.SendBatchesResponse]
+}
+
+// SendBatches calls ruuvi.v2.RuuviService.SendBatches.
+func (c *ruuviServiceClient) SendBatches(ctx context.Context) *connect.BidiStreamForClient[v2.SendBatchesRequest, v2.SendBatchesResponse] {
+	return c.sendBatches.CallBidiStream(ctx)
+}
+
+// RuuviServiceHandler is an implementation of the ruuvi.v2.RuuviService service.
+type RuuviServiceHandler interface {
+	// SendBatches acknowledges every received batch. Batches are deduplicated by
+	// collector, session and batch ID, hence resending unacknowledged ones is safe.
+	SendBatches(context.Context, *connect.BidiStream[v2.SendBatchesRequest, v2.SendBatchesResponse]) error
+}
+
+// NewRuuviServiceHandler builds an HTTP handler from the service implementation. It returns the
+// path on which to mount the handler and the handler itself.
+//
+// By default, handlers support the Connect, gRPC, and gRPC-Web protocols with the binary Protobuf
+// and JSON codecs. They also support gzip compression.
+func NewRuuviServiceHandler(svc RuuviServiceHandler, opts ...connect.HandlerOption) (string, http.Handler) {
+	ruuviServiceMethods := v2.File_ruuvi_v2_ruuvi_proto.Services().ByName("RuuviService").Methods()
+	ruuviServiceSendBatchesHandler := connect.NewBidiStreamHandler(
+		RuuviServiceSendBatchesProcedure,
+		svc.SendBatches,
+		connect.WithSchema(ruuviServiceMethods.ByName("SendBatches")),
+		connect.WithHandlerOptions(opts...),
+	)
+	return "/ruuvi.v2.RuuviService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case RuuviServiceSendBatchesProcedure:
+			ruuviServiceSendBatchesHandler.ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// UnimplementedRuuviServiceHandler returns CodeUnimplemented from all methods.
+type UnimplementedRuuviServiceHandler struct{}
+
+func (UnimplementedRuuviServiceHandler) SendBatches(context.Context, *connect.BidiStream[v2.SendBatchesRequest, v2.SendBatchesResponse]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("ruuvi.v2.RuuviService.SendBatches is not implemented"))
+}
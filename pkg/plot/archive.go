@@ -0,0 +1,352 @@
+package plot
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"weezel/ruuvigraph/pkg/cache"
+	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+	"weezel/ruuvigraph/pkg/units"
+)
+
+var (
+	errNotArchive = errors.New("not a measurement archive")
+	gzipMagic     = []byte{0x1f, 0x8b}
+)
+
+// summarizer is implemented by backends summarizing pruned measurements in rollups,
+// which are archived next to the measurements
+type summarizer interface {
+	Summaries() []cache.Summaries
+	Restore(summaries []cache.Summaries, reqs ...*ruuvipb.RuuviStreamDataRequest) (int, error)
+}
+
+// summariesFilename returns the name of the rollups archived next to the archive file
+func summariesFilename(fname string) string {
+	return fname + ".rollups"
+}
+
+func (p *PlottingServer) archive() error {
+	logger.Info("Writing archive file")
+
+	dataCopy, err := p.measureData.Range(nil, time.Time{}, time.Time{})
+	if err != nil {
+		return fmt.Errorf("read measurements: %w", err)
+	}
+
+	// Rollups are read after the measurements and written first, so that whatever was
+	// pruned meanwhile or before a crash in between is either summarized or archived
+	if s, ok := p.measureData.(summarizer); ok {
+		j, marshalErr := json.Marshal(s.Summaries())
+		if marshalErr != nil {
+			return fmt.Errorf("marshal rollups: %w", marshalErr)
+		}
+		if err = p.writeArchiveFile(summariesFilename(*p.storeFilename), 0, j); err != nil {
+			return fmt.Errorf("write rollups: %w", err)
+		}
+	}
+
+	j, err := json.MarshalIndent(dataCopy, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal measurements: %w", err)
+	}
+	if err = p.writeArchiveFile(*p.storeFilename, p.archiveGenerations, j); err != nil {
+		return fmt.Errorf("write json: %w", err)
+	}
+
+	logger.Info(
+		"Wrote archive file",
+		slog.String("fpath", *p.storeFilename),
+		slog.Bool("gzip", p.archiveGzip),
+	)
+
+	return nil
+}
+
+// writeArchiveFile writes the file atomically, compressed when gzip is chosen
+func (p *PlottingServer) writeArchiveFile(fname string, generations int, data []byte) error {
+	return writeAtomic(fname, generations, func(w io.Writer) error {
+		if !p.archiveGzip {
+			_, writeErr := w.Write(data)
+			return writeErr //nolint:wrapcheck // Wrapped by writeAtomic
+		}
+		zw := gzip.NewWriter(w)
+		if _, writeErr := zw.Write(data); writeErr != nil {
+			return fmt.Errorf("compress: %w", writeErr)
+		}
+		return zw.Close() //nolint:wrapcheck // Wrapped by writeAtomic
+	})
+}
+
+// generation returns the name of the i:th previous generation of the file
+func generation(fname string, i int) string {
+	return fmt.Sprintf("%s.%d", fname, i)
+}
+
+// writeAtomic writes the file through a temporary one synced to disk before renaming
+// it over the file, so that an interrupted write leaves the previous file intact.
+// Previous files are kept as fname.1 to fname.<generations>, the newest first.
+func writeAtomic(fname string, generations int, write func(w io.Writer) error) error {
+	tmpFilename := fname + ".tmp"
+	f, err := os.OpenFile(filepath.Clean(tmpFilename), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("create temporary file: %w", err)
+	}
+	if err = write(f); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpFilename)
+		return fmt.Errorf("write temporary file: %w", err)
+	}
+
+	if err = rotate(fname, generations); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpFilename, fname); err != nil {
+		return fmt.Errorf("rename temporary file: %w", err)
+	}
+	return syncDir(filepath.Dir(fname))
+}
+
+// rotate shifts the previous generations of the file by one, dropping the oldest,
+// and makes the file the newest one
+func rotate(fname string, generations int) error {
+	if generations <= 0 {
+		return nil
+	}
+	for i := generations - 1; i > 0; i-- {
+		err := os.Rename(generation(fname, i), generation(fname, i+1))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("rotate generation %d: %w", i, err)
+		}
+	}
+
+	newest := generation(fname, 1)
+	if err := os.Remove(newest); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove generation 1: %w", err)
+	}
+	// Linked, so that the file exists until it's replaced
+	err := os.Link(fname, newest)
+	if err == nil || errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	// File system without hard links, restoring falls back to the generation meanwhile
+	if err = os.Rename(fname, newest); err != nil {
+		return fmt.Errorf("rotate archive: %w", err)
+	}
+	return nil
+}
+
+// syncDir syncs the directory, so that a rename in it survives a power cut
+func syncDir(dir string) error {
+	d, err := os.Open(filepath.Clean(dir))
+	if err != nil {
+		return fmt.Errorf("open directory: %w", err)
+	}
+	defer d.Close()
+	if err = d.Sync(); err != nil {
+		return fmt.Errorf("sync directory: %w", err)
+	}
+	return nil
+}
+
+// readFile calls read with the content of the file, decompressed if it's compressed with gzip
+func readFile(fpath string, read func(r io.Reader) error) error {
+	f, err := os.Open(filepath.Clean(fpath))
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	var r io.Reader = br
+	if magic, _ := br.Peek(len(gzipMagic)); bytes.Equal(magic, gzipMagic) {
+		zr, zErr := gzip.NewReader(br)
+		if zErr != nil {
+			return fmt.Errorf("decompress: %w", zErr)
+		}
+		defer zr.Close()
+		r = zr
+	}
+	return read(r)
+}
+
+// readArchiveFile reads the archive file, which may be compressed with gzip
+func readArchiveFile(fpath string) ([]*ruuvipb.RuuviStreamDataRequest, int, error) {
+	var (
+		records []*ruuvipb.RuuviStreamDataRequest
+		corrupt int
+	)
+	err := readFile(fpath, func(r io.Reader) error {
+		var readErr error
+		records, corrupt, readErr = readArchive(r)
+		return readErr
+	})
+	return records, corrupt, err
+}
+
+// readSummaries reads the rollups archived next to the archive file. Missing file
+// has no rollups.
+func readSummaries(fpath string) ([]cache.Summaries, error) {
+	summaries := []cache.Summaries{}
+	err := readFile(fpath, func(r io.Reader) error {
+		if decodeErr := json.NewDecoder(r).Decode(&summaries); decodeErr != nil {
+			return fmt.Errorf("decode: %w", decodeErr)
+		}
+		return nil
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return summaries, err
+}
+
+// readArchives reads the newest intact generation of the archive. When all of them
+// are damaged, returns the one having the most records. Empty path tells there's no
+// archive at all.
+func (p *PlottingServer) readArchives() ([]*ruuvipb.RuuviStreamDataRequest, int, string) {
+	var (
+		best        []*ruuvipb.RuuviStreamDataRequest
+		bestCorrupt int
+		bestPath    string
+	)
+	for i := range p.archiveGenerations + 1 {
+		fpath := *p.storeFilename
+		if i > 0 {
+			fpath = generation(fpath, i)
+		}
+		records, corrupt, err := readArchiveFile(fpath)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err == nil {
+			return records, corrupt, fpath
+		}
+		logger.Error(
+			"Archive file is damaged",
+			slog.String("fpath", fpath),
+			slog.Int("intact_records", len(records)),
+			slog.Any("error", err),
+		)
+		if bestPath == "" || len(records) > len(best) {
+			best, bestCorrupt, bestPath = records, corrupt, fpath
+		}
+	}
+	return best, bestCorrupt, bestPath
+}
+
+// readArchive decodes the archive record by record, hence a corrupt record loses only
+// itself. Reading stops at a syntax error, e.g. truncated file, keeping the records
+// read so far. Returns the records and the count of corrupt ones.
+func readArchive(r io.Reader) ([]*ruuvipb.RuuviStreamDataRequest, int, error) {
+	dec := json.NewDecoder(r)
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, 0, fmt.Errorf("read archive: %w", err)
+	}
+	if tok != json.Delim('[') {
+		return nil, 0, errNotArchive
+	}
+
+	records := []*ruuvipb.RuuviStreamDataRequest{}
+	corrupt := 0
+	for index := 0; dec.More(); index++ {
+		var raw json.RawMessage
+		if err = dec.Decode(&raw); err != nil {
+			return records, corrupt, fmt.Errorf("read record %d: %w", index, err)
+		}
+
+		m := &ruuvipb.RuuviStreamDataRequest{}
+		err = json.Unmarshal(raw, m)
+		if err == nil {
+			// Archives written before canonical units have pressure in decapascals
+			units.NormalizeLegacy(m)
+			err = validateMeasurement(m)
+		}
+		if err == nil {
+			err = units.Validate(m)
+		}
+		if err != nil {
+			corrupt++
+			logger.Warn(
+				"Skipped corrupt archive record",
+				slog.Int("index", index),
+				slog.Any("error", err),
+			)
+			continue
+		}
+		records = append(records, m)
+	}
+	if _, err = dec.Token(); err != nil {
+		return records, corrupt, fmt.Errorf("read archive end: %w", err)
+	}
+
+	return records, corrupt, nil
+}
+
+// restoreArchive loads the measurements archived on the previous run, or the newest
+// intact generation before it. Problems are only reported, starting without history
+// is better than not starting.
+func (p *PlottingServer) restoreArchive() {
+	records, corrupt, fpath := p.readArchives()
+	if fpath == "" {
+		logger.Info(
+			"No archive file to restore",
+			slog.String("fpath", *p.storeFilename),
+		)
+		return
+	}
+
+	// Records are validated already, anything refused has expired
+	var restored int
+	if s, ok := p.measureData.(summarizer); ok {
+		restored, _ = s.Restore(p.restoreSummaries(), records...)
+	} else {
+		before := p.measureData.Stats().Measurements
+		_ = p.measureData.Append(records...)
+		restored = p.measureData.Stats().Measurements - before
+	}
+	logger.Info(
+		"Restored archive file",
+		slog.String("fpath", fpath),
+		slog.Int("restored", restored),
+		slog.Int("expired", len(records)-restored),
+		slog.Int("corrupt", corrupt),
+	)
+
+	if restored > 0 {
+		select {
+		case p.doPlot <- time.Since(p.lastGenerated):
+		default: // Plot already scheduled
+		}
+	}
+}
+
+// restoreSummaries reads the archived rollups. Damaged ones are only reported, raw
+// measurements are restored nevertheless.
+func (p *PlottingServer) restoreSummaries() []cache.Summaries {
+	fpath := summariesFilename(*p.storeFilename)
+	summaries, err := readSummaries(fpath)
+	if err != nil {
+		logger.Error(
+			"Failed to restore rollups",
+			slog.String("fpath", fpath),
+			slog.Any("error", err),
+		)
+		return nil
+	}
+	return summaries
+}
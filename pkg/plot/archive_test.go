@@ -0,0 +1,232 @@
+package plot
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"weezel/ruuvigraph/pkg/cache"
+	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+	"weezel/ruuvigraph/pkg/storage"
+)
+
+func TestPlottingServer_restoreArchive(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "archive.json")
+
+	// Keeps longer than the restarted one
+	server := NewPlottingServer(
+		WithArchiveFilename(fname),
+		WithStorage(cache.New(cache.WithMaxMeasureAge(30*24*time.Hour), cache.WithLateness(0))),
+	)
+	t.Cleanup(server.Stop)
+	for _, m := range []*ruuvipb.RuuviStreamDataRequest{
+		testMeasurement("Kitchen", time.Now().Add(-time.Hour)),
+		testMeasurement("Kitchen", time.Now().Add(-8*24*time.Hour)), // Expires before restart
+		testMeasurement("Balcony", time.Now()),
+	} {
+		if err := server.measureData.Append(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := server.archive(); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := NewPlottingServer(WithArchiveFilename(fname))
+	t.Cleanup(restarted.Stop)
+	all, err := restarted.measureData.Range(nil, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("restored %d measurements, want 2", len(all))
+	}
+	latest := storage.LatestOf(all)
+	i := slices.IndexFunc(latest, func(m *ruuvipb.RuuviStreamDataRequest) bool {
+		return m.GetDevice() == "Balcony"
+	})
+	if i < 0 || latest[i].GetPressure() != 101320 {
+		t.Errorf("restored measurements = %v", all)
+	}
+
+	// Expired one is summarized, and the rollup survives the next restart
+	rolledUp := func(server *PlottingServer) int {
+		t.Helper()
+		measurements, ok := server.measureData.(*cache.Measurements)
+		if !ok {
+			t.Fatalf("storage is %T, want the in-memory one", server.measureData)
+		}
+		rollups, rollupErr := measurements.Rollups(nil, time.Time{}, time.Now().Add(-7*24*time.Hour), cache.Day)
+		if rollupErr != nil {
+			t.Fatal(rollupErr)
+		}
+		count := 0
+		for _, r := range rollups {
+			count += r.Count()
+		}
+		return count
+	}
+	if got := rolledUp(restarted); got != 1 {
+		t.Errorf("rollups summarize %d measurements after restoring, want the expired one", got)
+	}
+	if err = restarted.archive(); err != nil {
+		t.Fatal(err)
+	}
+	again := NewPlottingServer(WithArchiveFilename(fname))
+	t.Cleanup(again.Stop)
+	if got := rolledUp(again); got != 1 {
+		t.Errorf("rollups summarize %d measurements after restarting again, want 1", got)
+	}
+}
+
+func TestReadArchive(t *testing.T) {
+	tests := []struct {
+		name        string
+		archive     string
+		wantRecords int
+		wantCorrupt int
+		wantErr     bool
+	}{
+		{
+			"valid",
+			`[{"device": "Kitchen", "pressure": 101320, "timestamp": {"seconds": 1700000000}}]`,
+			1, 0, false,
+		},
+		{
+			"legacy decapascals",
+			`[{"device": "Kitchen", "pressure": 10132, "timestamp": {"seconds": 1700000000}}]`,
+			1, 0, false,
+		},
+		{
+			"corrupt records skipped",
+			`[{"device": "Kitchen", "timestamp": {"seconds": 1700000000}},
+			  {"device": "Kitchen", "timestamp": "yesterday"},
+			  {"device": "Kitchen"},
+			  {"device": "Kitchen", "humidity": 150, "timestamp": {"seconds": 1700000000}},
+			  {"mac_address": "aa:bb:cc:dd:ee:ff", "timestamp": {"seconds": 1700000060}}]`,
+			2, 3, false,
+		},
+		{
+			"truncated",
+			`[{"device": "Kitchen", "timestamp": {"seconds": 1700000000}}, {"device": "Kit`,
+			1, 0, true,
+		},
+		{"not an archive", `{"device": "Kitchen"}`, 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, corrupt, err := readArchive(strings.NewReader(tt.archive))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readArchive() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(records) != tt.wantRecords || corrupt != tt.wantCorrupt {
+				t.Errorf("readArchive() records = %d, corrupt = %d, want %d and %d",
+					len(records), corrupt, tt.wantRecords, tt.wantCorrupt)
+			}
+			for _, r := range records {
+				if p := r.GetPressure(); p != 0 && p != 101320 {
+					t.Errorf("pressure = %v, want pascals", p)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteAtomic_interrupted(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "archive.json")
+	if err := os.WriteFile(fname, []byte("previous"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Fails midway, like a full disk
+	errFull := errors.New("no space left on device")
+	err := writeAtomic(fname, 2, func(w io.Writer) error {
+		if _, err := io.WriteString(w, "[{\"device\": \"Kit"); err != nil {
+			return err
+		}
+		return errFull
+	})
+	if !errors.Is(err, errFull) {
+		t.Fatalf("writeAtomic() error = %v, want %v", err, errFull)
+	}
+
+	if got, _ := os.ReadFile(fname); string(got) != "previous" {
+		t.Errorf("archive = %q after interrupted write, want the previous one", got)
+	}
+	for _, leftover := range []string{fname + ".tmp", generation(fname, 1)} {
+		if _, err = os.Stat(leftover); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s exists after interrupted write", filepath.Base(leftover))
+		}
+	}
+}
+
+func TestWriteAtomic_generations(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "archive.json")
+	for _, content := range []string{"1", "2", "3", "4"} {
+		err := writeAtomic(fname, 2, func(w io.Writer) error {
+			_, err := io.WriteString(w, content)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for name, want := range map[string]string{
+		fname:                "4",
+		generation(fname, 1): "3",
+		generation(fname, 2): "2",
+	} {
+		if got, err := os.ReadFile(name); err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v, want %q", filepath.Base(name), got, err, want)
+		}
+	}
+	if _, err := os.Stat(generation(fname, 3)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("generation 3 kept, want only 2")
+	}
+}
+
+func TestPlottingServer_restoreGeneration(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "archive.json.gz")
+	opts := func() []OptionServer {
+		return []OptionServer{
+			WithArchiveFilename(fname),
+			WithArchiveGenerations(1),
+			WithArchiveGzip(true),
+		}
+	}
+
+	server := NewPlottingServer(opts()...)
+	t.Cleanup(server.Stop)
+	for _, ts := range []time.Time{time.Now().Add(-time.Hour), time.Now()} {
+		if err := server.measureData.Append(testMeasurement("Kitchen", ts)); err != nil {
+			t.Fatal(err)
+		}
+		if err := server.archive(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	raw, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(raw, gzipMagic) {
+		t.Fatalf("archive isn't compressed")
+	}
+
+	// Power cut while the archive was written without a temporary file
+	if err = os.WriteFile(fname, raw[:len(raw)/2], 0o600); err != nil {
+		t.Fatal(err)
+	}
+	restarted := NewPlottingServer(opts()...)
+	t.Cleanup(restarted.Stop)
+	if got := restarted.measureData.Stats().Measurements; got != 1 {
+		t.Errorf("restored %d measurements, want 1 of the previous generation", got)
+	}
+}
@@ -0,0 +1,89 @@
+package plot
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+)
+
+var (
+	errMissingTimestamp = errors.New("missing timestamp")
+	errMissingDevice    = errors.New("missing device name and MAC address")
+	errInvalidValue     = errors.New("value is not a finite number")
+)
+
+// validateMeasurement checks that the measurement can be stored and plotted
+func validateMeasurement(m *ruuvipb.RuuviStreamDataRequest) error {
+	if m.GetTimestamp() == nil {
+		return errMissingTimestamp
+	}
+	if m.GetDevice() == "" && m.GetMacAddress() == "" {
+		return errMissingDevice
+	}
+	for _, v := range []float32{m.GetTemperature(), m.GetHumidity(), m.GetPressure(), m.GetBatterVolts()} {
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return errInvalidValue
+		}
+	}
+	return nil
+}
+
+// batchLog remembers recently received batch IDs per collector session
+// so that resent batches aren't stored twice.
+type batchLog struct {
+	mu       sync.Mutex
+	sessions map[string]*sessionBatches
+	// How many batch IDs are remembered per session
+	window int
+}
+
+type sessionBatches struct {
+	seen    map[uint64]struct{}
+	order   []uint64
+	updated time.Time
+}
+
+// Sessions of restarted collectors are forgotten after being idle this long
+const sessionIdleTimeout = 24 * time.Hour
+
+func newBatchLog(window int) *batchLog {
+	return &batchLog{
+		sessions: map[string]*sessionBatches{},
+		window:   window,
+	}
+}
+
+// Claim marks the batch received and reports whether it was seen for the first time
+func (b *batchLog) Claim(session string, batchID uint64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	sb, found := b.sessions[session]
+	if !found {
+		for key, other := range b.sessions {
+			if now.Sub(other.updated) > sessionIdleTimeout {
+				delete(b.sessions, key)
+			}
+		}
+		sb = &sessionBatches{seen: map[uint64]struct{}{}}
+		b.sessions[session] = sb
+	}
+	sb.updated = now
+
+	if _, seen := sb.seen[batchID]; seen {
+		return false
+	}
+
+	sb.seen[batchID] = struct{}{}
+	sb.order = append(sb.order, batchID)
+	if len(sb.order) > b.window {
+		delete(sb.seen, sb.order[0])
+		sb.order = sb.order[1:]
+	}
+
+	return true
+}
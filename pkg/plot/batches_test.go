@@ -0,0 +1,29 @@
+package plot
+
+import (
+	"testing"
+)
+
+func TestBatchLog_Claim(t *testing.T) {
+	b := newBatchLog(2)
+
+	steps := []struct {
+		session string
+		batchID uint64
+		want    bool
+	}{
+		{session: "pi/a", batchID: 1, want: true},
+		{session: "pi/a", batchID: 1, want: false},
+		{session: "pi/b", batchID: 1, want: true},
+		{session: "pi/a", batchID: 2, want: true},
+		{session: "pi/a", batchID: 3, want: true},
+		{session: "pi/a", batchID: 2, want: false},
+		// Fell out of the window
+		{session: "pi/a", batchID: 1, want: true},
+	}
+	for i, step := range steps {
+		if got := b.Claim(step.session, step.batchID); got != step.want {
+			t.Errorf("step %d: Claim(%q, %d) = %v, want %v", i, step.session, step.batchID, got, step.want)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package plot
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+	ruuviv2pb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v2"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	collectorsHTMLFilename = "collectors.html"
+	// Collectors report their status every minute by default
+	collectorStaleAfter = 3 * time.Minute
+)
+
+// collectorInfo is what is known about a collector
+type collectorInfo struct {
+	Name      string
+	LastHeard time.Time
+	// Measurements refused by the rate limits
+	Throttled uint64
+	// Latest reported status, nil until the collector has reported one
+	Status *ruuvipb.RuuviCollectorStatus
+	// Latest metadata sent by a v2 collector
+	Metadata *ruuviv2pb.CollectorMetadata
+}
+
+// collectorRegistry keeps track of the collectors the server has heard from
+type collectorRegistry struct {
+	mu         sync.Mutex
+	collectors map[string]*collectorInfo
+}
+
+func newCollectorRegistry() *collectorRegistry {
+	return &collectorRegistry{
+		collectors: map[string]*collectorInfo{},
+	}
+}
+
+func (r *collectorRegistry) get(name string) *collectorInfo {
+	info, found := r.collectors[name]
+	if !found {
+		info = &collectorInfo{Name: name}
+		r.collectors[name] = info
+	}
+	return info
+}
+
+// heard records that the collector sent something. Unnamed ones aren't tracked.
+func (r *collectorRegistry) heard(name string, at time.Time) {
+	if name == "" {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.get(name).LastHeard = at
+}
+
+// report records the status the collector reported. Unnamed ones aren't tracked.
+func (r *collectorRegistry) report(name string, status *ruuvipb.RuuviCollectorStatus, at time.Time) {
+	if name == "" {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	info := r.get(name)
+	info.LastHeard = at
+	info.Status = status
+}
+
+// describe records the metadata v2 collectors send with every batch
+func (r *collectorRegistry) describe(name string, metadata *ruuviv2pb.CollectorMetadata) {
+	if name == "" || metadata == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.get(name).Metadata = metadata
+}
+
+func (r *collectorRegistry) throttled(name string, count uint64) {
+	if name == "" {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.get(name).Throttled += count
+}
+
+// list returns copies of the collectors sorted by name
+func (r *collectorRegistry) list() []collectorInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	infos := make([]collectorInfo, 0, len(r.collectors))
+	for _, info := range r.collectors {
+		infos = append(infos, *info)
+	}
+	slices.SortFunc(infos, func(a, b collectorInfo) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return infos
+}
+
+var collectorsTemplate = template.Must(template.New("collectors").Funcs(template.FuncMap{
+	"ago": func(t time.Time) string {
+		return time.Since(t).Round(time.Second).String()
+	},
+	"duration": func(d *durationpb.Duration) string {
+		return d.AsDuration().Round(time.Second).String()
+	},
+	"alive": func(t time.Time) bool {
+		return time.Since(t) < collectorStaleAfter
+	},
+	"adapter": func(state ruuvipb.RuuviAdapterState) string {
+		return strings.ToLower(strings.TrimPrefix(state.String(), "RUUVI_ADAPTER_STATE_"))
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Collectors</title>
+<style>
+body { font-family: sans-serif; }
+td, th { padding: 0.2em 1em; text-align: left; }
+.alive { color: green; }
+.stale { color: red; }
+</style>
+</head>
+<body>
+<h1>Collectors</h1>
+<p>Generated {{ .Generated.Format "2006-01-02 15:04:05" }}</p>
+<table>
+<tr>
+<th>Collector</th><th>Host</th><th>State</th><th>Last heard</th><th>Throttled</th><th>Version</th><th>Uptime</th>
+<th>Adapter</th><th>Advertisements</th><th>Parse errors</th><th>Queued batches</th><th>Reconnects</th>
+</tr>
+{{- range .Collectors }}
+<tr>
+<td>{{ .Name }}</td>
+<td>{{ with .Metadata }}{{ .GetHostname }}{{ with .GetAdapter }} ({{ . }}){{ end }}{{ end }}</td>
+{{- if alive .LastHeard }}
+<td class="alive">alive</td>
+{{- else }}
+<td class="stale">stale</td>
+{{- end }}
+<td title="{{ .LastHeard.Format "2006-01-02 15:04:05" }}">{{ ago .LastHeard }} ago</td>
+<td>{{ .Throttled }}</td>
+{{- with .Status }}
+<td>{{ .GetVersion }}</td>
+<td>{{ duration .GetUptime }}</td>
+<td>{{ adapter .GetAdapterState }}</td>
+<td>{{ .GetAdvertisementsSeen }}</td>
+<td>{{ .GetParseErrors }}</td>
+<td>{{ .GetQueuedBatches }}</td>
+<td>{{ .GetReconnects }}</td>
+{{- else }}
+<td colspan="7">no status reported</td>
+{{- end }}
+</tr>
+{{- end }}
+</table>
+</body>
+</html>
+`))
+
+func (p *PlottingServer) renderCollectors(w io.Writer) error {
+	err := collectorsTemplate.Execute(w, struct {
+		Generated  time.Time
+		Collectors []collectorInfo
+	}{
+		Generated:  time.Now(),
+		Collectors: p.collectors.list(),
+	})
+	if err != nil {
+		return fmt.Errorf("render collectors: %w", err)
+	}
+	return nil
+}
+
+// writeCollectorsPage writes the status page next to the plots
+func (p *PlottingServer) writeCollectorsPage() error {
+	f, err := os.Create(collectorsHTMLFilename)
+	if err != nil {
+		return fmt.Errorf("create file: %w", err)
+	}
+	defer f.Close()
+
+	return p.renderCollectors(f)
+}
+
+func (p *PlottingServer) serveCollectors(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := p.renderCollectors(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (p *PlottingServer) reportStatus(ctx context.Context, status *ruuvipb.RuuviCollectorStatus) {
+	collector := collectorName(ctx, status.GetCollector())
+	p.collectors.report(collector, status, time.Now())
+
+	logger.Info(
+		"Collector reported status",
+		slog.String("collector", collector),
+		slog.String("adapter_state", status.GetAdapterState().String()),
+		slog.Uint64("advertisements_seen", status.GetAdvertisementsSeen()),
+		slog.Uint64("parse_errors", status.GetParseErrors()),
+		slog.Int("queued_batches", int(status.GetQueuedBatches())),
+		slog.Uint64("reconnects", status.GetReconnects()),
+	)
+}
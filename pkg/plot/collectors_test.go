@@ -0,0 +1,101 @@
+package plot
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestPlottingServer_ReportStatus(t *testing.T) {
+	server, conn := startTestServer(t)
+	client := ruuvipb.NewRuuviClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := client.ReportStatus(ctx, &ruuvipb.RuuviCollectorStatus{
+		Collector:          "livingroom-pi",
+		Version:            "abc123",
+		Uptime:             durationpb.New(time.Hour),
+		AdapterState:       ruuvipb.RuuviAdapterState_RUUVI_ADAPTER_STATE_SCANNING,
+		AdvertisementsSeen: 1234,
+		ParseErrors:        5,
+		QueuedBatches:      2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Heard only through measurements, hasn't reported status yet
+	stream, err := client.SendBatches(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = stream.Send(&ruuvipb.RuuviMeasurementBatch{
+		Collector:    "garage-pi",
+		BatchId:      1,
+		Measurements: []*ruuvipb.RuuviStreamDataRequest{testMeasurement("Garage", time.Now())},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+
+	collectors := server.collectors.list()
+	if len(collectors) != 2 || collectors[0].Name != "garage-pi" || collectors[1].Name != "livingroom-pi" {
+		t.Fatalf("collectors = %v, want garage-pi and livingroom-pi", collectors)
+	}
+	if collectors[0].Status != nil {
+		t.Errorf("garage-pi status = %v, want none", collectors[0].Status)
+	}
+	if collectors[1].Status.GetAdvertisementsSeen() != 1234 {
+		t.Errorf("livingroom-pi status = %v", collectors[1].Status)
+	}
+
+	httpServer := httptest.NewServer(server.HTTPHandler())
+	t.Cleanup(httpServer.Close)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, httpServer.URL+"/collectors", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	for _, want := range []string{"livingroom-pi", "scanning", "1234", "1h0m0s", "no status reported"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("collectors page doesn't contain %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestCollectorRegistry_stale(t *testing.T) {
+	r := newCollectorRegistry()
+	r.heard("", time.Now())
+	r.report("", &ruuvipb.RuuviCollectorStatus{}, time.Now())
+	r.heard("old-pi", time.Now().Add(-time.Hour))
+
+	var buf strings.Builder
+	server := &PlottingServer{collectors: r}
+	if err := server.renderCollectors(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `<td class="stale">stale</td>`) {
+		t.Errorf("collectors page doesn't show old-pi stale:\n%s", buf.String())
+	}
+	if len(r.list()) != 1 {
+		t.Errorf("list() = %v, want unnamed collector ignored", r.list())
+	}
+}
@@ -0,0 +1,165 @@
+package plot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// NewCollectorConfig creates a configuration pushed to collectors. Aliases map
+// MAC addresses to device names. Zero durations are left unset.
+func NewCollectorConfig(
+	aliases map[string]string,
+	sendInterval, scanWindow, scanInterval time.Duration,
+) *ruuvipb.RuuviCollectorConfig {
+	cfg := &ruuvipb.RuuviCollectorConfig{}
+	// Sorted, so that the same aliases produce the same version
+	for _, mac := range slices.Sorted(maps.Keys(aliases)) {
+		cfg.Devices = append(cfg.Devices, &ruuvipb.RuuviDevice{
+			Name:       aliases[mac],
+			MacAddress: mac,
+		})
+	}
+	if sendInterval > 0 {
+		cfg.SendInterval = durationpb.New(sendInterval)
+	}
+	if scanWindow > 0 {
+		cfg.ScanWindow = durationpb.New(scanWindow)
+		cfg.ScanInterval = durationpb.New(scanInterval)
+	}
+	return cfg
+}
+
+// configVersion hashes the unversioned configuration, hence restarting the server
+// with unchanged configuration doesn't make collectors reapply it
+func configVersion(cfg *ruuvipb.RuuviCollectorConfig) uint64 {
+	data, _ := proto.MarshalOptions{Deterministic: true}.Marshal(cfg)
+
+	h := fnv.New64a()
+	_, _ = h.Write(data)
+	return h.Sum64()
+}
+
+// collectorConfig holds the current configuration and notifies about changes
+type collectorConfig struct {
+	mu      sync.Mutex
+	cfg     *ruuvipb.RuuviCollectorConfig
+	changed chan struct{}
+}
+
+func newCollectorConfig() *collectorConfig {
+	return &collectorConfig{
+		changed: make(chan struct{}),
+	}
+}
+
+func (c *collectorConfig) set(cfg *ruuvipb.RuuviCollectorConfig) {
+	cfg = proto.Clone(cfg).(*ruuvipb.RuuviCollectorConfig) //nolint:forcetypeassert // Clone of the same type
+	cfg.Version = 0
+	cfg.Version = configVersion(cfg)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cfg = cfg
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// current returns the configuration and a channel closed when it changes
+func (c *collectorConfig) current() (*ruuvipb.RuuviCollectorConfig, <-chan struct{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cfg, c.changed
+}
+
+// SetCollectorConfig replaces the configuration and pushes it to the connected collectors
+func (p *PlottingServer) SetCollectorConfig(cfg *ruuvipb.RuuviCollectorConfig) {
+	p.config.set(cfg)
+	current, _ := p.config.current()
+	logger.Info(
+		"Collector configuration updated",
+		slog.Uint64("version", current.GetVersion()),
+		slog.Int("devices", len(current.GetDevices())),
+	)
+}
+
+// configure sends the configuration whenever the collector hasn't applied the current one.
+// Transports wrap their streams into recv and send functions.
+func (p *PlottingServer) configure(
+	ctx context.Context,
+	recv func() (*ruuvipb.RuuviConfigureRequest, error),
+	send func(*ruuvipb.RuuviCollectorConfig) error,
+) error {
+	cfg, changed := p.config.current()
+	if cfg == nil {
+		return status.Error(codes.FailedPrecondition, "remote configuration isn't enabled")
+	}
+
+	first, err := recv()
+	if err != nil {
+		return fmt.Errorf("stream receive error: %w", err)
+	}
+	collector := collectorName(ctx, first.GetCollector())
+	p.collectors.heard(collector, time.Now())
+
+	recvErrCh := make(chan error, 1)
+	go func() {
+		for {
+			req, recvErr := recv()
+			if recvErr != nil {
+				recvErrCh <- recvErr
+				return
+			}
+			logger.Info(
+				"Collector applied configuration",
+				slog.String("collector", collector),
+				slog.Uint64("version", req.GetAppliedVersion()),
+			)
+		}
+	}()
+
+	sent := first.GetAppliedVersion()
+	for {
+		if cfg.GetVersion() != sent {
+			logger.Info(
+				"Sending configuration to collector",
+				slog.String("collector", collector),
+				slog.Uint64("version", cfg.GetVersion()),
+			)
+			if err = send(cfg); err != nil {
+				return fmt.Errorf("send config: %w", err)
+			}
+			sent = cfg.GetVersion()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-changed:
+			cfg, changed = p.config.current()
+		case err = <-recvErrCh:
+			if errors.Is(err, io.EOF) || errors.Is(err, context.Canceled) {
+				return nil
+			}
+			return fmt.Errorf("stream receive error: %w", err)
+		}
+	}
+}
+
+func (p *PlottingServer) Configure(stream ruuvipb.Ruuvi_ConfigureServer) error {
+	return p.configure(stream.Context(), stream.Recv, stream.Send)
+}
@@ -0,0 +1,82 @@
+package plot
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPlottingServer_Configure(t *testing.T) {
+	initial := NewCollectorConfig(map[string]string{"d8:82:aa:bb:cc:dd": "Kitchen"}, time.Minute, 0, 0)
+	server, conn := startTestServer(t, WithCollectorConfig(initial))
+	client := ruuvipb.NewRuuviClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.Configure(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = stream.Send(&ruuvipb.RuuviConfigureRequest{Collector: "livingroom-pi"}); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.GetVersion() == 0 || len(cfg.GetDevices()) != 1 || cfg.GetSendInterval().AsDuration() != time.Minute {
+		t.Fatalf("Configure() = %v", cfg)
+	}
+	if err = stream.Send(&ruuvipb.RuuviConfigureRequest{AppliedVersion: cfg.GetVersion()}); err != nil {
+		t.Fatal(err)
+	}
+
+	server.SetCollectorConfig(NewCollectorConfig(map[string]string{
+		"d8:82:aa:bb:cc:dd": "Kitchen",
+		"fc:8a:aa:bb:cc:dd": "Balcony",
+	}, time.Minute, 0, 0))
+	updated, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.GetVersion() == cfg.GetVersion() || len(updated.GetDevices()) != 2 {
+		t.Errorf("Configure() after update = %v", updated)
+	}
+}
+
+func TestPlottingServer_Configure_disabled(t *testing.T) {
+	_, conn := startTestServer(t)
+	client := ruuvipb.NewRuuviClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.Configure(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = stream.Send(&ruuvipb.RuuviConfigureRequest{Collector: "livingroom-pi"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = stream.Recv(); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Configure() error = %v, want FailedPrecondition", err)
+	}
+}
+
+func TestConfigVersion(t *testing.T) {
+	aliases := map[string]string{"d8:82:aa:bb:cc:dd": "Kitchen", "fc:8a:aa:bb:cc:dd": "Balcony"}
+	first := configVersion(NewCollectorConfig(aliases, time.Minute, 0, 0))
+	second := configVersion(NewCollectorConfig(aliases, time.Minute, 0, 0))
+	if first != second {
+		t.Errorf("same configuration got versions %d and %d", first, second)
+	}
+	if changed := configVersion(NewCollectorConfig(aliases, 2*time.Minute, 0, 0)); changed == first {
+		t.Error("changed configuration kept the same version")
+	}
+}
@@ -0,0 +1,241 @@
+package plot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"weezel/ruuvigraph/pkg/auth"
+	"weezel/ruuvigraph/pkg/batching"
+	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+	"weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1/ruuviv1connect"
+	"weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v2/ruuviv2connect"
+
+	"connectrpc.com/connect"
+	"google.golang.org/grpc/status"
+)
+
+// connectHandler serves the Ruuvi service over Connect, gRPC-Web and
+// plain gRPC on HTTP, sharing the ingestion path with the native gRPC server.
+type connectHandler struct {
+	ruuviv1connect.UnimplementedRuuviHandler
+
+	p *PlottingServer
+}
+
+// toConnectError converts gRPC status errors, codes are numerically the same
+func toConnectError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if s, ok := status.FromError(err); ok {
+		code := connect.Code(s.Code()) //nolint:gosec // Codes are the same
+		return connect.NewError(code, errors.New(s.Message()))
+	}
+	return err
+}
+
+func (c *connectHandler) StreamData(
+	ctx context.Context,
+	stream *connect.ClientStream[ruuvipb.RuuviStreamDataRequest],
+) (*connect.Response[ruuvipb.RuuviStreamDataResponse], error) {
+	collector, _ := auth.CollectorFromContext(ctx)
+
+	recv := func() (*ruuvipb.RuuviStreamDataRequest, error) {
+		if !stream.Receive() {
+			if err := stream.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+		return stream.Msg(), nil
+	}
+	resp, err := c.p.receiveMeasurements(collector, c.p.admitted(collector, recv))
+	if err != nil {
+		return nil, toConnectError(err)
+	}
+	return connect.NewResponse(resp), nil
+}
+
+func (c *connectHandler) SendBatches(
+	ctx context.Context,
+	stream *connect.BidiStream[ruuvipb.RuuviMeasurementBatch, ruuvipb.RuuviBatchAck],
+) error {
+	for {
+		batch, err := stream.Receive()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("stream receive error: %w", err)
+		}
+
+		collector := collectorName(ctx, batch.GetCollector())
+		if err = c.p.admit(collector, batching.Len(batch)); err != nil {
+			return toConnectError(err)
+		}
+		ack := c.p.ingestBatch(collector, batch)
+		if err = stream.Send(ack); err != nil {
+			return fmt.Errorf("send ack: %w", err)
+		}
+	}
+}
+
+func (c *connectHandler) Subscribe(
+	ctx context.Context,
+	req *connect.Request[ruuvipb.RuuviSubscribeRequest],
+	stream *connect.ServerStream[ruuvipb.RuuviStreamDataRequest],
+) error {
+	return toConnectError(c.p.subscribe(ctx, req.Msg, stream.Send))
+}
+
+func (c *connectHandler) GetServerInfo(
+	_ context.Context,
+	_ *connect.Request[ruuvipb.RuuviServerInfoRequest],
+) (*connect.Response[ruuvipb.RuuviServerInfoResponse], error) {
+	return connect.NewResponse(c.p.serverInfo()), nil
+}
+
+func (c *connectHandler) PushMeasurements(
+	ctx context.Context,
+	req *connect.Request[ruuvipb.RuuviPushMeasurementsRequest],
+) (*connect.Response[ruuvipb.RuuviStreamDataResponse], error) {
+	collector, _ := auth.CollectorFromContext(ctx)
+	resp, err := c.p.pushMeasurements(collector, req.Msg)
+	if err != nil {
+		return nil, toConnectError(err)
+	}
+	return connect.NewResponse(resp), nil
+}
+
+func (c *connectHandler) GetMeasurements(
+	_ context.Context,
+	req *connect.Request[ruuvipb.RuuviGetMeasurementsRequest],
+) (*connect.Response[ruuvipb.RuuviGetMeasurementsResponse], error) {
+	resp, err := c.p.getMeasurements(req.Msg)
+	if err != nil {
+		return nil, toConnectError(err)
+	}
+	return connect.NewResponse(resp), nil
+}
+
+func (c *connectHandler) ReportStatus(
+	ctx context.Context,
+	req *connect.Request[ruuvipb.RuuviCollectorStatus],
+) (*connect.Response[ruuvipb.RuuviReportStatusResponse], error) {
+	c.p.reportStatus(ctx, req.Msg)
+	return connect.NewResponse(&ruuvipb.RuuviReportStatusResponse{}), nil
+}
+
+func (c *connectHandler) Configure(
+	ctx context.Context,
+	stream *connect.BidiStream[ruuvipb.RuuviConfigureRequest, ruuvipb.RuuviCollectorConfig],
+) error {
+	return toConnectError(c.p.configure(ctx, stream.Receive, stream.Send))
+}
+
+// HTTPHandler returns a handler serving the Ruuvi services with Connect, gRPC-Web and gRPC protocols
+func (p *PlottingServer) HTTPHandler() http.Handler {
+	mux := http.NewServeMux()
+	path, handler := ruuviv1connect.NewRuuviHandler(
+		&connectHandler{p: p},
+		connect.WithInterceptors(p.connectInterceptors...),
+	)
+	mux.Handle(path, handler)
+	path, handler = ruuviv2connect.NewRuuviServiceHandler(
+		&connectHandlerV2{p: p},
+		connect.WithInterceptors(p.connectInterceptors...),
+	)
+	mux.Handle(path, handler)
+	mux.HandleFunc("GET /collectors", p.serveCollectors)
+
+	return p.withCORS(mux)
+}
+
+// withCORS lets browser code from the allowed origins call the service
+func (p *PlottingServer) withCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || !slices.Contains(p.corsOrigins, origin) && !slices.Contains(p.corsOrigins, "*") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Add("Vary", "Origin")
+		w.Header().Set("Access-Control-Expose-Headers", strings.Join([]string{
+			"Grpc-Status", "Grpc-Message", "Grpc-Status-Details-Bin",
+		}, ", "))
+		if r.Method != http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join([]string{
+			"Authorization", "Content-Type", "Connect-Protocol-Version", "Connect-Timeout-Ms",
+			"Grpc-Timeout", "X-Grpc-Web", "X-User-Agent",
+		}, ", "))
+		w.Header().Set("Access-Control-Max-Age", "7200")
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// ListenHTTP serves HTTPHandler until the server is stopped. HTTP/2 is served
+// without TLS too, since gRPC clients require it.
+func (p *PlottingServer) ListenHTTP(host, port string) error {
+	addr := net.JoinHostPort(host, port)
+
+	protocols := &http.Protocols{}
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(p.httpTLSConfig == nil)
+
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           p.HTTPHandler(),
+		ReadHeaderTimeout: time.Second * 30,
+		TLSConfig:         p.httpTLSConfig,
+		Protocols:         protocols,
+	}
+
+	p.httpServer.Store(httpServer)
+
+	logger.Info(fmt.Sprintf("HTTP server listening on %s", addr))
+	var err error
+	if p.httpTLSConfig != nil {
+		err = httpServer.ListenAndServeTLS("", "")
+	} else {
+		err = httpServer.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("serve http: %w", err)
+	}
+
+	return nil
+}
+
+func (p *PlottingServer) shutdownHTTP() {
+	httpServer := p.httpServer.Load()
+	if httpServer == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	logger.Info("Stopping HTTP server")
+	if err := httpServer.Shutdown(ctx); err != nil {
+		logger.Error(
+			"Failed to shutdown HTTP server",
+			slog.Any("error", err),
+		)
+	}
+}
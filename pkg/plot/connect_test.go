@@ -0,0 +1,70 @@
+package plot
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+	"weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1/ruuviv1connect"
+
+	"connectrpc.com/connect"
+)
+
+func TestPlottingServer_HTTPHandler(t *testing.T) {
+	server := NewPlottingServer()
+	t.Cleanup(server.Stop)
+
+	httpServer := httptest.NewServer(server.HTTPHandler())
+	t.Cleanup(httpServer.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Same as a shell script would do with curl
+	hourAgo := time.Now().Add(-time.Hour).Truncate(time.Minute)
+	body := fmt.Sprintf(`{"measurements": [
+		{"device": "Kitchen", "temperature": 21.5, "timestamp": %q},
+		{"device": "Kitchen", "temperature": 22.5, "timestamp": %q},
+		{"device": "Kitchen"}
+	]}`, hourAgo.Format(time.RFC3339), hourAgo.Add(time.Minute).Format(time.RFC3339))
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		httpServer.URL+ruuviv1connect.RuuviPushMeasurementsProcedure,
+		strings.NewReader(body),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	respBody, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("PushMeasurements status = %d, body = %s", resp.StatusCode, respBody)
+	}
+	if !strings.Contains(string(respBody), `"accepted":2`) || !strings.Contains(string(respBody), `"rejected":1`) {
+		t.Errorf("PushMeasurements body = %s, want 2 accepted and 1 rejected", respBody)
+	}
+
+	client := ruuviv1connect.NewRuuviClient(httpServer.Client(), httpServer.URL, connect.WithHTTPGet())
+	latest, err := client.GetMeasurements(ctx, connect.NewRequest(&ruuvipb.RuuviGetMeasurementsRequest{
+		Devices:    []string{"Kitchen"},
+		LatestOnly: true,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := latest.Msg.GetMeasurements(); len(got) != 1 || got[0].GetTemperature() != 22.5 {
+		t.Errorf("GetMeasurements() = %v, want the latest measurement only", got)
+	}
+}
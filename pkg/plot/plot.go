@@ -3,25 +3,31 @@ package plot
 import (
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"time"
 
 	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+	"weezel/ruuvigraph/pkg/units"
 
 	"github.com/go-echarts/go-echarts/v2/charts"
 	"github.com/go-echarts/go-echarts/v2/components"
 	"github.com/go-echarts/go-echarts/v2/opts"
 )
 
-const outHTMLFilename = "sensor_data.html"
+const (
+	outHTMLFilename = "sensor_data.html"
+	// Lower bound of pressure axis in pascals
+	minPlotPressure = 90000.0
+)
 
-func getTemperatures(data []*ruuvipb.RuuviStreamDataRequest) []opts.LineData {
+func getTemperatures(data []*ruuvipb.RuuviStreamDataRequest, unit units.TemperatureUnit) []opts.LineData {
 	items := []opts.LineData{}
 	for _, d := range data {
 		items = append(items, opts.LineData{
 			Value: []any{
-				d.Timestamp.AsTime().Local().Format(time.RFC3339), // X axis
-				d.GetTemperature(), // Y axis
+				d.Timestamp.AsTime().Local().Format(time.RFC3339),             // X axis
+				units.Round(unit.FromCelsius(float64(d.GetTemperature())), 2), // Y axis
 			},
 		})
 	}
@@ -41,14 +47,14 @@ func getHumidity(data []*ruuvipb.RuuviStreamDataRequest) []opts.LineData {
 	return items
 }
 
-func getPressure(data []*ruuvipb.RuuviStreamDataRequest) []opts.LineData {
+func getPressure(data []*ruuvipb.RuuviStreamDataRequest, unit units.PressureUnit) []opts.LineData {
 	items := []opts.LineData{}
 	for _, d := range data {
 		items = append(items, opts.LineData{
 			// Name: d.Device,
 			Value: []any{
-				d.Timestamp.AsTime().Local().Format(time.RFC3339), // X axis
-				d.GetPressure() / 10.0,                            // Y axis
+				d.Timestamp.AsTime().Local().Format(time.RFC3339),          // X axis
+				units.Round(unit.FromPascals(float64(d.GetPressure())), 2), // Y axis
 			},
 		})
 	}
@@ -56,7 +62,7 @@ func getPressure(data []*ruuvipb.RuuviStreamDataRequest) []opts.LineData {
 }
 
 //nolint:dupl // Okay for now
-func plotTemperature(data []*ruuvipb.RuuviStreamDataRequest) *charts.Line {
+func plotTemperature(data []*ruuvipb.RuuviStreamDataRequest, unit units.TemperatureUnit) *charts.Line {
 	plotGraph := charts.NewLine()
 	plotGraph.SetGlobalOptions(
 		charts.WithInitializationOpts(opts.Initialization{
@@ -72,7 +78,8 @@ func plotTemperature(data []*ruuvipb.RuuviStreamDataRequest) *charts.Line {
 			Trigger: "axis",
 		}),
 		charts.WithYAxisOpts(opts.YAxis{
-			Min: 19.0,
+			Min:  math.Floor(unit.FromCelsius(19.0)),
+			Name: string(unit),
 		}),
 		charts.WithTitleOpts(opts.Title{
 			Title:    "Temperature",
@@ -93,7 +100,7 @@ func plotTemperature(data []*ruuvipb.RuuviStreamDataRequest) *charts.Line {
 
 	for Device, values := range m {
 		plotGraph.
-			AddSeries(Device, getTemperatures(values)).
+			AddSeries(Device, getTemperatures(values, unit)).
 			SetSeriesOptions(
 				charts.WithLineChartOpts(
 					opts.LineChart{
@@ -156,7 +163,7 @@ func plotHumidity(data []*ruuvipb.RuuviStreamDataRequest) *charts.Line {
 }
 
 //nolint:dupl // Okay for now
-func plotPressure(data []*ruuvipb.RuuviStreamDataRequest) *charts.Line {
+func plotPressure(data []*ruuvipb.RuuviStreamDataRequest, unit units.PressureUnit) *charts.Line {
 	plotGraph := charts.NewLine()
 	plotGraph.SetGlobalOptions(
 		charts.WithInitializationOpts(opts.Initialization{
@@ -172,7 +179,8 @@ func plotPressure(data []*ruuvipb.RuuviStreamDataRequest) *charts.Line {
 			Trigger: "axis",
 		}),
 		charts.WithYAxisOpts(opts.YAxis{
-			Min: 900.0,
+			Min:  math.Floor(unit.FromPascals(minPlotPressure)),
+			Name: string(unit),
 		}),
 		charts.WithTitleOpts(opts.Title{
 			Title:    "Air pressure",
@@ -193,7 +201,7 @@ func plotPressure(data []*ruuvipb.RuuviStreamDataRequest) *charts.Line {
 
 	for Device, values := range m {
 		plotGraph.
-			AddSeries(Device, getPressure(values)).
+			AddSeries(Device, getPressure(values, unit)).
 			SetSeriesOptions(
 				charts.WithLineChartOpts(
 					opts.LineChart{
@@ -207,12 +215,13 @@ func plotPressure(data []*ruuvipb.RuuviStreamDataRequest) *charts.Line {
 	return plotGraph
 }
 
-func Plot(data []*ruuvipb.RuuviStreamDataRequest) error {
+// Plot renders the measurements, which are in canonical units, in the display units
+func Plot(data []*ruuvipb.RuuviStreamDataRequest, display units.Display) error {
 	page := components.NewPage()
 	page.AddCharts(
-		plotTemperature(data),
+		plotTemperature(data, display.Temperature),
 		plotHumidity(data),
-		plotPressure(data),
+		plotPressure(data, display.Pressure),
 	)
 
 	f, err := os.Create(outHTMLFilename)
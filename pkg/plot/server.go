@@ -2,36 +2,94 @@ package plot
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/tls"
 	"errors"
+	"expvar"
 	"fmt"
 	"io"
 	"log/slog"
 	"net"
+	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"weezel/ruuvigraph/pkg/auth"
+	"weezel/ruuvigraph/pkg/batching"
 	"weezel/ruuvigraph/pkg/cache"
+	_ "weezel/ruuvigraph/pkg/compression" // Registers zstd compressor
 	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+	ruuviv2pb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v2"
 	"weezel/ruuvigraph/pkg/logging"
+	"weezel/ruuvigraph/pkg/pubsub"
+	"weezel/ruuvigraph/pkg/ratelimit"
+	"weezel/ruuvigraph/pkg/ruuvi"
+	"weezel/ruuvigraph/pkg/storage"
+	"weezel/ruuvigraph/pkg/units"
+	"weezel/ruuvigraph/pkg/unixsocket"
 
+	"connectrpc.com/connect"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	_ "google.golang.org/grpc/encoding/gzip" // Registers gzip compressor
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	reflectiongrpc "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionv1alphagrpc "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 var logger *slog.Logger = logging.NewColorLogHandler()
 
+// throttledMetric counts measurements refused by the rate limits of all servers,
+// published among the other expvar variables at /debug/vars of the pprof server
+var throttledMetric = expvar.NewInt("throttled_measurements")
+
 type PlottingServer struct {
 	ruuvipb.UnimplementedRuuviServer
 
 	storeFilename *string
 	server        *grpc.Server
-	measureData   *cache.Measurements
+	grpcOpts      []grpc.ServerOption
+	measureData   storage.Backend
+	subscribers   *pubsub.Hub
+	batches       *batchLog
+	collectors    *collectorRegistry
+	limiter       *ratelimit.Limiter
+	throttled     atomic.Uint64
+	config        *collectorConfig
+	groups        map[string][]string
 	once          *sync.Once
+	startOnce     sync.Once
 	lastGenerated time.Time
 	doPlot        chan time.Duration
 	stop          chan struct{}
+	display       units.Display
+	plotRange     time.Duration
+	socketMode    os.FileMode
+	socketGroup   string
+
+	archiveGenerations int
+	archiveGzip        bool
+
+	httpServer          atomic.Pointer[http.Server]
+	httpTLSConfig       *tls.Config
+	corsOrigins         []string
+	connectInterceptors []connect.Interceptor
+
+	health         *health.Server
+	plotterRunning atomic.Bool
+	reflection     bool
+	started        time.Time
+	version        string
+	buildTime      string
 }
 
 type OptionServer func(pOpt *PlottingServer)
@@ -51,55 +109,296 @@ func WithArchiveFilename(fname string) OptionServer {
 	}
 }
 
-func NewPlottingServer() *PlottingServer {
+// WithArchiveGenerations keeps n previous archive files, named like the archive
+// file suffixed with .1 to .n, newest first. Restoring falls back to them when the
+// archive file is missing or damaged.
+func WithArchiveGenerations(n int) OptionServer {
+	return func(psopt *PlottingServer) {
+		psopt.archiveGenerations = n
+	}
+}
+
+// WithArchiveGzip compresses the archive file with gzip. Restoring reads both
+// compressed and uncompressed archives.
+func WithArchiveGzip(compress bool) OptionServer {
+	return func(psopt *PlottingServer) {
+		psopt.archiveGzip = compress
+	}
+}
+
+// WithStorage stores measurements in the backend instead of the default in-memory cache.
+// Server closes the backend when stopped.
+func WithStorage(backend storage.Backend) OptionServer {
+	return func(psopt *PlottingServer) {
+		psopt.measureData = backend
+	}
+}
+
+// WithGroupsFile reads device groups used for filtering subscriptions
+func WithGroupsFile(fname string) OptionServer {
+	return func(psopt *PlottingServer) {
+		groups, err := ruuvi.ReadGroups(fname)
+		if err != nil {
+			logger.Error(
+				"Couldn't read groups file",
+				slog.String("fname", fname),
+				slog.Any("error", err),
+			)
+			return
+		}
+		psopt.groups = groups
+	}
+}
+
+// WithTransportCredentials sets credentials for incoming connections, e.g. TLS
+func WithTransportCredentials(creds credentials.TransportCredentials) OptionServer {
+	return func(psopt *PlottingServer) {
+		psopt.grpcOpts = append(psopt.grpcOpts, grpc.Creds(creds))
+	}
+}
+
+// WithTokens requires collectors to authenticate with one of the given pre-shared tokens
+func WithTokens(tokens map[string]string) OptionServer {
+	return func(psopt *PlottingServer) {
+		authenticator := auth.NewAuthenticator(
+			tokens,
+			auth.WithPublicServices(
+				healthpb.Health_ServiceDesc.ServiceName,
+				reflectiongrpc.ServerReflection_ServiceDesc.ServiceName,
+				reflectionv1alphagrpc.ServerReflection_ServiceDesc.ServiceName,
+			),
+		)
+		psopt.grpcOpts = append(
+			psopt.grpcOpts,
+			grpc.ChainUnaryInterceptor(authenticator.UnaryServerInterceptor()),
+			grpc.ChainStreamInterceptor(authenticator.StreamServerInterceptor()),
+		)
+		psopt.connectInterceptors = append(psopt.connectInterceptors, authenticator.ConnectInterceptor())
+	}
+}
+
+// WithTLSConfig enables TLS for both gRPC and HTTP servers
+func WithTLSConfig(cfg *tls.Config) OptionServer {
+	return func(psopt *PlottingServer) {
+		psopt.grpcOpts = append(psopt.grpcOpts, grpc.Creds(credentials.NewTLS(cfg)))
+		psopt.httpTLSConfig = cfg
+	}
+}
+
+// WithCORSOrigins allows browser code from the given origins to call the HTTP API, "*" allows all
+func WithCORSOrigins(origins ...string) OptionServer {
+	return func(psopt *PlottingServer) {
+		psopt.corsOrigins = append(psopt.corsOrigins, origins...)
+	}
+}
+
+// WithReflection enables gRPC server reflection, e.g. for grpcurl
+func WithReflection(enabled bool) OptionServer {
+	return func(psopt *PlottingServer) {
+		psopt.reflection = enabled
+	}
+}
+
+// WithVersion sets version information returned by GetServerInfo
+func WithVersion(version, buildTime string) OptionServer {
+	return func(psopt *PlottingServer) {
+		psopt.version = version
+		psopt.buildTime = buildTime
+	}
+}
+
+// WithCollectorConfig enables pushing configuration to collectors, see SetCollectorConfig
+func WithCollectorConfig(cfg *ruuvipb.RuuviCollectorConfig) OptionServer {
+	return func(psopt *PlottingServer) {
+		psopt.config.set(cfg)
+	}
+}
+
+// WithSocketPermissions sets permissions and owning group of the unix socket, group is left as is if empty
+func WithSocketPermissions(mode os.FileMode, group string) OptionServer {
+	return func(psopt *PlottingServer) {
+		psopt.socketMode = mode
+		psopt.socketGroup = group
+	}
+}
+
+// WithRateLimit limits measurements per second with bursts for each collector, and in total
+// with the global limits. Zero rate disables the corresponding limit.
+func WithRateLimit(rate float64, burst int, globalRate float64, globalBurst int) OptionServer {
+	return func(psopt *PlottingServer) {
+		if rate <= 0 && globalRate <= 0 {
+			return
+		}
+		psopt.limiter = ratelimit.New(rate, burst, ratelimit.WithGlobal(globalRate, globalBurst))
+	}
+}
+
+// WithPlotRange plots the last d instead of all raw measurements. Backends keeping
+// rollups plot them, when raw measurements don't cover the whole range.
+func WithPlotRange(d time.Duration) OptionServer {
+	return func(psopt *PlottingServer) {
+		psopt.plotRange = d
+	}
+}
+
+// WithDisplayUnits sets the units used in plots, measurements are stored in canonical units regardless
+func WithDisplayUnits(display units.Display) OptionServer {
+	return func(psopt *PlottingServer) {
+		psopt.display = display
+	}
+}
+
+func NewPlottingServer(opts ...OptionServer) *PlottingServer {
 	ps := &PlottingServer{
-		server:        grpc.NewServer(),
-		measureData:   cache.New(),
+		subscribers:   pubsub.NewHub(),
+		batches:       newBatchLog(1024),
+		collectors:    newCollectorRegistry(),
+		config:        newCollectorConfig(),
+		groups:        map[string][]string{},
 		lastGenerated: time.Now(),
 		once:          &sync.Once{},
 		doPlot:        make(chan time.Duration, 1),
 		stop:          make(chan struct{}, 1),
+		health:        health.NewServer(),
+		started:       time.Now(),
+		display:       units.DefaultDisplay,
+		socketMode:    0o660,
+	}
+
+	for _, opt := range opts {
+		opt(ps)
+	}
+	if ps.measureData == nil {
+		ps.measureData = cache.New()
+	}
+	if ps.storeFilename != nil && *ps.storeFilename != "" {
+		ps.restoreArchive()
 	}
 
+	ps.server = grpc.NewServer(ps.grpcOpts...)
 	ruuvipb.RegisterRuuviServer(ps.server, ps)
+	ruuviv2pb.RegisterRuuviServiceServer(ps.server, &serverV2{p: ps})
+	healthpb.RegisterHealthServer(ps.server, ps.health)
+	if ps.reflection {
+		reflection.Register(ps.server)
+	}
+	ps.updateHealth()
+
 	return ps
 }
 
+// Listen serves gRPC on the host and port, or on an unix socket when host is
+// an unix socket address (unix:/path/to/socket) in which case port is ignored
 func (p *PlottingServer) Listen(host, port string) error {
 	addr := net.JoinHostPort(host, port)
 
-	listen, err := net.Listen("tcp", addr)
+	var listen net.Listener
+	var err error
+	if socketPath, isUnix := unixsocket.Path(host); isUnix {
+		addr = host
+		listen, err = unixsocket.Listen(socketPath, p.socketMode, p.socketGroup)
+	} else {
+		listen, err = net.Listen("tcp", addr)
+	}
 	if err != nil {
 		return fmt.Errorf("net listen: %w", err)
 	}
 
-	logger.Info("Starting plotter service")
-	go p.plotter()
-	logger.Info("Started plotter service")
-
 	logger.Info(fmt.Sprintf("gRPC server listening on %s", addr))
-	errCh := make(chan error, 1)
-	go func() {
-		if err = p.server.Serve(listen); err != nil {
-			errCh <- fmt.Errorf("serve grpc: %w", err)
-		}
-	}()
+	return p.Serve(listen)
+}
 
-	return <-errCh
+// Start starts the plotter, which Serve does too. Needed only when the server
+// isn't serving gRPC, e.g. with an in-process collector only.
+func (p *PlottingServer) Start() {
+	p.startOnce.Do(func() {
+		logger.Info("Starting plotter service")
+		go p.plotter()
+		logger.Info("Started plotter service")
+	})
+}
+
+// Serve starts the plotter and serves gRPC on the given listener until stopped
+func (p *PlottingServer) Serve(listener net.Listener) error {
+	p.Start()
+
+	if err := p.server.Serve(listener); err != nil {
+		return fmt.Errorf("serve grpc: %w", err)
+	}
+	return nil
 }
 
 func (p *PlottingServer) Stop() {
 	p.once.Do(func() {
 		logger.Info("Shutting down plotting service")
-		p.measureData.Stop()
+		if err := p.measureData.Close(); err != nil {
+			logger.Error(
+				"Failed to close measurement storage",
+				slog.Any("error", err),
+			)
+		}
+		p.subscribers.Close()
+		p.shutdownHTTP()
 		p.stop <- struct{}{}
+		p.health.Shutdown()
 		logger.Info("Shutting down plotting service")
 	})
 }
 
+// updateHealth sets serving status based on whether the plotter and cache are running
+func (p *PlottingServer) updateHealth() {
+	servingStatus := healthpb.HealthCheckResponse_SERVING
+	storageRunning := true
+	if runner, ok := p.measureData.(interface{ Running() bool }); ok {
+		storageRunning = runner.Running()
+	}
+	if !p.plotterRunning.Load() || !storageRunning {
+		servingStatus = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+
+	p.health.SetServingStatus("", servingStatus)
+	p.health.SetServingStatus(ruuvipb.Ruuvi_ServiceDesc.ServiceName, servingStatus)
+	p.health.SetServingStatus(ruuviv2pb.RuuviService_ServiceDesc.ServiceName, servingStatus)
+}
+
+// downsampler is a backend which summarizes long ranges, e.g. cache.Measurements
+type downsampler interface {
+	Downsampled(devices []string, from, until time.Time) ([]*ruuvipb.RuuviStreamDataRequest, error)
+}
+
+// plotted returns the measurements to plot
+func (p *PlottingServer) plotted() ([]*ruuvipb.RuuviStreamDataRequest, error) {
+	var from, until time.Time
+	if p.plotRange > 0 {
+		until = time.Now()
+		from = until.Add(-p.plotRange)
+	}
+
+	var measurements []*ruuvipb.RuuviStreamDataRequest
+	var err error
+	if d, ok := p.measureData.(downsampler); ok && p.plotRange > 0 {
+		measurements, err = d.Downsampled(nil, from, until)
+	} else {
+		measurements, err = p.measureData.Range(nil, from, until)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read measurements: %w", err)
+	}
+	return measurements, nil
+}
+
 func (p *PlottingServer) plotter() {
+	p.plotterRunning.Store(true)
+	p.updateHealth()
+
+	healthTicker := time.NewTicker(10 * time.Second)
+	collectorsTicker := time.NewTicker(time.Minute)
 	defer func() {
 		logger.Info("Stopping plotter")
+		healthTicker.Stop()
+		collectorsTicker.Stop()
+		p.plotterRunning.Store(false)
+		p.updateHealth()
 
 		if p.server != nil {
 			logger.Info("Stopping gRPC server")
@@ -114,9 +413,23 @@ func (p *PlottingServer) plotter() {
 		select {
 		case <-p.stop:
 			return
+		case <-healthTicker.C:
+			p.updateHealth()
+		case <-collectorsTicker.C:
+			// Written regardless of measurements, silent collectors are the interesting ones
+			if err := p.writeCollectorsPage(); err != nil {
+				logger.Error(
+					"Failed to write collectors page",
+					slog.Any("error", err),
+				)
+			}
 		case lastGenerated := <-p.doPlot:
 			logger.Info("Plotting measurements")
-			if err := Plot(p.measureData.All()); err != nil {
+			measurements, err := p.plotted()
+			if err == nil {
+				err = Plot(measurements, p.display)
+			}
+			if err != nil {
 				logger.Error(
 					"Failed to generate plot",
 					slog.Any("error", err),
@@ -146,64 +459,365 @@ func (p *PlottingServer) plotter() {
 	}
 }
 
-func (p *PlottingServer) archive() error {
-	logger.Info("Writing archive file")
+// ingest validates and stores a single measurement, and delivers it to the subscribers
+func (p *PlottingServer) ingest(collector string, msg *ruuvipb.RuuviStreamDataRequest) error {
+	units.NormalizeLegacy(msg)
+	err := validateMeasurement(msg)
+	if err == nil {
+		if addErr := p.measureData.Append(msg); addErr != nil {
+			err = fmt.Errorf("store: %w", addErr)
+		}
+	}
+	if err != nil {
+		logger.Warn(
+			"Rejected measurement",
+			slog.String("collector", collector),
+			slog.String("device", msg.GetDevice()),
+			slog.String("mac", msg.GetMacAddress()),
+			slog.Any("error", err),
+		)
+		return err
+	}
 
-	dataCopy := p.measureData.All()
+	logger.Info(
+		"Received measurement",
+		slog.String("collector", collector),
+		slog.String("device", msg.Device),
+		slog.String("mac", msg.MacAddress),
+		slog.Float64("temperature", float64(msg.Temperature)),
+		slog.Float64("humidity", float64(msg.Humidity)),
+		slog.Float64("pressure", float64(msg.Pressure)),
+		slog.Float64("battery_volts", float64(msg.BatterVolts)),
+		slog.Int("rssi", int(msg.Rssi)),
+		slog.Time("timestamp", msg.Timestamp.AsTime().Local()),
+	)
 
-	j, err := json.MarshalIndent(dataCopy, "", "  ")
-	if err != nil {
-		return fmt.Errorf("marshal measurements: %w", err)
+	p.subscribers.Publish(msg)
+
+	if time.Since(p.lastGenerated) >= time.Minute {
+		p.lastGenerated = time.Now()
+		select {
+		case p.doPlot <- time.Since(p.lastGenerated):
+		default: // Plot already scheduled, no need to enqueue another
+		}
 	}
 
-	if err = os.WriteFile(*p.storeFilename, j, 0o600); err != nil {
-		return fmt.Errorf("write json: %w", err)
+	return nil
+}
+
+// admit checks that n more measurements from the collector fit in the rate limits.
+// Refused measurements aren't acknowledged, hence collectors resend them later.
+func (p *PlottingServer) admit(collector string, n int) error {
+	if p.limiter == nil || p.limiter.AllowN(collector, n) {
+		return nil
 	}
 
-	logger.Info(
-		"Wrote archive file",
-		slog.String("fpath", *p.storeFilename),
+	throttled := uint64(n) //nolint:gosec // Can't be negative
+	p.throttled.Add(throttled)
+	throttledMetric.Add(int64(n))
+	p.collectors.throttled(collector, throttled)
+	logger.Warn(
+		"Throttled collector",
+		slog.String("collector", collector),
+		slog.Int("measurements", n),
+		slog.Uint64("throttled_total", p.throttled.Load()),
 	)
+	return status.Errorf(codes.ResourceExhausted, "rate limit exceeded for collector %q", collector)
+}
 
-	return nil
+// admitted wraps recv to check the rate limits for every received measurement
+func (p *PlottingServer) admitted(
+	collector string,
+	recv func() (*ruuvipb.RuuviStreamDataRequest, error),
+) func() (*ruuvipb.RuuviStreamDataRequest, error) {
+	return func() (*ruuvipb.RuuviStreamDataRequest, error) {
+		msg, err := recv()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.admit(collector, 1); err != nil {
+			return nil, err
+		}
+		return msg, nil
+	}
 }
 
-func (p *PlottingServer) StreamData(stream ruuvipb.Ruuvi_StreamDataServer) error {
-	for {
-		msg, err := stream.Recv()
+// receiveMeasurements ingests measurements until recv returns io.EOF.
+// Transports wrap their streams into recv function.
+func (p *PlottingServer) receiveMeasurements(
+	collector string,
+	recv func() (*ruuvipb.RuuviStreamDataRequest, error),
+) (*ruuvipb.RuuviStreamDataResponse, error) {
+	resp := &ruuvipb.RuuviStreamDataResponse{
+		Message: "OK",
+	}
+
+	for index := uint32(0); ; index++ {
+		msg, err := recv()
 		if err != nil {
 			if errors.Is(err, io.EOF) || errors.Is(err, context.Canceled) {
-				err1 := stream.SendAndClose(&ruuvipb.RuuviStreamDataResponse{
-					Message: "OK",
-				})
-				if err1 != nil {
-					return fmt.Errorf("send and close: %w (%w)", err1, err)
-				}
-				return nil
+				return resp, nil
 			}
-			return fmt.Errorf("stream receive error: %w", err)
+			return nil, fmt.Errorf("stream receive error: %w", err)
 		}
+		p.collectors.heard(collector, time.Now())
 
+		if err = p.ingest(collector, msg); err != nil {
+			resp.Rejected++
+			resp.Rejections = append(resp.Rejections, &ruuvipb.RuuviRejection{
+				Index:  index,
+				Reason: err.Error(),
+			})
+			continue
+		}
+		resp.Accepted++
+	}
+}
+
+// ingestBatch stores the batch unless it has already been received
+func (p *PlottingServer) ingestBatch(collector string, batch *ruuvipb.RuuviMeasurementBatch) *ruuvipb.RuuviBatchAck {
+	p.collectors.heard(collector, time.Now())
+
+	ack := &ruuvipb.RuuviBatchAck{
+		BatchId: batch.GetBatchId(),
+	}
+	if !p.batches.Claim(collector+"/"+batch.GetSession(), batch.GetBatchId()) {
 		logger.Info(
-			"Received measurement",
-			slog.String("device", msg.Device),
-			slog.String("mac", msg.MacAddress),
-			slog.Float64("temperature", float64(msg.Temperature)),
-			slog.Float64("humidity", float64(msg.Humidity)),
-			slog.Float64("pressure", float64(msg.Pressure)),
-			slog.Float64("battery_volts", float64(msg.BatterVolts)),
-			slog.Int("rssi", int(msg.Rssi)),
-			slog.Time("timestamp", msg.Timestamp.AsTime().Local()),
+			"Received duplicate batch",
+			slog.String("collector", collector),
+			slog.Uint64("batch_id", batch.GetBatchId()),
 		)
+		ack.Duplicate = true
+		return ack
+	}
 
-		p.measureData.Add(msg)
+	for index, msg := range batching.Measurements(batch) {
+		if err := p.ingest(collector, msg); err != nil {
+			ack.Rejected++
+			ack.Rejections = append(ack.Rejections, &ruuvipb.RuuviRejection{
+				Index:  uint32(index), //nolint:gosec // Batch can't have more than 2^32 items
+				Reason: err.Error(),
+			})
+			continue
+		}
+		ack.Accepted++
+	}
 
-		if time.Since(p.lastGenerated) >= time.Minute {
-			p.lastGenerated = time.Now()
-			select {
-			case p.doPlot <- time.Since(p.lastGenerated):
-			default: // Plot already scheduled, no need to enqueue another
+	return ack
+}
+
+// IngestBatch stores a batch from a collector running in the same process
+func (p *PlottingServer) IngestBatch(ctx context.Context, batch *ruuvipb.RuuviMeasurementBatch) *ruuvipb.RuuviBatchAck {
+	return p.ingestBatch(collectorName(ctx, batch.GetCollector()), batch)
+}
+
+// collectorName returns the collector name used for deduplicating batches and tracking collectors.
+// Authenticated name can't be spoofed, hence it's preferred over the announced one.
+func collectorName(ctx context.Context, announced string) string {
+	if collector, authenticated := auth.CollectorFromContext(ctx); authenticated {
+		return collector
+	}
+	return announced
+}
+
+func (p *PlottingServer) serverInfo() *ruuvipb.RuuviServerInfoResponse {
+	stats := p.measureData.Stats()
+	return &ruuvipb.RuuviServerInfoResponse{
+		Version:               p.version,
+		BuildTime:             p.buildTime,
+		Started:               timestamppb.New(p.started),
+		Uptime:                durationpb.New(time.Since(p.started)),
+		DeviceCount:           uint32(stats.Devices),      //nolint:gosec // Can't be negative
+		MeasurementCount:      uint64(stats.Measurements), //nolint:gosec // Can't be negative
+		ThrottledMeasurements: p.throttled.Load(),
+		EvictedMeasurements:   uint64(stats.Evicted), //nolint:gosec // Can't be negative
+	}
+}
+
+func (p *PlottingServer) pushMeasurements(
+	collector string,
+	req *ruuvipb.RuuviPushMeasurementsRequest,
+) (*ruuvipb.RuuviStreamDataResponse, error) {
+	msgs := req.GetMeasurements()
+	// Whole request is refused at once rather than partially stored
+	if err := p.admit(collector, len(msgs)); err != nil {
+		return nil, err
+	}
+	// Can't fail since the measurements are already in memory and admitted
+	resp, _ := p.receiveMeasurements(collector, func() (*ruuvipb.RuuviStreamDataRequest, error) {
+		if len(msgs) == 0 {
+			return nil, io.EOF
+		}
+		msg := msgs[0]
+		msgs = msgs[1:]
+		return msg, nil
+	})
+	return resp, nil
+}
+
+func (p *PlottingServer) getMeasurements(
+	req *ruuvipb.RuuviGetMeasurementsRequest,
+) (*ruuvipb.RuuviGetMeasurementsResponse, error) {
+	var from, until time.Time
+	if req.GetSince() != nil {
+		from = req.GetSince().AsTime()
+	}
+	if req.GetUntil() != nil {
+		until = req.GetUntil().AsTime()
+	}
+
+	var measurements []*ruuvipb.RuuviStreamDataRequest
+	var err error
+	if req.GetLatestOnly() && from.IsZero() && until.IsZero() {
+		measurements, err = p.measureData.Latest(req.GetDevices())
+	} else {
+		measurements, err = p.measureData.Range(req.GetDevices(), from, until)
+		if req.GetLatestOnly() {
+			measurements = storage.LatestOf(measurements)
+		}
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "read measurements: %v", err)
+	}
+
+	return &ruuvipb.RuuviGetMeasurementsResponse{Measurements: measurements}, nil
+}
+
+// subscribe delivers measurements matching the request with send until
+// context is cancelled or the subscription ends.
+func (p *PlottingServer) subscribe(
+	ctx context.Context,
+	req *ruuvipb.RuuviSubscribeRequest,
+	send func(*ruuvipb.RuuviStreamDataRequest) error,
+) error {
+	filter, err := p.subscriptionFilter(req)
+	if err != nil {
+		return err
+	}
+
+	sub := p.subscribers.Subscribe(filter)
+	defer sub.Close()
+
+	logger.Info(
+		"Subscriber connected",
+		slog.Any("devices", req.GetDevices()),
+		slog.Any("groups", req.GetGroups()),
+		slog.Int("subscribers", p.subscribers.Len()),
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Subscriber disconnected")
+			return nil
+		case msg, ok := <-sub.Messages():
+			if !ok {
+				switch err := sub.Err(); {
+				case errors.Is(err, pubsub.ErrSlowConsumer):
+					logger.Warn("Dropped slow subscriber")
+					return status.Error(codes.ResourceExhausted, "subscriber is too slow")
+				case errors.Is(err, pubsub.ErrHubClosed):
+					return status.Error(codes.Unavailable, "server is shutting down")
+				}
+				return nil
 			}
+			if err := send(msg); err != nil {
+				return fmt.Errorf("send measurement: %w", err)
+			}
+		}
+	}
+}
+
+// subscriptionFilter constructs a filter which matches when the measurement's
+// device name or MAC address is either listed directly or belongs to one
+// of the listed groups.
+func (p *PlottingServer) subscriptionFilter(req *ruuvipb.RuuviSubscribeRequest) (pubsub.Filter, error) {
+	if len(req.GetDevices()) == 0 && len(req.GetGroups()) == 0 {
+		return nil, nil
+	}
+
+	members := slices.Clone(req.GetDevices())
+	for _, group := range req.GetGroups() {
+		groupMembers, found := p.groups[group]
+		if !found {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown group %q", group)
 		}
+		members = append(members, groupMembers...)
 	}
+
+	return func(m *ruuvipb.RuuviStreamDataRequest) bool {
+		return slices.Contains(members, m.GetDevice()) ||
+			slices.Contains(members, m.GetMacAddress())
+	}, nil
+}
+
+func (p *PlottingServer) StreamData(stream ruuvipb.Ruuvi_StreamDataServer) error {
+	collector, _ := auth.CollectorFromContext(stream.Context())
+
+	resp, err := p.receiveMeasurements(collector, p.admitted(collector, stream.Recv))
+	if err != nil {
+		return err
+	}
+	if err = stream.SendAndClose(resp); err != nil {
+		return fmt.Errorf("send and close: %w", err)
+	}
+	return nil
+}
+
+func (p *PlottingServer) SendBatches(stream ruuvipb.Ruuvi_SendBatchesServer) error {
+	for {
+		batch, err := stream.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("stream receive error: %w", err)
+		}
+
+		collector := collectorName(stream.Context(), batch.GetCollector())
+		if err = p.admit(collector, batching.Len(batch)); err != nil {
+			return err
+		}
+		ack := p.ingestBatch(collector, batch)
+		if err = stream.Send(ack); err != nil {
+			return fmt.Errorf("send ack: %w", err)
+		}
+	}
+}
+
+func (p *PlottingServer) GetServerInfo(
+	_ context.Context,
+	_ *ruuvipb.RuuviServerInfoRequest,
+) (*ruuvipb.RuuviServerInfoResponse, error) {
+	return p.serverInfo(), nil
+}
+
+func (p *PlottingServer) PushMeasurements(
+	ctx context.Context,
+	req *ruuvipb.RuuviPushMeasurementsRequest,
+) (*ruuvipb.RuuviStreamDataResponse, error) {
+	collector, _ := auth.CollectorFromContext(ctx)
+	return p.pushMeasurements(collector, req)
+}
+
+func (p *PlottingServer) GetMeasurements(
+	_ context.Context,
+	req *ruuvipb.RuuviGetMeasurementsRequest,
+) (*ruuvipb.RuuviGetMeasurementsResponse, error) {
+	return p.getMeasurements(req)
+}
+
+func (p *PlottingServer) Subscribe(
+	req *ruuvipb.RuuviSubscribeRequest,
+	stream ruuvipb.Ruuvi_SubscribeServer,
+) error {
+	return p.subscribe(stream.Context(), req, stream.Send)
+}
+
+func (p *PlottingServer) ReportStatus(
+	ctx context.Context,
+	status *ruuvipb.RuuviCollectorStatus,
+) (*ruuvipb.RuuviReportStatusResponse, error) {
+	p.reportStatus(ctx, status)
+	return &ruuvipb.RuuviReportStatusResponse{}, nil
 }
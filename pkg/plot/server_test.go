@@ -0,0 +1,235 @@
+package plot
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"weezel/ruuvigraph/pkg/batching"
+	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func startTestServer(t *testing.T, opts ...OptionServer) (*PlottingServer, *grpc.ClientConn) {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	server := NewPlottingServer(opts...)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return server, conn
+}
+
+func testMeasurement(device string, ts time.Time) *ruuvipb.RuuviStreamDataRequest {
+	return &ruuvipb.RuuviStreamDataRequest{
+		Device:      device,
+		MacAddress:  "aa:bb:cc:dd:ee:ff",
+		Temperature: 21.5,
+		Humidity:    40,
+		Pressure:    101320,
+		BatterVolts: 2.9,
+		Timestamp:   timestamppb.New(ts),
+	}
+}
+
+func TestPlottingServer_Health(t *testing.T) {
+	_, conn := startTestServer(t)
+
+	client := healthpb.NewHealthClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{
+		Service: ruuvipb.Ruuvi_ServiceDesc.ServiceName,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Watch() error = %v", err)
+		}
+		if resp.GetStatus() == healthpb.HealthCheckResponse_SERVING {
+			return
+		}
+	}
+}
+
+func TestPlottingServer_GetServerInfo(t *testing.T) {
+	_, conn := startTestServer(t, WithVersion("abc123", "today"))
+	client := ruuvipb.NewRuuviClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.StreamData(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dev := range []string{"Kitchen", "Balcony", "Kitchen"} {
+		if err = stream.Send(testMeasurement(dev, time.Now())); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = stream.Send(&ruuvipb.RuuviStreamDataRequest{Device: "Broken"}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetAccepted() != 3 || resp.GetRejected() != 1 {
+		t.Errorf("StreamData() accepted=%d rejected=%d, want 3 and 1", resp.GetAccepted(), resp.GetRejected())
+	}
+
+	info, err := client.GetServerInfo(ctx, &ruuvipb.RuuviServerInfoRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.GetVersion() != "abc123" || info.GetBuildTime() != "today" {
+		t.Errorf("GetServerInfo() version=%q build_time=%q", info.GetVersion(), info.GetBuildTime())
+	}
+	if info.GetDeviceCount() != 2 {
+		t.Errorf("GetServerInfo() device_count = %d, want 2", info.GetDeviceCount())
+	}
+	if info.GetMeasurementCount() != 3 {
+		t.Errorf("GetServerInfo() measurement_count = %d, want 3", info.GetMeasurementCount())
+	}
+}
+
+func TestPlottingServer_IngestBatch(t *testing.T) {
+	server := NewPlottingServer()
+	server.Start()
+	server.Start() // Serve starts it again with remote collectors
+	t.Cleanup(server.Stop)
+
+	ack := server.IngestBatch(context.Background(), &ruuvipb.RuuviMeasurementBatch{
+		Collector: "local",
+		BatchId:   1,
+		Measurements: []*ruuvipb.RuuviStreamDataRequest{
+			testMeasurement("Kitchen", time.Now()),
+			{Device: "Broken"},
+		},
+	})
+	if ack.GetBatchId() != 1 || ack.GetAccepted() != 1 || ack.GetRejected() != 1 {
+		t.Errorf("IngestBatch() = %v, want batch 1 with 1 accepted and 1 rejected", ack)
+	}
+	if collectors := server.collectors.list(); len(collectors) != 1 || collectors[0].Name != "local" {
+		t.Errorf("collectors = %v, want local", collectors)
+	}
+}
+
+func TestPlottingServer_rateLimit(t *testing.T) {
+	_, conn := startTestServer(t, WithRateLimit(0.001, 2, 0, 0))
+	client := ruuvipb.NewRuuviClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	sendBatch := func(collector string, batchID uint64, count int, compact bool) error {
+		stream, err := client.SendBatches(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		batch := &ruuvipb.RuuviMeasurementBatch{Collector: collector, BatchId: batchID}
+		for i := range count {
+			m := testMeasurement("Kitchen", time.Now().Add(time.Duration(i)))
+			batch.Measurements = append(batch.Measurements, m)
+		}
+		// Like collectors send by default
+		if compact {
+			batching.Compact(batch)
+		}
+		if err = stream.Send(batch); err != nil {
+			t.Fatal(err)
+		}
+		_, err = stream.Recv()
+		return err
+	}
+
+	if err := sendBatch("kitchen-pi", 1, 2, false); err != nil {
+		t.Fatalf("batch within burst: %v", err)
+	}
+	if err := sendBatch("kitchen-pi", 2, 1, false); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("batch over the limit: error = %v, want ResourceExhausted", err)
+	}
+	if err := sendBatch("garage-pi", 1, 1, false); err != nil {
+		t.Errorf("other collector was throttled: %v", err)
+	}
+	if err := sendBatch("attic-pi", 1, 2, true); err != nil {
+		t.Fatalf("compact batch within burst: %v", err)
+	}
+	if err := sendBatch("attic-pi", 2, 2, true); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("compact batch over the limit: error = %v, want ResourceExhausted", err)
+	}
+
+	info, err := client.GetServerInfo(ctx, &ruuvipb.RuuviServerInfoRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.GetThrottledMeasurements() != 3 {
+		t.Errorf("GetServerInfo() throttled_measurements = %d, want 3", info.GetThrottledMeasurements())
+	}
+	if throttledMetric.Value() < 3 {
+		t.Errorf("throttled_measurements metric = %d, want at least 3", throttledMetric.Value())
+	}
+}
+
+func TestPlottingServer_plotted(t *testing.T) {
+	server := NewPlottingServer(WithPlotRange(2 * time.Hour))
+	t.Cleanup(server.Stop)
+	err := server.measureData.Append(
+		testMeasurement("Kitchen", time.Now().Add(-5*time.Hour)),
+		testMeasurement("Kitchen", time.Now().Add(-time.Hour)),
+		testMeasurement("Kitchen", time.Now().Add(-time.Hour).Add(time.Second)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plotted, err := server.plotted()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plotted) != 2 {
+		t.Errorf("plotted %d measurements of the last two hours, want 2", len(plotted))
+	}
+
+	// Longer than raw measurements are kept, hence hourly means
+	server.plotRange = 30 * 24 * time.Hour
+	if plotted, err = server.plotted(); err != nil {
+		t.Fatal(err)
+	}
+	if len(plotted) == 0 {
+		t.Fatal("plotted nothing of the last month")
+	}
+	for _, m := range plotted {
+		if ts := m.GetTimestamp().AsTime(); !ts.Equal(ts.Truncate(time.Hour)) {
+			t.Errorf("plotted measurement at %v, want hourly means", ts)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package plot
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func TestPlottingServer_ListenUnix(t *testing.T) {
+	addr := "unix:" + filepath.Join(t.TempDir(), "grpc.sock")
+
+	server := NewPlottingServer(WithVersion("abc123", "today"), WithSocketPermissions(0o600, ""))
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.Listen(addr, "")
+	}()
+	t.Cleanup(func() {
+		server.Stop()
+		if err := <-errCh; err != nil {
+			t.Errorf("Listen() error = %v", err)
+		}
+	})
+
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	info, err := ruuvipb.NewRuuviClient(conn).GetServerInfo(
+		ctx,
+		&ruuvipb.RuuviServerInfoRequest{},
+		grpc.WaitForReady(true),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.GetVersion() != "abc123" {
+		t.Errorf("GetServerInfo() version = %q, want abc123", info.GetVersion())
+	}
+}
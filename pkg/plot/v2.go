@@ -0,0 +1,205 @@
+package plot
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+	ruuviv2pb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v2"
+	"weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v2/ruuviv2connect"
+
+	"connectrpc.com/connect"
+	"github.com/peterhellberg/ruuvitag"
+)
+
+// serverV2 serves ruuvi.v2 protocol by converting it to the v1 messages,
+// which are what cache and plots use.
+type serverV2 struct {
+	ruuviv2pb.UnimplementedRuuviServiceServer
+
+	p *PlottingServer
+}
+
+// connectHandlerV2 is the Connect counterpart of serverV2
+type connectHandlerV2 struct {
+	ruuviv2connect.UnimplementedRuuviServiceHandler
+
+	p *PlottingServer
+}
+
+// rawv2Format is the data format of RAWv2 advertisements
+const rawv2Format = 5
+
+var errMissingReading = errors.New("missing reading")
+
+// measurementFromV2 converts to the v1 message, which is used for storing. Readings
+// left unset are decoded from the raw RAWv2 payload when there's one. v1 can't tell
+// a missing reading from zero, hence a measurement still missing any is refused.
+// Unset RSSI is stored as 0 dBm, which no received advertisement has.
+func measurementFromV2(m *ruuviv2pb.Measurement) (*ruuvipb.RuuviStreamDataRequest, error) {
+	missing := m.TemperatureCelsius == nil || m.RelativeHumidityPercent == nil ||
+		m.PressurePascals == nil || m.BatteryVolts == nil
+	rawv2 := m.GetDataFormat() == 0 || m.GetDataFormat() == rawv2Format
+
+	var raw *ruuvitag.RAWv2
+	if missing && rawv2 && len(m.GetRawPayload()) > 0 {
+		payload, err := ruuvitag.ParseRAWv2(m.GetRawPayload())
+		if err != nil {
+			return nil, fmt.Errorf("raw payload: %w", err)
+		}
+		raw = &payload
+	}
+
+	reading := func(name string, value *float64, fromRaw func(r *ruuvitag.RAWv2) float64) (float32, error) {
+		switch {
+		case value != nil:
+			return float32(*value), nil
+		case raw != nil:
+			return float32(fromRaw(raw)), nil
+		default:
+			return 0, fmt.Errorf("%s: %w", name, errMissingReading)
+		}
+	}
+	temperature, errT := reading("temperature", m.TemperatureCelsius, func(r *ruuvitag.RAWv2) float64 {
+		return r.Temperature
+	})
+	humidity, errH := reading("humidity", m.RelativeHumidityPercent, func(r *ruuvitag.RAWv2) float64 {
+		return r.Humidity
+	})
+	pressure, errP := reading("pressure", m.PressurePascals, func(r *ruuvitag.RAWv2) float64 {
+		return float64(r.Pressure)
+	})
+	battery, errB := reading("battery", m.BatteryVolts, func(r *ruuvitag.RAWv2) float64 {
+		return float64(r.Battery) / 1000
+	})
+	if err := errors.Join(errT, errH, errP, errB); err != nil {
+		return nil, err //nolint:wrapcheck // Joined errors of this package
+	}
+
+	return &ruuvipb.RuuviStreamDataRequest{
+		Device:      m.GetDevice(),
+		MacAddress:  m.GetMacAddress(),
+		Temperature: temperature,
+		Humidity:    humidity,
+		Pressure:    pressure,
+		BatterVolts: battery,
+		Rssi:        m.GetRssiDbm(),
+		Timestamp:   m.GetTimestamp(),
+	}, nil
+}
+
+// batchFromV2 converts the measurements which v1 can represent. Returns the index
+// of each converted one in the request, and rejections of the others.
+func batchFromV2(
+	req *ruuviv2pb.SendBatchesRequest,
+) (*ruuvipb.RuuviMeasurementBatch, []uint32, []*ruuviv2pb.Rejection) {
+	batch := &ruuvipb.RuuviMeasurementBatch{
+		Collector:    req.GetCollector().GetName(),
+		Session:      req.GetCollector().GetSession(),
+		BatchId:      req.GetBatchId(),
+		Measurements: make([]*ruuvipb.RuuviStreamDataRequest, 0, len(req.GetMeasurements())),
+	}
+	indexes := make([]uint32, 0, len(req.GetMeasurements()))
+	rejections := []*ruuviv2pb.Rejection{}
+	for i, m := range req.GetMeasurements() {
+		index := uint32(i) //nolint:gosec // Batch can't have more than 2^32 items
+		converted, err := measurementFromV2(m)
+		if err != nil {
+			rejections = append(rejections, &ruuviv2pb.Rejection{Index: index, Reason: err.Error()})
+			continue
+		}
+		batch.Measurements = append(batch.Measurements, converted)
+		indexes = append(indexes, index)
+	}
+	return batch, indexes, rejections
+}
+
+// ackToV2 converts the ack of the converted measurements, indexes map them back to
+// the request and refused are the ones which couldn't be converted
+func ackToV2(
+	ack *ruuvipb.RuuviBatchAck,
+	indexes []uint32,
+	refused []*ruuviv2pb.Rejection,
+) *ruuviv2pb.SendBatchesResponse {
+	resp := &ruuviv2pb.SendBatchesResponse{
+		BatchId:   ack.GetBatchId(),
+		Accepted:  ack.GetAccepted(),
+		Rejected:  ack.GetRejected(),
+		Duplicate: ack.GetDuplicate(),
+	}
+	for _, r := range ack.GetRejections() {
+		resp.Rejections = append(resp.Rejections, &ruuviv2pb.Rejection{
+			Index:  indexes[r.GetIndex()],
+			Reason: r.GetReason(),
+		})
+	}
+	if !ack.GetDuplicate() {
+		resp.Rejected += uint32(len(refused)) //nolint:gosec // Batch can't have more than 2^32 items
+		resp.Rejections = append(resp.Rejections, refused...)
+		slices.SortFunc(resp.Rejections, func(a, b *ruuviv2pb.Rejection) int {
+			return cmp.Compare(a.GetIndex(), b.GetIndex())
+		})
+	}
+	return resp
+}
+
+func (p *PlottingServer) ingestBatchV2(
+	ctx context.Context,
+	req *ruuviv2pb.SendBatchesRequest,
+) (*ruuviv2pb.SendBatchesResponse, error) {
+	collector := collectorName(ctx, req.GetCollector().GetName())
+	if err := p.admit(collector, len(req.GetMeasurements())); err != nil {
+		return nil, err
+	}
+	p.collectors.describe(collector, req.GetCollector())
+
+	batch, indexes, refused := batchFromV2(req)
+	return ackToV2(p.ingestBatch(collector, batch), indexes, refused), nil
+}
+
+func (s *serverV2) SendBatches(stream ruuviv2pb.RuuviService_SendBatchesServer) error {
+	for {
+		req, err := stream.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("stream receive error: %w", err)
+		}
+
+		resp, err := s.p.ingestBatchV2(stream.Context(), req)
+		if err != nil {
+			return err
+		}
+		if err = stream.Send(resp); err != nil {
+			return fmt.Errorf("send ack: %w", err)
+		}
+	}
+}
+
+func (c *connectHandlerV2) SendBatches(
+	ctx context.Context,
+	stream *connect.BidiStream[ruuviv2pb.SendBatchesRequest, ruuviv2pb.SendBatchesResponse],
+) error {
+	for {
+		req, err := stream.Receive()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("stream receive error: %w", err)
+		}
+
+		resp, err := c.p.ingestBatchV2(ctx, req)
+		if err != nil {
+			return toConnectError(err)
+		}
+		if err = stream.Send(resp); err != nil {
+			return fmt.Errorf("send ack: %w", err)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package plot
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+	ruuviv2pb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v2"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestMeasurementFromV2(t *testing.T) {
+	ts := timestamppb.New(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	// RAWv2 example of Ruuvi's data format documentation
+	rawPayload := []byte{
+		0x99, 0x04, 0x05, 0x12, 0xFC, 0x53, 0x94, 0xC3, 0x7C, 0x00, 0x04, 0xFF, 0xFC,
+		0x04, 0x0C, 0xAC, 0x36, 0x42, 0x00, 0xCD, 0xCB, 0xB8, 0x33, 0x4C, 0x88, 0x4F,
+	}
+
+	tests := []struct {
+		name    string
+		m       *ruuviv2pb.Measurement
+		want    *ruuvipb.RuuviStreamDataRequest
+		wantErr bool
+	}{
+		{
+			name: "all readings",
+			m: &ruuviv2pb.Measurement{
+				Device:                  "Kitchen",
+				MacAddress:              "aa:bb:cc:dd:ee:ff",
+				Timestamp:               ts,
+				TemperatureCelsius:      proto.Float64(21.5),
+				RelativeHumidityPercent: proto.Float64(40.25),
+				PressurePascals:         proto.Float64(101320),
+				BatteryVolts:            proto.Float64(2.9),
+				RssiDbm:                 proto.Int32(-70),
+				DataFormat:              5,
+				RawPayload:              []byte{0x99, 0x04, 0x05},
+			},
+			want: &ruuvipb.RuuviStreamDataRequest{
+				Device:      "Kitchen",
+				MacAddress:  "aa:bb:cc:dd:ee:ff",
+				Temperature: 21.5,
+				Humidity:    40.25,
+				Pressure:    101320,
+				BatterVolts: 2.9,
+				Rssi:        -70,
+				Timestamp:   ts,
+			},
+		},
+		{
+			name: "missing readings",
+			m: &ruuviv2pb.Measurement{
+				Device:             "Kitchen",
+				Timestamp:          ts,
+				TemperatureCelsius: proto.Float64(21.5),
+				PressurePascals:    proto.Float64(101320),
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing readings from raw payload",
+			m: &ruuviv2pb.Measurement{
+				Device:             "Kitchen",
+				Timestamp:          ts,
+				TemperatureCelsius: proto.Float64(21.5),
+				DataFormat:         5,
+				RawPayload:         rawPayload,
+			},
+			want: &ruuvipb.RuuviStreamDataRequest{
+				Device:      "Kitchen",
+				Temperature: 21.5,
+				Humidity:    53.49,
+				Pressure:    100044,
+				BatterVolts: 2.977,
+				Timestamp:   ts,
+			},
+		},
+		{
+			name: "damaged raw payload",
+			m: &ruuviv2pb.Measurement{
+				Device:     "Kitchen",
+				Timestamp:  ts,
+				DataFormat: 5,
+				RawPayload: rawPayload[:10],
+			},
+			wantErr: true,
+		},
+		{
+			name: "raw payload of unknown format",
+			m: &ruuviv2pb.Measurement{
+				Device:     "Kitchen",
+				Timestamp:  ts,
+				DataFormat: 6,
+				RawPayload: rawPayload,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := measurementFromV2(tt.m)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("measurementFromV2() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !proto.Equal(got, tt.want) {
+				t.Errorf("measurementFromV2() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlottingServer_SendBatchesV2(t *testing.T) {
+	server, conn := startTestServer(t)
+	client := ruuviv2pb.NewRuuviServiceClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.SendBatches(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	complete := func(ts *timestamppb.Timestamp) *ruuviv2pb.Measurement {
+		return &ruuviv2pb.Measurement{
+			Device:                  "Kitchen",
+			Timestamp:               ts,
+			TemperatureCelsius:      proto.Float64(21.5),
+			RelativeHumidityPercent: proto.Float64(40),
+			PressurePascals:         proto.Float64(101320),
+			BatteryVolts:            proto.Float64(2.9),
+		}
+	}
+	req := &ruuviv2pb.SendBatchesRequest{
+		Collector: &ruuviv2pb.CollectorMetadata{
+			Name:     "livingroom-pi",
+			Session:  "abc",
+			Hostname: "pi.local",
+			Adapter:  "hci0",
+		},
+		BatchId: 1,
+		Measurements: []*ruuviv2pb.Measurement{
+			{Device: "Kitchen", Timestamp: timestamppb.Now()}, // Readings v1 can't represent
+			complete(timestamppb.Now()),
+			complete(nil), // Refused when stored
+		},
+	}
+
+	// Second send is a retry of the same batch
+	for range 2 {
+		if err = stream.Send(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	first, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rejected := []uint32{}
+	for _, r := range first.GetRejections() {
+		rejected = append(rejected, r.GetIndex())
+	}
+	if first.GetAccepted() != 1 || first.GetRejected() != 2 || !slices.Equal(rejected, []uint32{0, 2}) {
+		t.Errorf("SendBatches() first ack = %v, want 1 accepted and 0 and 2 rejected", first)
+	}
+	second, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !second.GetDuplicate() {
+		t.Errorf("SendBatches() second ack = %v, want duplicate", second)
+	}
+
+	stored, err := server.measureData.Range(nil, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 1 || stored[0].GetPressure() != 101320 {
+		t.Errorf("stored = %v, want one measurement with pressure in pascals", stored)
+	}
+	collectors := server.collectors.list()
+	if len(collectors) != 1 || collectors[0].Metadata.GetHostname() != "pi.local" {
+		t.Errorf("collectors = %v, want livingroom-pi with its metadata", collectors)
+	}
+}
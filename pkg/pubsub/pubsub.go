@@ -0,0 +1,149 @@
+package pubsub
+
+import (
+	"errors"
+	"sync"
+
+	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+)
+
+var (
+	// ErrSlowConsumer is set when a subscriber couldn't keep up with the published measurements
+	ErrSlowConsumer = errors.New("slow consumer")
+	// ErrHubClosed is set when the hub was closed while subscription was active
+	ErrHubClosed = errors.New("hub closed")
+)
+
+// Filter decides whether a measurement is delivered to the subscriber
+type Filter func(*ruuvipb.RuuviStreamDataRequest) bool
+
+type Subscription struct {
+	hub    *Hub
+	ch     chan *ruuvipb.RuuviStreamDataRequest
+	filter Filter
+	err    error
+}
+
+// Messages returns a channel for the subscribed measurements. Channel is closed when
+// subscription ends and the reason can be examined with Err.
+func (s *Subscription) Messages() <-chan *ruuvipb.RuuviStreamDataRequest {
+	return s.ch
+}
+
+// Err returns the reason why the subscription was ended or nil if the subscriber
+// closed it or it's still active.
+func (s *Subscription) Err() error {
+	s.hub.mu.RLock()
+	defer s.hub.mu.RUnlock()
+	return s.err
+}
+
+// Close ends the subscription. It's safe to call Close several times.
+func (s *Subscription) Close() {
+	s.hub.remove(s, nil)
+}
+
+// Hub fans out published measurements to all the subscribers.
+// Publishing never blocks, a subscriber whose buffer is full is
+// dropped with ErrSlowConsumer and is expected to resubscribe.
+type Hub struct {
+	mu         sync.RWMutex
+	subs       map[*Subscription]struct{}
+	bufferSize int
+	closed     bool
+}
+
+type HubOption func(*Hub)
+
+func WithBufferSize(size int) HubOption {
+	return func(h *Hub) {
+		h.bufferSize = size
+	}
+}
+
+func NewHub(opts ...HubOption) *Hub {
+	h := &Hub{
+		subs:       map[*Subscription]struct{}{},
+		bufferSize: 256,
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+// Subscribe registers a new subscriber. Nil filter matches every measurement.
+func (h *Hub) Subscribe(filter Filter) *Subscription {
+	s := &Subscription{
+		hub:    h,
+		ch:     make(chan *ruuvipb.RuuviStreamDataRequest, h.bufferSize),
+		filter: filter,
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		s.err = ErrHubClosed
+		close(s.ch)
+		return s
+	}
+	h.subs[s] = struct{}{}
+
+	return s
+}
+
+// Publish delivers the measurement to all matching subscribers
+func (h *Hub) Publish(msg *ruuvipb.RuuviStreamDataRequest) {
+	var slow []*Subscription
+
+	h.mu.RLock()
+	for s := range h.subs {
+		if s.filter != nil && !s.filter(msg) {
+			continue
+		}
+		select {
+		case s.ch <- msg:
+		default:
+			slow = append(slow, s)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, s := range slow {
+		h.remove(s, ErrSlowConsumer)
+	}
+}
+
+// Len returns the count of active subscribers
+func (h *Hub) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subs)
+}
+
+// Close ends all the subscriptions with ErrHubClosed
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for s := range h.subs {
+		s.err = ErrHubClosed
+		delete(h.subs, s)
+		close(s.ch)
+	}
+}
+
+func (h *Hub) remove(s *Subscription, reason error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, found := h.subs[s]; !found {
+		return
+	}
+	s.err = reason
+	delete(h.subs, s)
+	close(s.ch)
+}
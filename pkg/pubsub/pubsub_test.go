@@ -0,0 +1,78 @@
+package pubsub
+
+import (
+	"errors"
+	"testing"
+
+	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+)
+
+func TestHub_Publish(t *testing.T) {
+	h := NewHub(WithBufferSize(4))
+
+	all := h.Subscribe(nil)
+	kitchen := h.Subscribe(func(m *ruuvipb.RuuviStreamDataRequest) bool {
+		return m.GetDevice() == "Kitchen"
+	})
+
+	for _, dev := range []string{"Kitchen", "Balcony", "Kitchen"} {
+		h.Publish(&ruuvipb.RuuviStreamDataRequest{Device: dev})
+	}
+
+	if got := len(all.Messages()); got != 3 {
+		t.Errorf("Publish() unfiltered subscriber got %d messages, want 3", got)
+	}
+	if got := len(kitchen.Messages()); got != 2 {
+		t.Errorf("Publish() filtered subscriber got %d messages, want 2", got)
+	}
+
+	kitchen.Close()
+	kitchen.Close()
+	if h.Len() != 1 {
+		t.Errorf("Len() = %d after close, want 1", h.Len())
+	}
+	if err := kitchen.Err(); err != nil {
+		t.Errorf("Err() = %v after close, want nil", err)
+	}
+}
+
+func TestHub_SlowConsumer(t *testing.T) {
+	h := NewHub(WithBufferSize(2))
+
+	slow := h.Subscribe(nil)
+	for range 3 {
+		h.Publish(&ruuvipb.RuuviStreamDataRequest{Device: "Kitchen"})
+	}
+
+	received := 0
+	for range slow.Messages() {
+		received++
+	}
+	if received != 2 {
+		t.Errorf("slow consumer received %d messages, want 2", received)
+	}
+	if !errors.Is(slow.Err(), ErrSlowConsumer) {
+		t.Errorf("Err() = %v, want %v", slow.Err(), ErrSlowConsumer)
+	}
+	if h.Len() != 0 {
+		t.Errorf("Len() = %d, want 0", h.Len())
+	}
+}
+
+func TestHub_Close(t *testing.T) {
+	h := NewHub()
+	sub := h.Subscribe(nil)
+	h.Close()
+
+	if _, ok := <-sub.Messages(); ok {
+		t.Error("Messages() channel should be closed")
+	}
+	if !errors.Is(sub.Err(), ErrHubClosed) {
+		t.Errorf("Err() = %v, want %v", sub.Err(), ErrHubClosed)
+	}
+
+	late := h.Subscribe(nil)
+	if !errors.Is(late.Err(), ErrHubClosed) {
+		t.Errorf("Err() = %v for subscription after close, want %v", late.Err(), ErrHubClosed)
+	}
+}
@@ -0,0 +1,160 @@
+// Package ratelimit limits events with token buckets, both per key and in total.
+// Rate is refilled continuously, hence there are no windows to align bursts to.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// bucket is a token bucket refilled continuously at the limiter's rate
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func (b *bucket) refill(now time.Time, rate, burst float64) {
+	b.tokens = min(burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+}
+
+// full tells whether the bucket would be full by now, when it's no different from a new one
+func (b *bucket) full(now time.Time, rate, burst float64) bool {
+	return b.tokens+now.Sub(b.last).Seconds()*rate >= burst
+}
+
+// allows tells whether n tokens can be taken. Requests larger than the burst
+// are allowed with a full bucket, otherwise they would never pass.
+func (b *bucket) allows(n, burst float64) bool {
+	return b.tokens >= min(n, burst)
+}
+
+// DefaultMaxKeys is how many keys are tracked separately by default
+const DefaultMaxKeys = 1024
+
+// Limiter limits events per key, e.g. measurements per collector, and in total.
+// Keys whose buckets have refilled are forgotten, and keys beyond the maximum
+// share a single bucket, so that inventing keys doesn't grow memory or get more
+// events through.
+type Limiter struct {
+	mu          sync.Mutex
+	rate        float64
+	burst       float64
+	buckets     map[string]*bucket
+	overflow    *bucket
+	maxKeys     int
+	swept       time.Time
+	global      *bucket
+	globalRate  float64
+	globalBurst float64
+	now         func() time.Time
+}
+
+type Option func(*Limiter)
+
+// WithGlobal limits the total rate of all keys together
+func WithGlobal(rate float64, burst int) Option {
+	return func(l *Limiter) {
+		l.globalRate = rate
+		l.globalBurst = float64(burst)
+	}
+}
+
+// WithMaxKeys sets how many keys are tracked separately, the others share a bucket
+func WithMaxKeys(n int) Option {
+	return func(l *Limiter) {
+		l.maxKeys = n
+	}
+}
+
+// New creates a limiter allowing rate events per second with bursts of burst
+// events for each key. Zero rate leaves keys unlimited.
+func New(rate float64, burst int, opts ...Option) *Limiter {
+	l := &Limiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: map[string]*bucket{},
+		maxKeys: DefaultMaxKeys,
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	l.swept = l.now()
+	if l.globalRate > 0 {
+		l.global = &bucket{tokens: l.globalBurst, last: l.now()}
+	}
+	return l
+}
+
+// AllowN tells whether n events for the key fit in the limits, and takes them
+// if so. Nothing is taken when either the key's or the global limit is exceeded.
+func (l *Limiter) AllowN(key string, n int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	events := float64(n)
+
+	var keyBucket *bucket
+	if l.rate > 0 {
+		keyBucket = l.bucket(key, now)
+		keyBucket.refill(now, l.rate, l.burst)
+		if !keyBucket.allows(events, l.burst) {
+			return false
+		}
+	}
+	if l.global != nil {
+		l.global.refill(now, l.globalRate, l.globalBurst)
+		if !l.global.allows(events, l.globalBurst) {
+			return false
+		}
+		l.global.tokens -= events
+	}
+	if keyBucket != nil {
+		keyBucket.tokens -= events
+	}
+	return true
+}
+
+// bucket returns the key's bucket, caller holds the lock
+func (l *Limiter) bucket(key string, now time.Time) *bucket {
+	if b, found := l.buckets[key]; found {
+		return b
+	}
+
+	// Sweep about as often as an empty bucket refills
+	if now.Sub(l.swept).Seconds()*l.rate >= l.burst || len(l.buckets) >= l.maxKeys {
+		l.sweep(now)
+	}
+	if len(l.buckets) >= l.maxKeys {
+		if l.overflow == nil {
+			l.overflow = &bucket{tokens: l.burst, last: now}
+		}
+		return l.overflow
+	}
+
+	b := &bucket{tokens: l.burst, last: now}
+	l.buckets[key] = b
+	return b
+}
+
+// sweep forgets the keys whose buckets have refilled, caller holds the lock
+func (l *Limiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if b.full(now, l.rate, l.burst) {
+			delete(l.buckets, key)
+		}
+	}
+	if l.overflow != nil && l.overflow.full(now, l.rate, l.burst) {
+		l.overflow = nil
+	}
+	l.swept = now
+}
+
+// Keys returns how many keys are tracked separately
+func (l *Limiter) Keys() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
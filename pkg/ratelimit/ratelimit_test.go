@@ -0,0 +1,124 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newTestLimiter(rate float64, burst int, opts ...Option) (*Limiter, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)}
+	l := New(rate, burst, opts...)
+	l.now = clock.Now
+	l.swept = clock.now
+	if l.global != nil {
+		l.global.last = clock.now
+	}
+	return l, clock
+}
+
+func TestLimiter_AllowN(t *testing.T) {
+	l, clock := newTestLimiter(1, 10)
+
+	if !l.AllowN("kitchen-pi", 10) {
+		t.Fatal("burst wasn't allowed")
+	}
+	if l.AllowN("kitchen-pi", 1) {
+		t.Error("allowed over the burst")
+	}
+	if !l.AllowN("garage-pi", 5) {
+		t.Error("other collector was limited")
+	}
+
+	clock.now = clock.now.Add(3 * time.Second)
+	if !l.AllowN("kitchen-pi", 3) {
+		t.Error("refilled tokens weren't allowed")
+	}
+	if l.AllowN("kitchen-pi", 1) {
+		t.Error("allowed more than refilled")
+	}
+
+	// Larger than burst passes only with a full bucket
+	clock.now = clock.now.Add(time.Hour)
+	if !l.AllowN("kitchen-pi", 50) {
+		t.Error("batch over the burst wasn't allowed with a full bucket")
+	}
+	clock.now = clock.now.Add(20 * time.Second)
+	if l.AllowN("kitchen-pi", 1) {
+		t.Error("allowed before the debt was paid")
+	}
+}
+
+func TestLimiter_global(t *testing.T) {
+	l, clock := newTestLimiter(0, 0, WithGlobal(1, 10))
+
+	if !l.AllowN("kitchen-pi", 6) {
+		t.Fatal("within global burst wasn't allowed")
+	}
+	if l.AllowN("garage-pi", 6) {
+		t.Error("allowed over the global burst")
+	}
+	clock.now = clock.now.Add(2 * time.Second)
+	if !l.AllowN("garage-pi", 6) {
+		t.Error("refilled global tokens weren't allowed")
+	}
+}
+
+func TestLimiter_globalDeniesWithoutTaking(t *testing.T) {
+	l, _ := newTestLimiter(1, 10, WithGlobal(1, 5))
+
+	if !l.AllowN("kitchen-pi", 5) {
+		t.Fatal("within limits wasn't allowed")
+	}
+	if l.AllowN("kitchen-pi", 1) {
+		t.Fatal("allowed over the global burst")
+	}
+	// Collector's own bucket must still have the tokens global limit denied
+	if tokens := l.buckets["kitchen-pi"].tokens; tokens != 5 {
+		t.Errorf("collector tokens = %v, want 5", tokens)
+	}
+}
+
+func TestLimiter_forgetsRefilled(t *testing.T) {
+	l, clock := newTestLimiter(1, 10)
+
+	for _, key := range []string{"kitchen-pi", "garage-pi", "attic-pi"} {
+		l.AllowN(key, 5)
+	}
+	l.AllowN("balcony-pi", 50) // In debt for 40 seconds more than the others
+
+	clock.now = clock.now.Add(10 * time.Second)
+	if !l.AllowN("new-pi", 1) {
+		t.Fatal("new key wasn't allowed")
+	}
+	// Refilled ones are forgotten, the one in debt and the new one are not
+	if keys := l.Keys(); keys != 2 {
+		t.Errorf("Keys() = %d, want 2", keys)
+	}
+	if l.AllowN("balcony-pi", 1) {
+		t.Error("forgot the debt")
+	}
+}
+
+func TestLimiter_maxKeys(t *testing.T) {
+	l, _ := newTestLimiter(1, 10, WithMaxKeys(2))
+
+	l.AllowN("kitchen-pi", 1)
+	l.AllowN("garage-pi", 1)
+	// Rotating names share the overflow bucket instead of getting a burst each
+	for i, key := range []string{"a", "b", "c"} {
+		if got, want := l.AllowN(key, 5), i < 2; got != want {
+			t.Errorf("AllowN(%q) = %v, want %v", key, got, want)
+		}
+	}
+	if keys := l.Keys(); keys != 2 {
+		t.Errorf("Keys() = %d, want 2", keys)
+	}
+}
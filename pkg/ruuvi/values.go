@@ -3,11 +3,16 @@ package ruuvi
 import (
 	"bufio"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"weezel/ruuvigraph/pkg/logging"
 )
 
+var logger *slog.Logger = logging.NewColorLogHandler()
+
 // ReadAliases reads Ruuvitag aliases into memory for human friendly name mapping
 func ReadAliases(filename string) (map[string]string, error) {
 	file, err := os.OpenFile(filepath.Clean(filename), os.O_RDONLY, 0o600)
@@ -29,3 +34,34 @@ func ReadAliases(filename string) (map[string]string, error) {
 
 	return macNameMapping, nil
 }
+
+// ReadGroups reads device groups into memory. Each line maps a group
+// to a single member which can be either a device alias or MAC address.
+func ReadGroups(filename string) (map[string][]string, error) {
+	file, err := os.OpenFile(filepath.Clean(filename), os.O_RDONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("file open: %w", err)
+	}
+	defer file.Close()
+
+	groupMembers := map[string][]string{}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		splt := strings.Split(strings.TrimRight(scanner.Text(), "\r\t\n"), "|")
+		if len(splt) != 2 {
+			logger.Warn(
+				"Skipped malformed group line",
+				slog.String("fname", filename),
+				slog.Int("line", line),
+				slog.String("text", scanner.Text()),
+			)
+			continue
+		}
+		groupMembers[splt[0]] = append(groupMembers[splt[0]], splt[1])
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read groups: %w", err)
+	}
+
+	return groupMembers, nil
+}
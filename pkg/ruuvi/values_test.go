@@ -1,6 +1,10 @@
 package ruuvi
 
 import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -46,3 +50,68 @@ func TestReadConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestReadGroups(t *testing.T) {
+	type args struct {
+		filename string
+	}
+	tests := []struct {
+		name    string
+		want    map[string][]string
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "",
+			args: args{
+				filename: "example_groups.conf",
+			},
+			want: map[string][]string{
+				"Indoors":  {"Kitchen", "Bedroom"},
+				"Outdoors": {"fc:8a:aa:bb:cc:dd"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Missing file",
+			args: args{
+				filename: "nonexistent.conf",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadGroups(tt.args.filename)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ReadGroups() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) && !tt.wantErr {
+				t.Errorf("ReadGroups() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadGroups_malformed(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "groups.conf")
+	if err := os.WriteFile(fname, []byte("Indoors|Kitchen\nmalformed\nIndoors|Bedroom|extra\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadGroups(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string][]string{"Indoors": {"Kitchen"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadGroups() = %v, want %v", got, want)
+	}
+
+	// Longer than the scanner's buffer
+	if err = os.WriteFile(fname, []byte("Indoors|"+strings.Repeat("x", 100_000)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ReadGroups(fname); err == nil {
+		t.Error("ReadGroups() succeeded reading an overlong line, want the scanner error")
+	}
+}
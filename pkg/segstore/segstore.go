@@ -0,0 +1,542 @@
+// Package segstore stores measurements in append-only, time-segmented files.
+// Writes are buffered and batched, and expired data is removed by deleting whole
+// segments, hence nothing is ever rewritten. A torn record left by a power cut
+// is truncated away when the store is opened.
+package segstore
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+	"weezel/ruuvigraph/pkg/logging"
+
+	"google.golang.org/protobuf/proto"
+)
+
+var logger *slog.Logger = logging.NewColorLogHandler()
+
+const (
+	segmentSuffix = ".seg"
+	// Length and CRC-32C of the payload
+	headerSize = 8
+	// Measurements are small, anything larger is garbage from a torn write
+	maxRecordSize = 64 << 10
+)
+
+var (
+	crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+	errCorruptRecord = errors.New("corrupt record")
+	errFileName      = errors.New("invalid file name")
+	ErrClosed        = errors.New("store closed")
+)
+
+// SyncPolicy decides when written data is fsynced to the disk
+type SyncPolicy int
+
+const (
+	// SyncInterval fsyncs when the buffer is flushed periodically
+	SyncInterval SyncPolicy = iota
+	// SyncAlways flushes and fsyncs every append
+	SyncAlways
+	// SyncNone leaves syncing to the operating system
+	SyncNone
+)
+
+// ParseSyncPolicy parses the policy names used in flags: interval, always or none
+func ParseSyncPolicy(policy string) (SyncPolicy, error) {
+	switch policy {
+	case "interval":
+		return SyncInterval, nil
+	case "always":
+		return SyncAlways, nil
+	case "none":
+		return SyncNone, nil
+	default:
+		return 0, fmt.Errorf("unknown sync policy %q", policy)
+	}
+}
+
+// segment is a file holding measurements appended during its period
+type segment struct {
+	path  string
+	start time.Time
+	// Latest measurement timestamp, segment expires after it
+	latest time.Time
+}
+
+type Store struct {
+	dir             string
+	segmentDuration time.Duration
+	flushInterval   time.Duration
+	bufferSize      int
+	syncPolicy      SyncPolicy
+	now             func() time.Time
+
+	mu       sync.Mutex
+	segments []*segment
+	active   *os.File
+	writer   *bufio.Writer
+	closed   bool
+
+	quit chan struct{}
+	done chan struct{}
+}
+
+type Option func(s *Store)
+
+// WithSegmentDuration sets how long a segment is appended to before starting a new one
+func WithSegmentDuration(d time.Duration) Option {
+	return func(s *Store) {
+		s.segmentDuration = d
+	}
+}
+
+// WithFlushInterval sets how often buffered writes are flushed to the file
+func WithFlushInterval(d time.Duration) Option {
+	return func(s *Store) {
+		s.flushInterval = d
+	}
+}
+
+// WithBufferSize sets the write buffer size, full buffer is flushed regardless of the interval
+func WithBufferSize(size int) Option {
+	return func(s *Store) {
+		s.bufferSize = size
+	}
+}
+
+func WithSyncPolicy(policy SyncPolicy) Option {
+	return func(s *Store) {
+		s.syncPolicy = policy
+	}
+}
+
+// Open opens the store in the directory, creating it if needed. Existing
+// segments are checked and torn records at their ends are truncated.
+func Open(dir string, opts ...Option) (*Store, error) {
+	s := &Store{
+		dir:             dir,
+		segmentDuration: 6 * time.Hour,
+		flushInterval:   5 * time.Second,
+		bufferSize:      64 << 10,
+		syncPolicy:      SyncInterval,
+		now:             time.Now,
+		quit:            make(chan struct{}),
+		done:            make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("create directory: %w", err)
+	}
+	if err := s.recover(); err != nil {
+		return nil, err
+	}
+
+	go s.flushLoop()
+
+	return s, nil
+}
+
+func segmentPath(dir string, start time.Time) string {
+	return filepath.Join(dir, strconv.FormatInt(start.UnixNano(), 10)+segmentSuffix)
+}
+
+// recover reads all segments to find their latest timestamps and truncates
+// what follows the last intact record
+func (s *Store) recover() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("read directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		nanos, parseErr := strconv.ParseInt(strings.TrimSuffix(name, segmentSuffix), 10, 64)
+		if parseErr != nil {
+			continue
+		}
+
+		seg := &segment{
+			path:  filepath.Join(s.dir, name),
+			start: time.Unix(0, nanos),
+		}
+		if err = s.recoverSegment(seg); err != nil {
+			return err
+		}
+		s.segments = append(s.segments, seg)
+	}
+	slices.SortFunc(s.segments, func(a, b *segment) int {
+		return a.start.Compare(b.start)
+	})
+
+	return nil
+}
+
+func (s *Store) recoverSegment(seg *segment) error {
+	f, err := os.OpenFile(seg.path, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("open segment: %w", err)
+	}
+	defer f.Close()
+
+	valid, readErr := readRecords(f, func(m *ruuvipb.RuuviStreamDataRequest) {
+		if ts := m.GetTimestamp().AsTime(); ts.After(seg.latest) {
+			seg.latest = ts
+		}
+	})
+	if readErr == nil {
+		return nil
+	}
+
+	logger.Warn(
+		"Truncating damaged segment",
+		slog.String("segment", seg.path),
+		slog.Int64("valid_bytes", valid),
+		slog.Any("error", readErr),
+	)
+	if err = f.Truncate(valid); err != nil {
+		return fmt.Errorf("truncate segment: %w", err)
+	}
+	if err = f.Sync(); err != nil {
+		return fmt.Errorf("sync segment: %w", err)
+	}
+	return nil
+}
+
+// readRecords reads records until the end of the file or a damaged record.
+// Returns the size of the intact records and why reading stopped early.
+func readRecords(r io.Reader, fn func(*ruuvipb.RuuviStreamDataRequest)) (int64, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, headerSize)
+	var valid int64
+
+	for {
+		if _, err := io.ReadFull(br, header); err != nil {
+			if errors.Is(err, io.EOF) {
+				return valid, nil
+			}
+			return valid, fmt.Errorf("read header: %w", err)
+		}
+		size := binary.LittleEndian.Uint32(header[:4])
+		if size > maxRecordSize {
+			return valid, fmt.Errorf("record of %d bytes: %w", size, errCorruptRecord)
+		}
+
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(br, payload); err != nil {
+			return valid, fmt.Errorf("read payload: %w", err)
+		}
+		if crc32.Checksum(payload, crcTable) != binary.LittleEndian.Uint32(header[4:]) {
+			return valid, fmt.Errorf("checksum mismatch: %w", errCorruptRecord)
+		}
+
+		m := &ruuvipb.RuuviStreamDataRequest{}
+		if err := proto.Unmarshal(payload, m); err != nil {
+			return valid, fmt.Errorf("unmarshal: %w", errors.Join(errCorruptRecord, err))
+		}
+		fn(m)
+		valid += int64(headerSize + size)
+	}
+}
+
+// rotate starts a new segment when there's none or the active one is full of time
+func (s *Store) rotate(now time.Time) error {
+	if s.active != nil {
+		if now.Before(s.segments[len(s.segments)-1].start.Add(s.segmentDuration)) {
+			return nil
+		}
+		if err := s.closeActive(); err != nil {
+			return err
+		}
+	}
+
+	seg := &segment{
+		path:  segmentPath(s.dir, now),
+		start: now,
+	}
+	f, err := os.OpenFile(seg.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("create segment: %w", err)
+	}
+	if err = s.syncDir(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	s.segments = append(s.segments, seg)
+	s.active = f
+	s.writer = bufio.NewWriterSize(f, s.bufferSize)
+	return nil
+}
+
+func (s *Store) closeActive() error {
+	if err := s.flush(true); err != nil {
+		return err
+	}
+	if err := s.active.Close(); err != nil {
+		return fmt.Errorf("close segment: %w", err)
+	}
+	s.active = nil
+	s.writer = nil
+	return nil
+}
+
+// flush writes the buffer to the file, and fsyncs if sync is set and policy allows
+func (s *Store) flush(sync bool) error {
+	if s.writer == nil {
+		return nil
+	}
+	if err := s.writer.Flush(); err != nil {
+		return fmt.Errorf("flush segment: %w", err)
+	}
+	if sync && s.syncPolicy != SyncNone {
+		if err := s.active.Sync(); err != nil {
+			return fmt.Errorf("sync segment: %w", err)
+		}
+	}
+	return nil
+}
+
+// Append stores the measurement. Depending on the sync policy, it reaches the
+// disk immediately or on the next flush.
+func (s *Store) Append(m *ruuvipb.RuuviStreamDataRequest) error {
+	payload, err := proto.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+	header := make([]byte, headerSize)
+	binary.LittleEndian.PutUint32(header[:4], uint32(len(payload))) //nolint:gosec // Measurements are small
+	binary.LittleEndian.PutUint32(header[4:], crc32.Checksum(payload, crcTable))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+
+	if err = s.rotate(s.now()); err != nil {
+		return err
+	}
+	if _, err = s.writer.Write(header); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+	if _, err = s.writer.Write(payload); err != nil {
+		return fmt.Errorf("write payload: %w", err)
+	}
+	seg := s.segments[len(s.segments)-1]
+	if ts := m.GetTimestamp().AsTime(); ts.After(seg.latest) {
+		seg.latest = ts
+	}
+
+	if s.syncPolicy == SyncAlways {
+		return s.flush(true)
+	}
+	return nil
+}
+
+// Load reads the stored measurements which aren't older than the cutoff, in the order they were appended
+func (s *Store) Load(cutoff time.Time) ([]*ruuvipb.RuuviStreamDataRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.flush(false); err != nil {
+		return nil, err
+	}
+
+	measurements := []*ruuvipb.RuuviStreamDataRequest{}
+	for _, seg := range s.segments {
+		if seg.latest.Before(cutoff) {
+			continue
+		}
+		if err := readSegment(seg.path, func(m *ruuvipb.RuuviStreamDataRequest) {
+			if !m.GetTimestamp().AsTime().Before(cutoff) {
+				measurements = append(measurements, m)
+			}
+		}); err != nil {
+			return measurements, err
+		}
+	}
+	return measurements, nil
+}
+
+func readSegment(path string, fn func(*ruuvipb.RuuviStreamDataRequest)) error {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return fmt.Errorf("open segment: %w", err)
+	}
+	defer f.Close()
+
+	if _, err = readRecords(f, fn); err != nil {
+		return fmt.Errorf("read segment %s: %w", path, err)
+	}
+	return nil
+}
+
+// Prune deletes the segments having only measurements older than the cutoff.
+// Returns the count of deleted segments.
+func (s *Store) Prune(cutoff time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var active *segment
+	if s.active != nil {
+		active = s.segments[len(s.segments)-1]
+	}
+
+	removed := 0
+	var errs []error
+	s.segments = slices.DeleteFunc(s.segments, func(seg *segment) bool {
+		if seg == active || !seg.latest.Before(cutoff) {
+			return false
+		}
+		if err := os.Remove(seg.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("remove segment: %w", err))
+			return false
+		}
+		removed++
+		return true
+	})
+	if removed > 0 {
+		if err := s.syncDir(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return removed, errors.Join(errs...)
+}
+
+// WriteFile replaces the named file in the store's directory with data, e.g. state
+// summarizing pruned segments. Data is written to a temporary file first, so that a
+// crash leaves either the previous or the new content. Synced unless policy is SyncNone.
+func (s *Store) WriteFile(name string, data []byte) error {
+	if err := checkFileName(name); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+
+	fpath := filepath.Join(s.dir, name)
+	tmpPath := fpath + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("create temporary file: %w", err)
+	}
+	_, err = f.Write(data)
+	if err == nil && s.syncPolicy != SyncNone {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("write temporary file: %w", err)
+	}
+	if err = os.Rename(tmpPath, fpath); err != nil {
+		return fmt.Errorf("rename temporary file: %w", err)
+	}
+	return s.syncDir()
+}
+
+// ReadFile reads the named file written with WriteFile
+func (s *Store) ReadFile(name string) ([]byte, error) {
+	if err := checkFileName(name); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, name))
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+	return data, nil
+}
+
+// checkFileName refuses names which could be taken for segments or point outside the directory
+func checkFileName(name string) error {
+	if name == "" || filepath.Base(name) != name || strings.HasSuffix(name, segmentSuffix) {
+		return fmt.Errorf("%w: %q", errFileName, name)
+	}
+	return nil
+}
+
+// syncDir syncs the directory, so that created and removed segments survive a
+// power cut. Skipped when syncing is left to the operating system.
+func (s *Store) syncDir() error {
+	if s.syncPolicy == SyncNone {
+		return nil
+	}
+	d, err := os.Open(filepath.Clean(s.dir))
+	if err != nil {
+		return fmt.Errorf("open directory: %w", err)
+	}
+	defer d.Close()
+	if err = d.Sync(); err != nil {
+		return fmt.Errorf("sync directory: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) flushLoop() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.quit:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			err := s.flush(true)
+			s.mu.Unlock()
+			if err != nil {
+				logger.Error(
+					"Failed to flush measurements",
+					slog.Any("error", err),
+				)
+			}
+		}
+	}
+}
+
+// Close flushes and syncs the buffered measurements and closes the store
+func (s *Store) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	close(s.quit)
+	<-s.done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active == nil {
+		return nil
+	}
+	return s.closeActive()
+}
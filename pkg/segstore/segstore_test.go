@@ -0,0 +1,228 @@
+package segstore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func measurement(device string, ts time.Time) *ruuvipb.RuuviStreamDataRequest {
+	return &ruuvipb.RuuviStreamDataRequest{
+		Device:      device,
+		Temperature: 21.5,
+		Pressure:    101320,
+		Timestamp:   timestamppb.New(ts),
+	}
+}
+
+func openStore(t *testing.T, dir string, opts ...Option) *Store {
+	t.Helper()
+
+	s, err := Open(dir, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func segmentFiles(t *testing.T, dir string) []string {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"+segmentSuffix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestStore_reopen(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	s := openStore(t, dir)
+	for _, device := range []string{"Kitchen", "Balcony", "Sauna"} {
+		if err := s.Append(measurement(device, now)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Append(measurement("Kitchen", now)); err == nil {
+		t.Error("Append() after Close() succeeded")
+	}
+
+	reopened := openStore(t, dir)
+	if err := reopened.Append(measurement("Garage", now)); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := reopened.Load(now.Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 4 || loaded[0].GetDevice() != "Kitchen" || loaded[3].GetDevice() != "Garage" {
+		t.Errorf("Load() = %v, want 4 measurements in append order", loaded)
+	}
+	// Recovered segments aren't appended to
+	if files := segmentFiles(t, dir); len(files) != 2 {
+		t.Errorf("segments = %v, want 2", files)
+	}
+}
+
+func TestStore_Prune(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	s := openStore(t, dir, WithSegmentDuration(time.Hour), WithSyncPolicy(SyncNone))
+	s.now = func() time.Time { return now }
+	for range 3 {
+		if err := s.Append(measurement("Kitchen", now)); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(time.Hour)
+	}
+	if files := segmentFiles(t, dir); len(files) != 3 {
+		t.Fatalf("segments = %v, want one per hour", files)
+	}
+
+	removed, err := s.Prune(now.Add(-90 * time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 {
+		t.Errorf("Prune() removed %d segments, want 2", removed)
+	}
+	// Active segment is kept even when expired
+	removed, err = s.Prune(now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 0 || len(segmentFiles(t, dir)) != 1 {
+		t.Errorf("Prune() removed the active segment")
+	}
+
+	loaded, err := s.Load(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 1 {
+		t.Errorf("Load() = %d measurements, want 1", len(loaded))
+	}
+}
+
+func TestStore_WriteFile(t *testing.T) {
+	dir := t.TempDir()
+	s := openStore(t, dir)
+
+	for _, content := range []string{"first", "second"} {
+		if err := s.WriteFile("state.json", []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Append(measurement("Kitchen", time.Now())); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"", "../state.json", "1.seg"} {
+		if err := s.WriteFile(name, nil); err == nil {
+			t.Errorf("WriteFile(%q) succeeded, want error", name)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Files aren't taken for segments
+	reopened := openStore(t, dir)
+	data, err := reopened.ReadFile("state.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "second" {
+		t.Errorf("ReadFile() = %q, want the latest content", data)
+	}
+	if loaded, _ := reopened.Load(time.Time{}); len(loaded) != 1 {
+		t.Errorf("Load() = %d measurements, want 1", len(loaded))
+	}
+	if _, err = os.Stat(filepath.Join(dir, "state.json.tmp")); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+}
+
+func TestStore_tornTail(t *testing.T) {
+	tests := []struct {
+		name       string
+		wantIntact int
+		damage     func(t *testing.T, path string)
+	}{
+		{
+			"partial record",
+			2,
+			func(t *testing.T, path string) {
+				info, err := os.Stat(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err = os.Truncate(path, info.Size()-5); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			"garbage",
+			3,
+			func(t *testing.T, path string) {
+				f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer f.Close()
+				if _, err = f.Write([]byte{0x10, 0, 0, 0, 1, 2, 3, 4, 5, 6, 7, 8}); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			now := time.Now()
+
+			// Power cut, synced on every append but never closed
+			s := openStore(t, dir, WithSyncPolicy(SyncAlways))
+			for range 3 {
+				if err := s.Append(measurement("Kitchen", now)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			files := segmentFiles(t, dir)
+			if len(files) != 1 {
+				t.Fatalf("segments = %v, want 1", files)
+			}
+			tt.damage(t, files[0])
+
+			recovered := openStore(t, dir)
+			loaded, err := recovered.Load(time.Time{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(loaded) != tt.wantIntact {
+				t.Errorf("Load() = %d measurements, want %d intact", len(loaded), tt.wantIntact)
+			}
+
+			// Truncated, so that a later append doesn't hide behind the damage
+			if err = recovered.Close(); err != nil {
+				t.Fatal(err)
+			}
+			again := openStore(t, dir)
+			if loaded, err = again.Load(time.Time{}); err != nil || len(loaded) != tt.wantIntact {
+				t.Errorf("Load() after recovery = %d measurements, %v", len(loaded), err)
+			}
+		})
+	}
+}
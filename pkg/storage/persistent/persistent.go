@@ -0,0 +1,249 @@
+// Package persistent keeps measurements in memory for queries and appends them
+// to segment files on disk, from which they're loaded on start. Rollups of pruned
+// measurements are saved next to the segments.
+package persistent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"weezel/ruuvigraph/pkg/cache"
+	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+	"weezel/ruuvigraph/pkg/logging"
+	"weezel/ruuvigraph/pkg/segstore"
+	"weezel/ruuvigraph/pkg/storage"
+)
+
+var logger *slog.Logger = logging.NewColorLogHandler()
+
+// summariesFilename is where the rollups are saved in the store's directory
+const summariesFilename = "rollups.json"
+
+type Backend struct {
+	memory *cache.Measurements
+	store  *segstore.Store
+	maxAge time.Duration
+	ticker *time.Ticker
+	once   sync.Once
+	quit   chan struct{}
+	done   chan struct{}
+}
+
+var _ storage.Backend = (*Backend)(nil)
+
+// Open opens the segment store in the directory and loads the rollups and the
+// measurements kept by memory to it. Segments are pruned periodically, once older than memory keeps any device.
+// Backend owns memory.
+func Open(dir string, memory *cache.Measurements, opts ...segstore.Option) (*Backend, error) {
+	store, err := segstore.Open(dir, opts...)
+	if err != nil {
+		memory.Stop()
+		return nil, fmt.Errorf("open store: %w", err)
+	}
+
+	b := &Backend{
+		memory: memory,
+		store:  store,
+		maxAge: memory.MaxAge(),
+		ticker: time.NewTicker(5 * time.Minute),
+		quit:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	b.load()
+	go b.run()
+
+	return b, nil
+}
+
+// load restores the rollups and the stored measurements. Segments expired while stopped
+// are read too, their measurements not summarized yet are rolled up.
+func (b *Backend) load() {
+	summaries, err := b.loadSummaries()
+	if err != nil {
+		logger.Error(
+			"Failed to load rollups",
+			slog.Any("error", err),
+		)
+	}
+	loaded, err := b.store.Load(time.Time{})
+	if err != nil {
+		// Whatever was read before the error is still usable
+		logger.Error(
+			"Failed to load stored measurements",
+			slog.Any("error", err),
+		)
+	}
+	restored, err := b.memory.Restore(summaries, loaded...)
+	if err != nil {
+		logger.Warn(
+			"Skipped invalid and summarized stored measurements",
+			slog.Any("error", err),
+		)
+	}
+	logger.Info(
+		"Loaded stored measurements",
+		slog.Int("loaded", restored),
+		slog.Int("rolled_up_devices", len(summaries)),
+	)
+}
+
+func (b *Backend) loadSummaries() ([]cache.Summaries, error) {
+	data, err := b.store.ReadFile(summariesFilename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+	summaries := []cache.Summaries{}
+	if err = json.Unmarshal(data, &summaries); err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+	return summaries, nil
+}
+
+// saveSummaries saves the rollups, which must be done before deleting segments
+// having measurements summarized in them
+func (b *Backend) saveSummaries() error {
+	data, err := json.Marshal(b.memory.Summaries())
+	if err != nil {
+		return fmt.Errorf("encode rollups: %w", err)
+	}
+	if err = b.store.WriteFile(summariesFilename, data); err != nil {
+		return fmt.Errorf("save rollups: %w", err)
+	}
+	return nil
+}
+
+func (b *Backend) run() {
+	defer close(b.done)
+	defer b.ticker.Stop()
+
+	for {
+		select {
+		case <-b.quit:
+			return
+		case <-b.ticker.C:
+			if _, err := b.Prune(time.Now().Add(-b.maxAge)); err != nil {
+				logger.Error(
+					"Failed to prune stored measurements",
+					slog.Any("error", err),
+				)
+			}
+		}
+	}
+}
+
+// Append stores the measurements in memory and appends the ones accepted by it
+// to the segment store. Memory is the primary copy, failing to write to the disk
+// loses only history on restart, hence it's only logged.
+func (b *Backend) Append(measurements ...*ruuvipb.RuuviStreamDataRequest) error {
+	accepted, err := b.memory.AppendAccepted(measurements...)
+	for _, m := range accepted {
+		if storeErr := b.store.Append(m); storeErr != nil {
+			logger.Warn(
+				"Failed to store measurement on disk",
+				slog.Any("error", storeErr),
+			)
+			break
+		}
+	}
+
+	return err //nolint:wrapcheck // Tells which measurements were refused and why
+}
+
+func (b *Backend) Range(devices []string, from, until time.Time) ([]*ruuvipb.RuuviStreamDataRequest, error) {
+	measurements, err := b.memory.Range(devices, from, until)
+	if err != nil {
+		return nil, fmt.Errorf("memory: %w", err)
+	}
+	return measurements, nil
+}
+
+func (b *Backend) Latest(devices []string) ([]*ruuvipb.RuuviStreamDataRequest, error) {
+	measurements, err := b.memory.Latest(devices)
+	if err != nil {
+		return nil, fmt.Errorf("memory: %w", err)
+	}
+	return measurements, nil
+}
+
+// Downsampled returns the measurements in the finest resolution kept in memory,
+// see cache.Measurements.Downsampled
+func (b *Backend) Downsampled(
+	devices []string,
+	from, until time.Time,
+) ([]*ruuvipb.RuuviStreamDataRequest, error) {
+	measurements, err := b.memory.Downsampled(devices, from, until)
+	if err != nil {
+		return nil, fmt.Errorf("memory: %w", err)
+	}
+	return measurements, nil
+}
+
+// Prune removes measurements older than the cutoff from memory, saves the rollups
+// and deletes the segments having only such. Returns the count removed from memory.
+func (b *Backend) Prune(cutoff time.Time) (int, error) {
+	removed, err := b.memory.Prune(cutoff)
+	if err != nil {
+		return removed, fmt.Errorf("memory: %w", err)
+	}
+	if err = b.saveSummaries(); err != nil {
+		return removed, err
+	}
+	segments, err := b.store.Prune(cutoff)
+	if err != nil {
+		return removed, fmt.Errorf("store: %w", err)
+	}
+	if segments > 0 {
+		logger.Info(
+			"Deleted expired segments",
+			slog.Int("segments", segments),
+		)
+	}
+	return removed, nil
+}
+
+func (b *Backend) Stats() storage.Stats {
+	return b.memory.Stats()
+}
+
+// Running reports whether the pruning loops are still running
+func (b *Backend) Running() bool {
+	select {
+	case <-b.done:
+		return false
+	default:
+		return b.memory.Running()
+	}
+}
+
+// Close stops pruning, saves the rollups and flushes the buffered measurements to the
+// disk, only once. Closing again returns nil.
+func (b *Backend) Close() error {
+	var err error
+	b.once.Do(func() {
+		close(b.quit)
+		<-b.done
+
+		if memErr := b.memory.Close(); memErr != nil {
+			err = fmt.Errorf("memory: %w", memErr)
+			return
+		}
+		if saveErr := b.saveSummaries(); saveErr != nil {
+			logger.Error(
+				"Failed to save rollups",
+				slog.Any("error", saveErr),
+			)
+		}
+		if storeErr := b.store.Close(); storeErr != nil {
+			err = fmt.Errorf("store: %w", storeErr)
+		}
+	})
+	return err
+}
@@ -0,0 +1,111 @@
+package persistent
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"weezel/ruuvigraph/pkg/cache"
+	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+	"weezel/ruuvigraph/pkg/storage"
+	"weezel/ruuvigraph/pkg/storage/storagetest"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestBackend_conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Backend {
+		b, err := Open(t.TempDir(), cache.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	})
+}
+
+func TestBackend_reopen(t *testing.T) {
+	dir := t.TempDir()
+	b, err := Open(dir, cache.New(cache.WithMaxMeasureAge(time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ts := range []time.Time{time.Now().Add(-30 * time.Minute), time.Now()} {
+		err = b.Append(&ruuvipb.RuuviStreamDataRequest{Device: "Kitchen", Timestamp: timestamppb.New(ts)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Restarted later with shorter retention, older one has expired meanwhile
+	reopened, err := Open(dir, cache.New(cache.WithMaxMeasureAge(15*time.Minute)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = reopened.Close() })
+	if stats := reopened.Stats(); stats.Measurements != 1 {
+		t.Errorf("%d measurements after reopening, want the unexpired one", stats.Measurements)
+	}
+}
+
+func TestBackend_concurrentClose(t *testing.T) {
+	b, err := Open(t.TempDir(), cache.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if closeErr := b.Close(); closeErr != nil {
+				t.Error(closeErr)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestBackend_rollupsReopen(t *testing.T) {
+	dir := t.TempDir()
+	b, err := Open(dir, cache.New(cache.WithMaxMeasureAge(time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ts := range []time.Time{time.Now().Add(-30 * time.Minute), time.Now()} {
+		err = b.Append(&ruuvipb.RuuviStreamDataRequest{Device: "Kitchen", Timestamp: timestamppb.New(ts)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each restart has shorter retention, the older measurement expires while stopped
+	for i, maxAge := range []time.Duration{15 * time.Minute, 10 * time.Minute} {
+		reopened, openErr := Open(dir, cache.New(cache.WithMaxMeasureAge(maxAge)))
+		if openErr != nil {
+			t.Fatal(openErr)
+		}
+		rollups, rollupErr := reopened.memory.Rollups(nil, time.Time{}, time.Time{}, time.Hour)
+		if rollupErr != nil {
+			t.Fatal(rollupErr)
+		}
+		count := 0
+		for _, r := range rollups {
+			count += r.Count()
+		}
+		// Rolled up once, not again from the segment on the next restart
+		if count != 2 || reopened.Stats().Measurements != 1 {
+			t.Errorf("restart %d: %d measurements summarized and %d stored, want 2 and 1",
+				i, count, reopened.Stats().Measurements)
+		}
+		if err = reopened.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
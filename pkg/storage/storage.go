@@ -0,0 +1,93 @@
+// Package storage defines the interface of measurement storage backends. Backends
+// must pass the conformance tests in storagetest.
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+	"weezel/ruuvigraph/pkg/units"
+)
+
+var (
+	// ErrExpired is returned for measurements older than the backend keeps
+	ErrExpired = errors.New("measurement expired")
+	// ErrDuplicate is returned for measurements already stored
+	ErrDuplicate = errors.New("duplicate measurement")
+	// ErrTooLate is returned for measurements arriving too long after newer ones of the device
+	ErrTooLate = errors.New("measurement too late")
+)
+
+// Stats describes the stored measurements
+type Stats struct {
+	Measurements int
+	// Distinct pairs of device name and MAC address
+	Devices int
+	Oldest  time.Time
+	Newest  time.Time
+	// Measurements evicted to stay within a budget since start
+	Evicted int
+}
+
+type Backend interface {
+	// Append stores the measurements, which must be in canonical units. Invalid and
+	// expired ones aren't stored, the returned error tells which and why.
+	Append(measurements ...*ruuvipb.RuuviStreamDataRequest) error
+	// Range returns the measurements of the devices, or all if none is given, timestamped
+	// between from and until inclusive. Zero time leaves the corresponding end open.
+	Range(devices []string, from, until time.Time) ([]*ruuvipb.RuuviStreamDataRequest, error)
+	// Latest returns the latest measurement of each device, or of the given ones
+	Latest(devices []string) ([]*ruuvipb.RuuviStreamDataRequest, error)
+	// Prune removes measurements older than the cutoff, returns the count of removed ones
+	Prune(cutoff time.Time) (int, error)
+	Stats() Stats
+	Close() error
+}
+
+// Check tells whether the measurement can be stored by a backend keeping measurements
+// since the cutoff. Expired ones are refused on arrival rather than left to Prune, so
+// that every backend answers queries the same way regardless of when it last pruned.
+func Check(m *ruuvipb.RuuviStreamDataRequest, cutoff time.Time) error {
+	if err := units.Validate(m); err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+	if m.GetTimestamp().AsTime().Before(cutoff) {
+		return ErrExpired
+	}
+	return nil
+}
+
+// Matches tells whether the measurement is from one of the devices, matched by name or
+// MAC address. Empty devices matches all.
+func Matches(m *ruuvipb.RuuviStreamDataRequest, devices []string) bool {
+	return len(devices) == 0 ||
+		slices.Contains(devices, m.GetDevice()) ||
+		slices.Contains(devices, m.GetMacAddress())
+}
+
+// InRange tells whether the timestamp is between from and until, zero times are open ends
+func InRange(ts, from, until time.Time) bool {
+	return (from.IsZero() || !ts.Before(from)) && (until.IsZero() || !ts.After(until))
+}
+
+// LatestOf returns the latest of the measurements for each device
+func LatestOf(measurements []*ruuvipb.RuuviStreamDataRequest) []*ruuvipb.RuuviStreamDataRequest {
+	latest := map[string]*ruuvipb.RuuviStreamDataRequest{}
+	for _, m := range measurements {
+		key := DeviceKey(m)
+		prev, found := latest[key]
+		if !found || prev.GetTimestamp().AsTime().Before(m.GetTimestamp().AsTime()) {
+			latest[key] = m
+		}
+	}
+	return slices.Collect(maps.Values(latest))
+}
+
+// DeviceKey identifies a device by both name and MAC address
+func DeviceKey(m *ruuvipb.RuuviStreamDataRequest) string {
+	return m.GetMacAddress() + "|" + m.GetDevice()
+}
@@ -0,0 +1,229 @@
+// Package storagetest has the conformance tests every storage backend must pass
+package storagetest
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+	"weezel/ruuvigraph/pkg/storage"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// NewBackend returns an empty backend, which keeps measurements for at least
+// a day but less than a year. Test closes it.
+type NewBackend func(t *testing.T) storage.Backend
+
+func measurement(device, mac string, ts time.Time) *ruuvipb.RuuviStreamDataRequest {
+	return &ruuvipb.RuuviStreamDataRequest{
+		Device:      device,
+		MacAddress:  mac,
+		Temperature: 21.5,
+		Humidity:    40,
+		Pressure:    101320,
+		BatterVolts: 2.9,
+		Timestamp:   timestamppb.New(ts),
+	}
+}
+
+// Run runs the conformance tests against backends created with newBackend
+func Run(t *testing.T, newBackend NewBackend) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		test func(t *testing.T, b storage.Backend)
+	}{
+		{"Append", testAppend},
+		{"Range", testRange},
+		{"Latest", testLatest},
+		{"Prune", testPrune},
+		{"Stats", testStats},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBackend(t)
+			tt.test(t, b)
+			if err := b.Close(); err != nil {
+				t.Errorf("Close() error = %v", err)
+			}
+		})
+	}
+}
+
+// now is rounded, so that timestamps survive any encoding exactly
+var now = time.Now().Truncate(time.Second)
+
+func devices(ms []*ruuvipb.RuuviStreamDataRequest) []string {
+	names := make([]string, 0, len(ms))
+	for _, m := range ms {
+		names = append(names, m.GetDevice())
+	}
+	slices.Sort(names)
+	return names
+}
+
+func rangeOf(t *testing.T, b storage.Backend, devs []string, from, until time.Time) []string {
+	t.Helper()
+
+	ms, err := b.Range(devs, from, until)
+	if err != nil {
+		t.Fatalf("Range() error = %v", err)
+	}
+	return devices(ms)
+}
+
+func testAppend(t *testing.T, b storage.Backend) {
+	err := b.Append(
+		measurement("Kitchen", "aa:aa:aa:aa:aa:aa", now),
+		&ruuvipb.RuuviStreamDataRequest{Device: "Wet", Humidity: 150, Timestamp: timestamppb.New(now)},
+		measurement("Ancient", "bb:bb:bb:bb:bb:bb", now.AddDate(-2, 0, 0)),
+		measurement("Balcony", "cc:cc:cc:cc:cc:cc", now),
+	)
+	if err == nil {
+		t.Error("Append() accepted invalid and expired measurements")
+	}
+	if !errors.Is(err, storage.ErrExpired) {
+		t.Errorf("Append() error = %v, want ErrExpired among them", err)
+	}
+	if !strings.Contains(err.Error(), "measurement 1") || !strings.Contains(err.Error(), "measurement 2") {
+		t.Errorf("Append() error = %v, want indexes of the rejected measurements", err)
+	}
+
+	if got := rangeOf(t, b, nil, time.Time{}, time.Time{}); !slices.Equal(got, []string{"Balcony", "Kitchen"}) {
+		t.Errorf("stored %v, want the valid ones", got)
+	}
+
+	if err = b.Append(measurement("Sauna", "dd:dd:dd:dd:dd:dd", now)); err != nil {
+		t.Errorf("Append() error = %v", err)
+	}
+	if got := b.Stats().Measurements; got != 3 {
+		t.Errorf("stored %d measurements, want 3", got)
+	}
+}
+
+func testRange(t *testing.T, b storage.Backend) {
+	err := b.Append(
+		measurement("Kitchen", "aa:aa:aa:aa:aa:aa", now.Add(-3*time.Hour)),
+		measurement("Kitchen", "aa:aa:aa:aa:aa:aa", now.Add(-2*time.Hour)),
+		measurement("Kitchen", "aa:aa:aa:aa:aa:aa", now.Add(-time.Hour)),
+		measurement("Balcony", "bb:bb:bb:bb:bb:bb", now.Add(-2*time.Hour)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var open time.Time
+	twoHoursAgo := now.Add(-2 * time.Hour)
+	aroundTwoHoursAgo := []time.Time{twoHoursAgo.Add(-time.Minute), twoHoursAgo.Add(time.Minute)}
+	tests := []struct {
+		name        string
+		devices     []string
+		from, until time.Time
+		wantDevices []string
+	}{
+		{"all", nil, open, open, []string{"Balcony", "Kitchen", "Kitchen", "Kitchen"}},
+		{"by name", []string{"Kitchen"}, open, open, []string{"Kitchen", "Kitchen", "Kitchen"}},
+		{"by MAC", []string{"bb:bb:bb:bb:bb:bb"}, open, open, []string{"Balcony"}},
+		{"from inclusive", nil, twoHoursAgo, open, []string{"Balcony", "Kitchen", "Kitchen"}},
+		{"until inclusive", nil, open, twoHoursAgo, []string{"Balcony", "Kitchen", "Kitchen"}},
+		{"between", []string{"Kitchen"}, aroundTwoHoursAgo[0], aroundTwoHoursAgo[1], []string{"Kitchen"}},
+		{"unknown device", []string{"Garage"}, open, open, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rangeOf(t, b, tt.devices, tt.from, tt.until); !slices.Equal(got, tt.wantDevices) {
+				t.Errorf("Range() = %v, want %v", got, tt.wantDevices)
+			}
+		})
+	}
+}
+
+func testLatest(t *testing.T, b storage.Backend) {
+	err := b.Append(
+		measurement("Kitchen", "aa:aa:aa:aa:aa:aa", now.Add(-time.Minute)),
+		measurement("Kitchen", "aa:aa:aa:aa:aa:aa", now),
+		measurement("Kitchen", "aa:aa:aa:aa:aa:aa", now.Add(-2*time.Minute)),
+		measurement("Balcony", "bb:bb:bb:bb:bb:bb", now.Add(-time.Hour)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	latest, err := b.Latest(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := devices(latest); !slices.Equal(got, []string{"Balcony", "Kitchen"}) {
+		t.Fatalf("Latest() = %v, want one per device", got)
+	}
+	for _, m := range latest {
+		if m.GetDevice() == "Kitchen" && !m.GetTimestamp().AsTime().Equal(now) {
+			t.Errorf("Latest() Kitchen at %v, want %v", m.GetTimestamp().AsTime(), now)
+		}
+	}
+
+	latest, err = b.Latest([]string{"bb:bb:bb:bb:bb:bb"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := devices(latest); !slices.Equal(got, []string{"Balcony"}) {
+		t.Errorf("Latest(Balcony) = %v", got)
+	}
+}
+
+func testPrune(t *testing.T, b storage.Backend) {
+	err := b.Append(
+		measurement("Kitchen", "aa:aa:aa:aa:aa:aa", now.Add(-3*time.Hour)),
+		measurement("Kitchen", "aa:aa:aa:aa:aa:aa", now.Add(-2*time.Hour)),
+		measurement("Kitchen", "aa:aa:aa:aa:aa:aa", now.Add(-time.Hour)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := b.Prune(now.Add(-2 * time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Errorf("Prune() removed %d, want 1", removed)
+	}
+	if got := b.Stats().Measurements; got != 2 {
+		t.Errorf("%d measurements after Prune(), want 2", got)
+	}
+	if got := b.Stats().Oldest; !got.Equal(now.Add(-2 * time.Hour)) {
+		t.Errorf("oldest after Prune() = %v, want cutoff kept", got)
+	}
+}
+
+func testStats(t *testing.T, b storage.Backend) {
+	if stats := b.Stats(); stats != (storage.Stats{}) {
+		t.Errorf("Stats() of empty backend = %+v", stats)
+	}
+
+	err := b.Append(
+		measurement("Kitchen", "aa:aa:aa:aa:aa:aa", now.Add(-time.Hour)),
+		measurement("Kitchen", "aa:aa:aa:aa:aa:aa", now),
+		// Same name on another tag is another device
+		measurement("Kitchen", "bb:bb:bb:bb:bb:bb", now.Add(-30*time.Minute)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := storage.Stats{
+		Measurements: 3,
+		Devices:      2,
+		Oldest:       now.Add(-time.Hour),
+		Newest:       now,
+	}
+	if stats := b.Stats(); stats.Measurements != want.Measurements || stats.Devices != want.Devices ||
+		!stats.Oldest.Equal(want.Oldest) || !stats.Newest.Equal(want.Newest) {
+		t.Errorf("Stats() = %+v, want %+v", stats, want)
+	}
+}
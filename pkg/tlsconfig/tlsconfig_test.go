@@ -0,0 +1,260 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	file string
+}
+
+type keyPair struct {
+	certFile string
+	keyFile  string
+}
+
+func newTestCA(t *testing.T, dir, name string) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(dir, name+".pem")
+	writePEM(t, file, "CERTIFICATE", der)
+
+	return &testCA{cert: cert, key: key, file: file}
+}
+
+func (ca *testCA) issue(t *testing.T, dir, name string, usage x509.ExtKeyUsage) keyPair {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kp := keyPair{
+		certFile: filepath.Join(dir, name+".crt"),
+		keyFile:  filepath.Join(dir, name+".key"),
+	}
+	writePEM(t, kp.certFile, "CERTIFICATE", der)
+	writePEM(t, kp.keyFile, "EC PRIVATE KEY", keyDer)
+
+	return kp
+}
+
+func writePEM(t *testing.T, fname, blockType string, der []byte) {
+	t.Helper()
+
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(fname, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type ackServer struct {
+	ruuvipb.UnimplementedRuuviServer
+}
+
+func (ackServer) StreamData(stream ruuvipb.Ruuvi_StreamDataServer) error {
+	for {
+		if _, err := stream.Recv(); err != nil {
+			if errors.Is(err, io.EOF) {
+				return stream.SendAndClose(&ruuvipb.RuuviStreamDataResponse{Message: "OK"})
+			}
+			return err
+		}
+	}
+}
+
+func startServer(t *testing.T, creds credentials.TransportCredentials) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer(grpc.Creds(creds))
+	ruuvipb.RegisterRuuviServer(server, ackServer{})
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	return listener.Addr().String()
+}
+
+func sendMeasurement(addr string, creds credentials.TransportCredentials) error {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := ruuvipb.NewRuuviClient(conn).StreamData(ctx)
+	if err != nil {
+		return err
+	}
+	if err = stream.Send(&ruuvipb.RuuviStreamDataRequest{Device: "Kitchen"}); err != nil {
+		return err
+	}
+	_, err = stream.CloseAndRecv()
+	return err
+}
+
+func TestCredentials(t *testing.T) {
+	dir := t.TempDir()
+
+	ca := newTestCA(t, dir, "ca")
+	rogueCA := newTestCA(t, dir, "rogue-ca")
+	serverPair := ca.issue(t, dir, "server", x509.ExtKeyUsageServerAuth)
+	clientPair := ca.issue(t, dir, "client", x509.ExtKeyUsageClientAuth)
+	rogueClientPair := rogueCA.issue(t, dir, "rogue-client", x509.ExtKeyUsageClientAuth)
+
+	tlsCreds, err := ServerCredentials(serverPair.certFile, serverPair.keyFile, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mtlsCreds, err := ServerCredentials(serverPair.certFile, serverPair.keyFile, ca.file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tlsAddr := startServer(t, tlsCreds)
+	mtlsAddr := startServer(t, mtlsCreds)
+
+	tests := []struct {
+		name       string
+		addr       string
+		caFile     string
+		client     keyPair
+		serverName string
+		wantErr    bool
+	}{
+		{
+			name:   "TLS without client certificate",
+			addr:   tlsAddr,
+			caFile: ca.file,
+		},
+		{
+			name:    "TLS with untrusted server certificate",
+			addr:    tlsAddr,
+			caFile:  rogueCA.file,
+			wantErr: true,
+		},
+		{
+			name:       "TLS with mismatching server name",
+			addr:       tlsAddr,
+			caFile:     ca.file,
+			serverName: "ruuvi.example.com",
+			wantErr:    true,
+		},
+		{
+			name:   "mTLS with valid client certificate",
+			addr:   mtlsAddr,
+			caFile: ca.file,
+			client: clientPair,
+		},
+		{
+			name:    "mTLS without client certificate",
+			addr:    mtlsAddr,
+			caFile:  ca.file,
+			wantErr: true,
+		},
+		{
+			name:    "mTLS with client certificate from unknown CA",
+			addr:    mtlsAddr,
+			caFile:  ca.file,
+			client:  rogueClientPair,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			creds, err := ClientCredentials(tt.caFile, tt.client.certFile, tt.client.keyFile, tt.serverName)
+			if err != nil {
+				t.Fatalf("ClientCredentials() error = %v", err)
+			}
+			err = sendMeasurement(tt.addr, creds)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("sendMeasurement() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestServerConfig_InvalidCA(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, dir, "ca")
+	serverPair := ca.issue(t, dir, "server", x509.ExtKeyUsageServerAuth)
+
+	bogusCA := filepath.Join(dir, "bogus.pem")
+	if err := os.WriteFile(bogusCA, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := ServerConfig(serverPair.certFile, serverPair.keyFile, bogusCA)
+	if !errors.Is(err, errNoCertificates) {
+		t.Errorf("ServerConfig() error = %v, want %v", err, errNoCertificates)
+	}
+}
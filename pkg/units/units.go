@@ -0,0 +1,150 @@
+// Package units defines the canonical units of the stored measurements and
+// conversions to the units shown to the user.
+//
+// Measurements are stored in pascals, degrees Celsius, relative humidity
+// percent and volts. Conversions happen only when displaying values.
+package units
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+)
+
+const (
+	// Older collectors sent pressure in decapascals, which can't be confused
+	// with pascals since RuuviTag measures from 50000 Pa upwards.
+	legacyPressureLimit = 20000
+
+	minPressure    = 30000.0
+	maxPressure    = 130000.0
+	minTemperature = -273.15
+	maxTemperature = 200.0
+	maxHumidity    = 100.0
+	maxBatteryVolt = 10.0
+
+	absoluteZeroOffset            = 273.15
+	pascalsPerInchOfMercury       = 3386.389
+	pascalsPerMillimeterOfMercury = 133.322387415
+)
+
+var ErrOutOfRange = errors.New("value out of range")
+
+// NormalizeLegacy converts pressure sent by older collectors from
+// decapascals to pascals. Measurement is modified in place.
+func NormalizeLegacy(m *ruuvipb.RuuviStreamDataRequest) {
+	if p := m.GetPressure(); p > 0 && p < legacyPressureLimit {
+		m.Pressure = p * 10
+	}
+}
+
+// Validate checks that the values are in canonical units.
+// Zero pressure means the value is missing.
+func Validate(m *ruuvipb.RuuviStreamDataRequest) error {
+	if p := m.GetPressure(); p != 0 && (p < minPressure || p > maxPressure) {
+		return fmt.Errorf("pressure %.1f Pa: %w", p, ErrOutOfRange)
+	}
+	if t := m.GetTemperature(); t < minTemperature || t > maxTemperature {
+		return fmt.Errorf("temperature %.2f °C: %w", t, ErrOutOfRange)
+	}
+	if h := m.GetHumidity(); h < 0 || h > maxHumidity {
+		return fmt.Errorf("humidity %.2f %%: %w", h, ErrOutOfRange)
+	}
+	if v := m.GetBatterVolts(); v < 0 || v > maxBatteryVolt {
+		return fmt.Errorf("battery %.3f V: %w", v, ErrOutOfRange)
+	}
+	return nil
+}
+
+type PressureUnit string
+
+const (
+	Pascal              PressureUnit = "Pa"
+	Hectopascal         PressureUnit = "hPa"
+	Kilopascal          PressureUnit = "kPa"
+	InchOfMercury       PressureUnit = "inHg"
+	MillimeterOfMercury PressureUnit = "mmHg"
+)
+
+// ParsePressureUnit accepts unit symbols case insensitively
+func ParsePressureUnit(s string) (PressureUnit, error) {
+	for _, u := range []PressureUnit{Pascal, Hectopascal, Kilopascal, InchOfMercury, MillimeterOfMercury} {
+		if strings.EqualFold(s, string(u)) {
+			return u, nil
+		}
+	}
+	return "", fmt.Errorf("unknown pressure unit %q, use Pa, hPa, kPa, inHg or mmHg", s)
+}
+
+// FromPascals converts pressure in pascals to this unit
+func (u PressureUnit) FromPascals(pa float64) float64 {
+	switch u {
+	case Hectopascal:
+		return pa / 100
+	case Kilopascal:
+		return pa / 1000
+	case InchOfMercury:
+		return pa / pascalsPerInchOfMercury
+	case MillimeterOfMercury:
+		return pa / pascalsPerMillimeterOfMercury
+	case Pascal:
+		return pa
+	}
+	return pa
+}
+
+type TemperatureUnit string
+
+const (
+	Celsius    TemperatureUnit = "°C"
+	Fahrenheit TemperatureUnit = "°F"
+	Kelvin     TemperatureUnit = "K"
+)
+
+// ParseTemperatureUnit accepts C, F and K with or without the degree sign
+func ParseTemperatureUnit(s string) (TemperatureUnit, error) {
+	switch strings.ToUpper(strings.TrimPrefix(s, "°")) {
+	case "C":
+		return Celsius, nil
+	case "F":
+		return Fahrenheit, nil
+	case "K":
+		return Kelvin, nil
+	}
+	return "", fmt.Errorf("unknown temperature unit %q, use C, F or K", s)
+}
+
+// FromCelsius converts temperature in degrees Celsius to this unit
+func (u TemperatureUnit) FromCelsius(c float64) float64 {
+	switch u {
+	case Fahrenheit:
+		return c*9/5 + 32
+	case Kelvin:
+		return c + absoluteZeroOffset
+	case Celsius:
+		return c
+	}
+	return c
+}
+
+// Display holds the units values are shown in
+type Display struct {
+	Pressure    PressureUnit
+	Temperature TemperatureUnit
+}
+
+// DefaultDisplay is what the plots have always been using
+var DefaultDisplay = Display{
+	Pressure:    Hectopascal,
+	Temperature: Celsius,
+}
+
+// Round rounds to the given amount of decimals, avoiding long fractions
+// after conversions.
+func Round(v float64, decimals int) float64 {
+	scale := math.Pow10(decimals)
+	return math.Round(v*scale) / scale
+}
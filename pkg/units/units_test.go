@@ -0,0 +1,110 @@
+package units
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	ruuvipb "weezel/ruuvigraph/pkg/generated/ruuvi/ruuvi/v1"
+)
+
+func TestNormalizeLegacy(t *testing.T) {
+	tests := []struct {
+		name     string
+		pressure float32
+		want     float32
+	}{
+		{"decapascals", 10132, 101320},
+		{"pascals", 101320, 101320},
+		{"missing", 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &ruuvipb.RuuviStreamDataRequest{Pressure: tt.pressure}
+			NormalizeLegacy(m)
+			if m.GetPressure() != tt.want {
+				t.Errorf("NormalizeLegacy() pressure = %v, want %v", m.GetPressure(), tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		m       *ruuvipb.RuuviStreamDataRequest
+		wantErr bool
+	}{
+		{
+			"valid",
+			&ruuvipb.RuuviStreamDataRequest{Temperature: 21.5, Humidity: 40, Pressure: 101320, BatterVolts: 2.9},
+			false,
+		},
+		{"missing pressure", &ruuvipb.RuuviStreamDataRequest{Temperature: 21.5}, false},
+		{"pressure in hectopascals", &ruuvipb.RuuviStreamDataRequest{Pressure: 1013.2}, true},
+		{"humidity over 100", &ruuvipb.RuuviStreamDataRequest{Humidity: 101}, true},
+		{"below absolute zero", &ruuvipb.RuuviStreamDataRequest{Temperature: -300}, true},
+		{"battery in millivolts", &ruuvipb.RuuviStreamDataRequest{BatterVolts: 2900}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.m)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrOutOfRange) {
+				t.Errorf("Validate() error = %v, want ErrOutOfRange", err)
+			}
+		})
+	}
+}
+
+func TestPressureUnit_FromPascals(t *testing.T) {
+	tests := []struct {
+		unit string
+		want float64
+	}{
+		{"Pa", 101325},
+		{"hpa", 1013.25},
+		{"kPa", 101.325},
+		{"inHg", 29.92},
+		{"mmHg", 760},
+	}
+	for _, tt := range tests {
+		t.Run(tt.unit, func(t *testing.T) {
+			u, err := ParsePressureUnit(tt.unit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := u.FromPascals(101325); math.Abs(got-tt.want) > 0.01 {
+				t.Errorf("FromPascals() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParsePressureUnit("bar"); err == nil {
+		t.Error("ParsePressureUnit(bar) succeeded, want error")
+	}
+}
+
+func TestTemperatureUnit_FromCelsius(t *testing.T) {
+	tests := []struct {
+		unit string
+		want float64
+	}{
+		{"C", 20},
+		{"°F", 68},
+		{"k", 293.15},
+	}
+	for _, tt := range tests {
+		t.Run(tt.unit, func(t *testing.T) {
+			u, err := ParseTemperatureUnit(tt.unit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := u.FromCelsius(20); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("FromCelsius() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+// Package unixsocket serves on unix domain sockets, which lets access to be
+// restricted with file permissions when collector and server share a host.
+package unixsocket
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+)
+
+// Prefix marks unix socket addresses, e.g. unix:/run/ruuvigraph/grpc.sock.
+// Same format is understood by gRPC clients.
+const Prefix = "unix:"
+
+var errInUse = errors.New("socket is in use")
+
+// Path returns the socket path of an unix socket address
+func Path(addr string) (string, bool) {
+	path, found := strings.CutPrefix(addr, Prefix)
+	if !found {
+		return "", false
+	}
+	// unix:///path form of gRPC
+	return strings.TrimPrefix(path, "//"), true
+}
+
+// Listen listens on the socket path. Mode sets the socket's permissions and
+// group its owning group, when not empty. Socket left behind by a crashed
+// process is removed, but a socket still accepting connections isn't.
+func Listen(path string, mode fs.FileMode, group string) (net.Listener, error) {
+	if err := removeStale(path); err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("listen unix: %w", err)
+	}
+
+	if err = setPermissions(path, mode, group); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	return listener, nil
+}
+
+func removeStale(path string) error {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("stat socket: %w", err)
+	}
+	if info.Mode().Type() != fs.ModeSocket {
+		return fmt.Errorf("%s exists and isn't a socket", path)
+	}
+
+	if conn, dialErr := net.Dial("unix", path); dialErr == nil {
+		conn.Close()
+		return fmt.Errorf("%s: %w", path, errInUse)
+	}
+	if err = os.Remove(path); err != nil {
+		return fmt.Errorf("remove stale socket: %w", err)
+	}
+	return nil
+}
+
+func setPermissions(path string, mode fs.FileMode, group string) error {
+	if group != "" {
+		grp, err := user.LookupGroup(group)
+		if err != nil {
+			return fmt.Errorf("lookup group: %w", err)
+		}
+		gid, err := strconv.Atoi(grp.Gid)
+		if err != nil {
+			return fmt.Errorf("parse gid: %w", err)
+		}
+		if err = os.Chown(path, -1, gid); err != nil {
+			return fmt.Errorf("chown socket: %w", err)
+		}
+	}
+
+	if err := os.Chmod(path, mode); err != nil {
+		return fmt.Errorf("chmod socket: %w", err)
+	}
+	return nil
+}
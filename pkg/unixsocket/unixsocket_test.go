@@ -0,0 +1,101 @@
+package unixsocket
+
+import (
+	"errors"
+	"io/fs"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+func TestPath(t *testing.T) {
+	tests := []struct {
+		addr   string
+		want   string
+		wantOk bool
+	}{
+		{"unix:/run/ruuvigraph.sock", "/run/ruuvigraph.sock", true},
+		{"unix:///run/ruuvigraph.sock", "/run/ruuvigraph.sock", true},
+		{"unix:ruuvigraph.sock", "ruuvigraph.sock", true},
+		{"127.0.0.1", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			got, ok := Path(tt.addr)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("Path() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestListen(t *testing.T) {
+	current, err := user.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+	grp, err := user.LookupGroupId(current.Gid)
+	if err != nil {
+		t.Skipf("Can't look up own group: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "grpc.sock")
+	listener, err := Listen(path, 0o640, grp.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o640 {
+		t.Errorf("socket permissions = %o, want 640", perm)
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && strconv.Itoa(int(stat.Gid)) != current.Gid {
+		t.Errorf("socket gid = %d, want %s", stat.Gid, current.Gid)
+	}
+
+	// Socket in use isn't removed
+	if _, err = Listen(path, 0o640, ""); !errors.Is(err, errInUse) {
+		t.Errorf("Listen() on used socket error = %v, want %v", err, errInUse)
+	}
+	listener.Close()
+}
+
+func TestListen_staleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "grpc.sock")
+
+	// Leave the socket file behind like a crashed process would
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.SetUnlinkOnClose(false)
+	stale.Close()
+
+	listener, err := Listen(path, 0o600, "")
+	if err != nil {
+		t.Fatalf("Listen() with stale socket error = %v", err)
+	}
+	listener.Close()
+
+	if _, err = os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("socket wasn't removed on close: %v", err)
+	}
+}
+
+func TestListen_notSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "grpc.sock")
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Listen(path, 0o600, ""); err == nil {
+		t.Error("Listen() over a regular file succeeded")
+	}
+}